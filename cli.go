@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+)
+
+const usage = `Usage: groqy <command> [arguments]
+
+Commands:
+  serve [--restore <backup>]  run the bot (the default)
+  users list                  list the users who have authenticated
+  users revoke <id|username>  remove a user's access, keeping their data
+  migrate                     create the tables and apply migrations
+  backup [path]               back up the SQLite database to path
+
+The commands use the same environment as the bot.
+`
+
+// openStore opens the configured database for the shell commands.
+func openStore() (store.Store, error) {
+	cfg, err := setup()
+	if err != nil {
+		return nil, err
+	}
+	db, err := store.Open(cfg.DatabaseURL, cfg.SQLiteDriver)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the database: %v", err)
+	}
+	return db, nil
+}
+
+func users(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: groqy users list|revoke <id|username>")
+	}
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		users, err := db.GetUsers()
+		if err != nil {
+			return fmt.Errorf("could not list users: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tWORKSPACE\tSINCE")
+		for _, u := range users {
+			since := "-"
+			if u.CreatedAt.Valid {
+				since = u.CreatedAt.Time.Format(time.DateOnly)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.UserID, u.Username, u.Workspace, since)
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: groqy users revoke <id|username>")
+		}
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			u, err := db.GetUserByUsername(strings.TrimPrefix(args[1], "@"))
+			if err != nil {
+				return fmt.Errorf("could not find %s: %v", args[1], err)
+			}
+			userID = u.UserID
+		}
+		revoked, err := db.RevokeUser(userID)
+		if err != nil {
+			return fmt.Errorf("could not revoke %s: %v", args[1], err)
+		}
+		if !revoked {
+			return fmt.Errorf("could not revoke %s: %v", args[1], store.ErrUserNotFound)
+		}
+		fmt.Printf("Revoked %s, they have to /auth again to use the bot\n", args[1])
+		return nil
+	}
+	return fmt.Errorf("unknown users command %q", args[0])
+}
+
+func migrate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: groqy migrate")
+	}
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+	if err := db.CreateTables(); err != nil {
+		return fmt.Errorf("could not migrate the database: %v", err)
+	}
+	fmt.Println("The database is up to date")
+	return nil
+}
+
+func backup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		return errors.New("usage: groqy backup [path]")
+	}
+	path := flags.Arg(0)
+	if path == "" {
+		path = "groqy-" + time.Now().Format("20060102-150405") + ".db"
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+	if err := db.Backup(context.Background(), path); err != nil {
+		return fmt.Errorf("could not back up the database: %v", err)
+	}
+	fmt.Println("Backed up the database to", path)
+	return nil
+}
@@ -0,0 +1,298 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	burstWindow  = time.Minute
+	repeatWindow = 10 * time.Minute
+	// strikeMemory is how long a cooldown counts towards the next one
+	// being longer.
+	strikeMemory = 24 * time.Hour
+	maxCooldown  = 24 * time.Hour
+	// similarPrompts is the share of words two prompts have in common to
+	// count as the same one.
+	similarPrompts = 0.8
+)
+
+// abuseTracker keeps the signals of recent activity per user and the
+// cooldowns they earned. It lives in memory, a restart forgives everyone.
+type abuseTracker struct {
+	mu    sync.Mutex
+	users map[int64]*abuseRecord
+}
+
+type abuseRecord struct {
+	requests   []time.Time
+	prompts    []recentPrompt
+	violations []time.Time
+
+	until time.Time
+	// told is whether the user heard about the cooldown they're in.
+	told    bool
+	strikes int
+	struck  time.Time
+}
+
+type recentPrompt struct {
+	words map[string]bool
+	at    time.Time
+}
+
+func newAbuseTracker() *abuseTracker {
+	return &abuseTracker{users: map[int64]*abuseRecord{}}
+}
+
+func (t *abuseTracker) record(userID int64) *abuseRecord {
+	r, ok := t.users[userID]
+	if !ok {
+		r = &abuseRecord{}
+		t.users[userID] = r
+	}
+	return r
+}
+
+// since drops the times before cutoff.
+func since(times []time.Time, cutoff time.Time) []time.Time {
+	for len(times) > 0 && times[0].Before(cutoff) {
+		times = times[1:]
+	}
+	return times
+}
+
+func promptWords(prompt string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.Fields(normalizePrompt(prompt)) {
+		words[w] = true
+	}
+	return words
+}
+
+// similar reports whether two prompts share most of their words.
+func similar(a, b map[string]bool) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared)/float64(len(a)+len(b)-shared) >= similarPrompts
+}
+
+// cooldown reports how much longer the user is throttled for, and whether
+// they were already told about it.
+func (t *abuseTracker) cooldown(userID int64, now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.users[userID]
+	if !ok || !now.Before(r.until) {
+		return 0, false
+	}
+	told := r.told
+	r.told = true
+	return r.until.Sub(now), told
+}
+
+// request records a request with prompt, which may be empty, and returns
+// the signal it tripped, if any.
+func (t *abuseTracker) request(userID int64, prompt string, now time.Time, burst, repeats int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.record(userID)
+
+	r.requests = append(since(r.requests, now.Add(-burstWindow)), now)
+	if burst > 0 && len(r.requests) > burst {
+		return fmt.Sprintf("%d requests in a minute", len(r.requests))
+	}
+
+	if prompt == "" {
+		return ""
+	}
+	for len(r.prompts) > 0 && r.prompts[0].at.Before(now.Add(-repeatWindow)) {
+		r.prompts = r.prompts[1:]
+	}
+	p := recentPrompt{words: promptWords(prompt), at: now}
+	same := 1
+	for _, earlier := range r.prompts {
+		if similar(p.words, earlier.words) {
+			same++
+		}
+	}
+	r.prompts = append(r.prompts, p)
+	if repeats > 0 && same >= repeats {
+		return fmt.Sprintf("the same prompt %d times in ten minutes", same)
+	}
+	return ""
+}
+
+// violation records a moderation violation and returns the signal it
+// tripped, if any.
+func (t *abuseTracker) violation(userID int64, now time.Time, limit int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.record(userID)
+	r.violations = append(since(r.violations, now.Add(-strikeMemory)), now)
+	if limit > 0 && len(r.violations) >= limit {
+		return fmt.Sprintf("%d moderation violations in a day", len(r.violations))
+	}
+	return ""
+}
+
+// throttle starts a cooldown of base, doubled for every other one the user
+// had in the past day, and returns its length.
+func (t *abuseTracker) throttle(userID int64, now time.Time, base time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.record(userID)
+	if now.Sub(r.struck) > strikeMemory {
+		r.strikes = 0
+	}
+	d := base
+	for i := 0; i < r.strikes && d < maxCooldown; i++ {
+		d *= 2
+	}
+	d = min(d, maxCooldown)
+
+	r.strikes++
+	r.struck = now
+	r.until = now.Add(d)
+	r.told = false
+	// The signals that led here are spent.
+	r.requests, r.prompts, r.violations = nil, nil, nil
+	return d
+}
+
+// lift ends the user's cooldown and forgets their strikes, reporting
+// whether they had one.
+func (t *abuseTracker) lift(userID int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.users[userID]
+	if !ok {
+		return false
+	}
+	delete(t.users, userID)
+	return time.Now().Before(r.until)
+}
+
+// allowRequest checks c's sender against the abuse signals, throttling them
+// when they trip one. It replies the first time a request is refused and
+// drops the ones after that. Admins are never throttled.
+func (b *Bot) allowRequest(c tele.Context) bool {
+	cfg := b.cfg()
+	if cfg.AbuseCooldown <= 0 || b.isAdmin(c) {
+		return true
+	}
+	user := c.Sender()
+	now := time.Now()
+	if left, told := b.abuse.cooldown(user.ID, now); left > 0 {
+		if !told {
+			c.Send(b.t(c, "You're sending too much, take a break for %s", roundCooldown(left)))
+		}
+		return false
+	}
+
+	prompt := ""
+	if msg := c.Message(); msg != nil && c.Callback() == nil {
+		prompt = msg.Text
+	}
+	signal := b.abuse.request(user.ID, prompt, now, cfg.AbuseBurst, cfg.AbuseRepeats)
+	if signal == "" {
+		return true
+	}
+	b.throttleUser(c, signal)
+	return false
+}
+
+// gatewayCooldown checks a gateway request by user against the abuse
+// signals like allowRequest does, returning how long they're throttled for,
+// zero when the request may go ahead.
+func (b *Bot) gatewayCooldown(ctx context.Context, user *tele.User, prompt string) time.Duration {
+	cfg := b.cfg()
+	if cfg.AbuseCooldown <= 0 || listed(cfg.Admins, user) {
+		return 0
+	}
+	now := time.Now()
+	if left, _ := b.abuse.cooldown(user.ID, now); left > 0 {
+		return left
+	}
+	signal := b.abuse.request(user.ID, prompt, now, cfg.AbuseBurst, cfg.AbuseRepeats)
+	if signal == "" {
+		return 0
+	}
+	return b.throttle(ctx, user, signal)
+}
+
+// noteViolation counts a moderation violation towards throttling c's sender.
+func (b *Bot) noteViolation(c tele.Context) {
+	cfg := b.cfg()
+	if cfg.AbuseCooldown <= 0 || b.isAdmin(c) {
+		return
+	}
+	if signal := b.abuse.violation(c.Sender().ID, time.Now(), cfg.AbuseViolations); signal != "" {
+		b.throttleUser(c, signal)
+	}
+}
+
+// throttleUser puts c's sender in a cooldown for signal, telling them and
+// the alert chat.
+func (b *Bot) throttleUser(c tele.Context, signal string) {
+	d := b.throttle(requestContext(c), c.Sender(), signal)
+	c.Send(b.t(c, "You're sending too much, take a break for %s", roundCooldown(d)))
+}
+
+// throttle cools user down for tripping signal and lets the alert chat
+// know, returning how long the cooldown is.
+func (b *Bot) throttle(ctx context.Context, user *tele.User, signal string) time.Duration {
+	d := b.abuse.throttle(user.ID, time.Now(), b.cfg().AbuseCooldown)
+	b.abuse.cooldown(user.ID, time.Now())
+	throttledTotal.Inc()
+	slog.WarnContext(ctx, "Throttled user", "user_id", user.ID, "username", user.Username, "signal", signal, "cooldown", d)
+
+	if b.cfg().AlertChatID == 0 {
+		return d
+	}
+	name := strconv.FormatInt(user.ID, 10)
+	if user.Username != "" {
+		name = "@" + user.Username
+	}
+	text := fmt.Sprintf("🚫 Throttled %s for %s: %s. /unthrottle %d lifts it", name, roundCooldown(d), signal, user.ID)
+	if _, err := b.tele.Send(&tele.Chat{ID: b.cfg().AlertChatID}, text); err != nil {
+		slog.ErrorContext(ctx, "Could not send throttle alert", "err", err)
+	}
+	return d
+}
+
+func roundCooldown(d time.Duration) time.Duration {
+	if d < time.Minute {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Minute)
+}
+
+func (b *Bot) unthrottleHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send(b.t(c, "Usage: /unthrottle <user id|@username>"))
+	}
+	userID, err := b.userArg(args[0])
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not find %s: ", args[0]) + err.Error())
+	}
+	if !b.abuse.lift(userID) {
+		return c.Send(b.t(c, "%s isn't throttled", args[0]))
+	}
+	return c.Send(b.t(c, "Lifted the cooldown of %s", args[0]))
+}
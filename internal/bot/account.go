@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var btnUnlink = tele.Btn{Unique: "unlink"}
+
+func (b *Bot) whoamiHandler(c tele.Context) error {
+	sender := c.Sender()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@%s (ID %d)\n", sender.Username, sender.ID)
+
+	user, err := b.lookupUser(requestContext(c), sender)
+	if err != nil || !b.authorized(user) {
+		sb.WriteString(b.t(c, "Not authenticated, use /auth yourtoken"))
+		return c.Send(sb.String())
+	}
+	sb.WriteString(b.t(c, "Authenticated"))
+	if user.CreatedAt.Valid {
+		sb.WriteString(b.t(c, ", member since %s", user.CreatedAt.Time.Format("2006-01-02")))
+	}
+	if b.isAdmin(c) {
+		sb.WriteString(b.t(c, ", admin"))
+	}
+	sb.WriteString("\n")
+	if user.Workspace != "" {
+		sb.WriteString(b.t(c, "Workspace: %s", user.Workspace) + "\n")
+	}
+
+	if b.onPlan(sender.ID) {
+		p, expires := b.plan(sender.ID)
+		if expires.IsZero() {
+			sb.WriteString(b.t(c, "Plan: free, see /plans") + "\n")
+		} else {
+			sb.WriteString(b.t(c, "Plan: %s until %s", p.Name, expires.Format("Jan 2 2006")) + "\n")
+		}
+		switch left, quota, err := b.quotaLeft(c); {
+		case err != nil:
+			slog.ErrorContext(requestContext(c), "Could not count requests", "err", err)
+		case quota > 0:
+			sb.WriteString(b.t(c, "Messages left today: %d of %d", left, quota) + "\n")
+		}
+	}
+
+	model := b.pickedModel(sender.ID)
+	if _, auto := autoTiers[model]; auto {
+		r := b.routeModel(sender.ID, model)
+		model = b.t(c, "%s, now routing to %s", model, r.Model)
+		if r.Stats.Samples > 0 {
+			model += b.t(c, " (%.1fs, %.0f%% errors over the last %d requests)", r.Stats.Latency.Seconds(), r.Stats.ErrorRate*100, r.Stats.Samples)
+		}
+	}
+	sb.WriteString(b.t(c, "Model: %s\nPersona: %s\n", model, b.t(c, b.userPersona(sender.ID).Label)))
+	switch _, err := b.db.GetAPIKey(sender.ID); {
+	case err == nil && b.keysEnabled():
+		sb.WriteString(b.t(c, "Groq key: your own") + "\n")
+	case b.serverKeyAllowed(sender):
+		sb.WriteString(b.t(c, "Groq key: shared") + "\n")
+	default:
+		sb.WriteString(b.t(c, "Groq key: none, set one with /apikey") + "\n")
+	}
+
+	if quota := b.imageQuota(sender.ID); b.images != nil && quota > 0 {
+		n, err := b.db.CountImageGenerations(sender.ID, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			slog.ErrorContext(requestContext(c), "Could not count image generations", "err", err)
+		} else {
+			sb.WriteString(b.t(c, "Images left today: %d of %d", max(quota-n, 0), quota) + "\n")
+		}
+	}
+	if b.speech != nil {
+		voice := b.t(c, "off")
+		if b.speaking(sender.ID) {
+			voice = b.t(c, "on")
+		}
+		sb.WriteString(b.t(c, "Voice replies: %s", voice) + "\n")
+	}
+	return c.Send(sb.String())
+}
+
+func (b *Bot) unlinkHandler(c tele.Context) error {
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(menu.Data(b.t(c, "Yes, delete everything"), btnUnlink.Unique)))
+	return c.Send(b.t(c, "This deletes your account, conversations, memories, documents, reminders, API key and usage history. It can't be undone."), menu)
+}
+
+func (b *Bot) confirmUnlinkHandler(c tele.Context) error {
+	sender := c.Sender()
+	if cancel, ok := b.generations.LoadAndDelete(sender.ID); ok {
+		cancel.(context.CancelFunc)()
+	}
+	b.pendingEdits.Delete(sender.ID)
+
+	if err := b.db.DeleteUser(sender.ID); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not delete user", "user", sender.ID, "err", err)
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not delete your data")})
+	}
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Deleted")})
+	return c.Edit(b.t(c, "Your account and all your data are gone. Use /auth yourtoken to start over."))
+}
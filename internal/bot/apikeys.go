@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var errNoAPIKey = errors.New("no groq api key available for user")
+
+// groqKeyFor returns the Groq key to bill the user's requests to: their own
+// key if they set one, otherwise the server's GROQ_TOKEN when they are
+// allowed to use it.
+func (b *Bot) groqKeyFor(user *tele.User) (string, error) {
+	if b.keysEnabled() {
+		ciphertext, err := b.db.GetAPIKey(user.ID)
+		if err == nil {
+			return b.decryptKey(ciphertext)
+		}
+		if err != sql.ErrNoRows {
+			return "", err
+		}
+	}
+
+	if !b.serverKeyAllowed(user) {
+		return "", errNoAPIKey
+	}
+	ws := b.workspace(user.ID)
+	if err := b.checkWorkspaceBudget(ws); err != nil {
+		return "", err
+	}
+	return ws.GroqToken, nil
+}
+
+// serverKeyAllowed reports whether the user may fall back to GROQ_TOKEN.
+// Everyone may when SERVER_KEY_USERS is unset.
+func (b *Bot) serverKeyAllowed(user *tele.User) bool {
+	return len(b.cfg().ServerKeyUsers) == 0 || listed(b.cfg().ServerKeyUsers, user)
+}
+
+func (b *Bot) keysEnabled() bool {
+	return b.cfg().EncryptionKey != ""
+}
+
+func (b *Bot) keyCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(b.cfg().EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (b *Bot) encryptKey(plaintext string) ([]byte, error) {
+	gcm, err := b.keyCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+func (b *Bot) decryptKey(ciphertext []byte) (string, error) {
+	gcm, err := b.keyCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("stored key is corrupt")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt stored key: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func (b *Bot) apiKeyHandler(c tele.Context) error {
+	if !b.keysEnabled() {
+		return c.Send(b.t(c, "Custom API keys are not enabled on this bot"))
+	}
+
+	args := c.Args()
+	switch {
+	case len(args) == 0:
+		if _, err := b.db.GetAPIKey(c.Sender().ID); err == nil {
+			return c.Send(b.t(c, "You are using your own Groq key.\nUse /apikey remove to go back to the shared one"))
+		}
+		return c.Send(b.t(c, "Usage: /apikey <your groq key>"))
+	case len(args) == 1 && args[0] == "remove":
+		if err := b.db.DeleteAPIKey(c.Sender().ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not remove your key: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Removed your Groq key"))
+	case len(args) > 1:
+		return c.Send(b.t(c, "Either provided too many or too little arguments"))
+	}
+
+	// Don't leave the key sitting in the chat history.
+	c.Delete()
+
+	key := args[0]
+	ctx, cancel := context.WithTimeout(requestContext(c), 10*time.Second)
+	defer cancel()
+	if err := b.llm.CheckKey(ctx, key); err != nil {
+		return c.Send(b.t(c, "That key didn't work with Groq: ") + err.Error())
+	}
+
+	ciphertext, err := b.encryptKey(key)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your key: ") + err.Error())
+	}
+	if err := b.db.SaveAPIKey(c.Sender().ID, ciphertext); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your key: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Saved your Groq key, your requests are now billed to it"))
+}
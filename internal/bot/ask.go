@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+// statelessKey is set on the context of /ask requests, whose answers aren't
+// part of any conversation.
+const statelessKey = "stateless"
+
+// askHandler answers a one-off question without the conversation, persona,
+// memories or documents, and doesn't keep the exchange.
+func (b *Bot) askHandler(c tele.Context) error {
+	question := strings.TrimSpace(c.Message().Payload)
+	if question == "" {
+		return c.Send(b.t(c, "Usage: /ask <question>, answered on its own without your conversation or persona"))
+	}
+	if !b.allowPrompt(c, question) {
+		return nil
+	}
+
+	messages := []llm.Message{{Role: "system", Content: b.instructions(c.Sender().ID)}}
+	if prompt := b.chatPrompt(c); prompt != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: prompt})
+	}
+	if instruct := languageInstruct(question); instruct != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: instruct})
+	}
+	messages = append(messages, llm.Message{Role: "user", Content: question})
+
+	c.Set(statelessKey, true)
+	res, msg, err := b.streamAnswer(c, question, messages, "")
+	if err != nil {
+		if msg == nil {
+			return err
+		}
+		return nil
+	}
+	if b.speaking(c.Sender().ID) {
+		b.sendVoice(c, res.Content)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	auditPruneInterval = time.Hour
+	auditEntriesShown  = 10
+	auditPreviewLen    = 200
+)
+
+// audit records a completed (or failed) request when the audit log is enabled.
+func (b *Bot) audit(user *tele.User, prompt string, res llm.Completion, latency time.Duration, reqErr error) {
+	if !b.cfg().AuditLog {
+		return
+	}
+
+	entry := store.AuditEntry{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Prompt:    redact(b.cfg().Redaction, prompt),
+		Response:  redact(b.cfg().Redaction, res.Content),
+		Model:     res.Model,
+		LatencyMS: latency.Milliseconds(),
+		Status:    "ok",
+	}
+	if entry.Model == "" {
+		entry.Model = llm.DefaultModel
+	}
+	if reqErr != nil {
+		entry.Status = "error: " + reqErr.Error()
+	}
+
+	if err := b.db.SaveAuditEntry(entry); err != nil {
+		slog.Error("Could not write audit log", "err", err)
+	}
+}
+
+// pruneAuditLog periodically deletes entries older than the retention policy.
+func (b *Bot) pruneAuditLog() {
+	for {
+		n, err := b.db.DeleteAuditEntriesBefore(time.Now().Add(-b.cfg().AuditRetention))
+		if err != nil {
+			slog.Error("Could not prune audit log", "err", err)
+		} else if n > 0 {
+			slog.Info("Pruned audit log", "entries", n)
+		}
+		time.Sleep(auditPruneInterval)
+	}
+}
+
+func (b *Bot) auditHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send(b.t(c, "Usage: /audit @username"))
+	}
+	username := strings.TrimPrefix(args[0], "@")
+
+	entries, err := b.db.GetAuditEntries(username, auditEntriesShown)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not read audit log: ") + err.Error())
+	}
+	if len(entries) == 0 {
+		return c.Send(b.t(c, "No audit entries for @") + username)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.t(c, "Last %d requests by @%s", len(entries), username) + "\n\n")
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("%s  %s  %dms  %s\n> %s\n\n",
+			e.CreatedAt.Format(time.DateTime), e.Model, e.LatencyMS, e.Status, truncate(e.Prompt, auditPreviewLen)))
+	}
+	return c.Send(sb.String())
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+func (b *Bot) isAdmin(c tele.Context) bool {
+	return listed(b.cfg().Admins, c.Sender())
+}
+
+func (b *Bot) withAdmin(handler tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !b.isAdmin(c) {
+			return c.Send(b.t(c, "This command is only available to admins"))
+		}
+		return handler(c)
+	}
+}
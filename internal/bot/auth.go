@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+var errNotPermitted = errors.New("not allowed to use the bot")
+
+func (b *Bot) authHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send(b.t(c, "Either provided too many or too little arguments"))
+	}
+	rotated, err := b.authenticate(c, args[0])
+	if err != nil {
+		return c.Send(err.Error())
+	}
+	if rotated {
+		return c.Send(b.t(c, "Your token has been updated"))
+	}
+	return c.Send(b.t(c, "Authenticated successfully"))
+}
+
+// authenticate saves the sender as a user if token is valid, reporting
+// whether it replaced the token of their existing account.
+func (b *Bot) authenticate(c tele.Context, token string) (bool, error) {
+	if !b.permitted(c.Sender()) {
+		return false, errors.New(b.t(c, "You're not allowed to use this bot"))
+	}
+	workspace, ok := b.tokenWorkspace(token)
+	if !ok {
+		return false, errors.New(b.t(c, "Invalid token"))
+	}
+	existing, err := b.lookupUser(requestContext(c), c.Sender())
+	found := err == nil
+	if err != nil && err != store.ErrUserNotFound {
+		return false, errors.New(b.t(c, "ERROR: Could not save your token: ") + err.Error())
+	}
+	// Conversations and usage stay with the user, so moving them would take
+	// their data along to the other workspace.
+	if found && existing.Workspace != workspace {
+		return false, errors.New(b.t(c, "That token is for another workspace, /unlink your account first to join it"))
+	}
+	if err := b.db.CreateUser(c.Sender().ID, c.Sender().Username, token, workspace); err != nil {
+		return false, errors.New(b.t(c, "ERROR: Could not save your token: ") + err.Error())
+	}
+	return found && existing.Token != token, nil
+}
+
+// permitted checks the sender against ALLOWED_USERS and DENIED_USERS.
+func (b *Bot) permitted(user *tele.User) bool {
+	if slices.Contains(b.cfg().DeniedUsers, user.ID) {
+		return false
+	}
+	return len(b.cfg().AllowedUsers) == 0 || slices.Contains(b.cfg().AllowedUsers, user.ID)
+}
+
+// listed reports whether list names the user by ID or username.
+func listed(list []string, user *tele.User) bool {
+	return slices.Contains(list, strconv.FormatInt(user.ID, 10)) ||
+		(user.Username != "" && slices.Contains(list, user.Username))
+}
+
+// lookupUser finds the sender's account by their Telegram ID, claiming the
+// one they made by username before IDs were kept.
+func (b *Bot) lookupUser(ctx context.Context, sender *tele.User) (store.User, error) {
+	db := b.db.WithContext(ctx)
+	user, err := db.GetUser(sender.ID)
+	if err != store.ErrUserNotFound || sender.Username == "" {
+		return user, err
+	}
+	claimed, err := db.ClaimUser(sender.ID, sender.Username)
+	if err != nil {
+		return user, err
+	}
+	if !claimed {
+		return user, store.ErrUserNotFound
+	}
+	slog.Info("Moved user to their ID", "username", sender.Username, "user", sender.ID)
+	return db.GetUser(sender.ID)
+}
+
+func (b *Bot) checkAuth(c tele.Context) (err error) {
+	end := startSpan(c, "auth_check")
+	defer func() { end(err) }()
+	if !b.permitted(c.Sender()) {
+		return errNotPermitted
+	}
+
+	dbUser, err := b.lookupUser(requestContext(c), c.Sender())
+	if err != nil {
+		return fmt.Errorf("could not get user: %v", err)
+	}
+
+	if b.authorized(dbUser) {
+		return nil
+	}
+
+	return fmt.Errorf("invalid token")
+}
+
+func (b *Bot) withAuth(handler tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if err := b.checkAuth(c); err != nil {
+			if err == errNotPermitted {
+				return c.Send(b.t(c, "You're not allowed to use this bot"))
+			}
+			return c.Send(b.t(c, "Authentication required\nPlease use /auth yourtoken"))
+		}
+		return handler(c)
+	}
+}
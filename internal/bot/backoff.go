@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// gateReleaseInterval spaces out queued requests once a backoff ends so
+	// they don't trip the rate limit again all at once.
+	gateReleaseInterval = time.Second
+	maxRateLimitRetries = 3
+)
+
+var errQueueFull = errors.New("rate limit queue is full")
+
+// backoffGate holds requests on the shared Groq key back while it is rate
+// limited, letting them through in arrival order once it recovers.
+type backoffGate struct {
+	size int
+
+	mu       sync.Mutex
+	until    time.Time
+	waiters  []chan struct{}
+	draining bool
+}
+
+func newBackoffGate(size int) *backoffGate {
+	return &backoffGate{size: size}
+}
+
+// resize changes how many requests may wait, the ones already waiting
+// keeping their place.
+func (g *backoffGate) resize(size int) {
+	g.mu.Lock()
+	g.size = size
+	g.mu.Unlock()
+}
+
+// Wait returns straight away unless the key is backing off or others are
+// already queued. Otherwise it calls onQueued with the caller's position and
+// blocks until its turn, or returns errQueueFull.
+func (g *backoffGate) Wait(onQueued func(position int)) error {
+	g.mu.Lock()
+	if len(g.waiters) == 0 && time.Now().After(g.until) {
+		g.mu.Unlock()
+		return nil
+	}
+	if len(g.waiters) >= g.size {
+		g.mu.Unlock()
+		return errQueueFull
+	}
+	ch := make(chan struct{})
+	g.waiters = append(g.waiters, ch)
+	position := len(g.waiters)
+	g.mu.Unlock()
+
+	onQueued(position)
+	<-ch
+	return nil
+}
+
+// Backoff stops requests for d, or longer if an earlier backoff ends later.
+func (g *backoffGate) Backoff(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+	if !g.draining {
+		g.draining = true
+		go g.drain()
+	}
+}
+
+func (g *backoffGate) drain() {
+	for {
+		g.mu.Lock()
+		wait := time.Until(g.until)
+		if wait <= 0 {
+			if len(g.waiters) == 0 {
+				g.draining = false
+				g.mu.Unlock()
+				return
+			}
+			close(g.waiters[0])
+			g.waiters = g.waiters[1:]
+			wait = gateReleaseInterval
+		}
+		g.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+// waitForGroq queues c's request behind the backoff gate if it will be sent
+// with the shared key.
+func (b *Bot) waitForGroq(c tele.Context) error {
+	key, err := b.groqKeyFor(c.Sender())
+	if err != nil || key != b.cfg().GroqToken {
+		return nil
+	}
+	return b.gate.Wait(b.queuedNotice(c))
+}
+
+func (b *Bot) queuedNotice(c tele.Context) func(position int) {
+	return func(position int) {
+		c.Send(b.t(c, "Groq is rate limited right now, you're #%d in queue", position))
+	}
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+// maxUploadSize is the largest file a bot may send on Telegram.
+const maxUploadSize = 50 << 20
+
+// snapshot backs the database up into a new temporary file. The caller
+// removes it.
+func (b *Bot) snapshot(ctx context.Context) (string, error) {
+	dir, err := os.MkdirTemp("", "groqy-backup-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "groqy-"+time.Now().UTC().Format("2006-01-02T150405Z")+".db")
+	if err := b.db.Backup(ctx, path); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// backup snapshots the database and uploads it when S3 is configured,
+// returning the snapshot's path for the caller to remove with
+// removeSnapshot.
+func (b *Bot) backup(ctx context.Context) (string, error) {
+	path, err := b.snapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	if b.s3 == nil {
+		return path, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return path, err
+	}
+	defer f.Close()
+	if err := b.s3.put(ctx, filepath.Base(path), f); err != nil {
+		return path, fmt.Errorf("Could not upload the backup:\n%v", err)
+	}
+	return path, nil
+}
+
+func removeSnapshot(path string) {
+	if path != "" {
+		os.RemoveAll(filepath.Dir(path))
+	}
+}
+
+func (b *Bot) backupHandler(c tele.Context) error {
+	c.Send(b.t(c, "💾 Backing up the database…"))
+	path, err := b.backup(requestContext(c))
+	defer removeSnapshot(path)
+	if errors.Is(err, store.ErrBackupUnsupported) {
+		return c.Send(b.t(c, "Backups only work with SQLite, use pg_dump for Postgres"))
+	}
+	if path == "" {
+		return c.Send(b.t(c, "ERROR: Could not back up the database: ") + err.Error())
+	}
+
+	info, statErr := os.Stat(path)
+	caption := b.t(c, "Backup of %s", time.Now().UTC().Format(time.RFC1123))
+	if err != nil {
+		caption += "\n" + b.t(c, "ERROR: %v", err)
+	} else if b.s3 != nil {
+		caption += b.t(c, ", also uploaded to %s", b.s3.bucket)
+	}
+	if statErr != nil || info.Size() > maxUploadSize {
+		return c.Send(caption + "\n" + b.t(c, "It's too large to send here."))
+	}
+	return c.Send(&tele.Document{File: tele.FromDisk(path), FileName: filepath.Base(path), Caption: caption})
+}
+
+// runBackups uploads a backup every BackupInterval.
+func (b *Bot) runBackups() {
+	for range time.Tick(b.cfg().BackupInterval) {
+		path, err := b.backup(context.Background())
+		removeSnapshot(path)
+		if err != nil {
+			errorsTotal.WithLabelValues("backup").Inc()
+			slog.Error("Could not back up the database", "err", err)
+			continue
+		}
+		slog.Info("Backed up the database", "bucket", b.s3.bucket)
+	}
+}
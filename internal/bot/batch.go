@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	maxBatchQuestions = 10
+	// batchConcurrency is how many of a batch's questions are asked at once.
+	batchConcurrency = 3
+	// maxBatchReply is how long the reply may be before it's sent as a file.
+	maxBatchReply = 4000
+)
+
+// batchItemRe matches a numbered list item, "1. question" or "1) question".
+var batchItemRe = regexp.MustCompile(`^\s*(\d+)[.)]\s+(.*)$`)
+
+// batchQuestion is one question of a /batch and how it was answered.
+type batchQuestion struct {
+	number string
+	text   string
+	res    llm.Completion
+	err    error
+}
+
+// commandText is the text after the command, which unlike Payload runs past
+// the first line, or the replied-to message's when there's none.
+func commandText(m *tele.Message) string {
+	var text string
+	if i := strings.IndexFunc(m.Text, unicode.IsSpace); i >= 0 {
+		text = m.Text[i:]
+	}
+	if reply := replyTo(m); strings.TrimSpace(text) == "" && reply != nil {
+		text = reply.Text
+		if text == "" {
+			text = reply.Caption
+		}
+	}
+	return text
+}
+
+// parseBatch reads the questions of a numbered list, keeping their numbers.
+// Lines that aren't numbered continue the question before them.
+func parseBatch(text string) []batchQuestion {
+	var questions []batchQuestion
+	for _, line := range strings.Split(text, "\n") {
+		if m := batchItemRe.FindStringSubmatch(line); m != nil {
+			questions = append(questions, batchQuestion{number: m[1], text: strings.TrimSpace(m[2])})
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" && len(questions) > 0 {
+			last := &questions[len(questions)-1]
+			last.text = strings.TrimSpace(last.text + "\n" + line)
+		}
+	}
+	kept := questions[:0]
+	for _, q := range questions {
+		if q.text != "" {
+			kept = append(kept, q)
+		}
+	}
+	return kept
+}
+
+// batchHandler asks each question of a numbered list on its own, a few at
+// a time, and replies with every answer under its number and the tokens
+// they took together.
+func (b *Bot) batchHandler(c tele.Context) error {
+	text := commandText(c.Message())
+	questions := parseBatch(text)
+	if len(questions) == 0 {
+		return c.Send(b.t(c, "Usage: /batch followed by a numbered list of questions, or in reply to one, each answered on its own, e.g.\n/batch\n1. What is Go?\n2. What is Rust?"))
+	}
+	if len(questions) > maxBatchQuestions {
+		return c.Send(b.t(c, "A batch can have at most %d questions", maxBatchQuestions))
+	}
+	if !b.fitsQuota(c, len(questions)) {
+		return nil
+	}
+	if !b.allowPrompt(c, text) {
+		return nil
+	}
+	apiKey, err := b.groqKeyFor(c.Sender())
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+
+	system := []llm.Message{{Role: "system", Content: b.instructions(c.Sender().ID)}}
+	if prompt := b.chatPrompt(c); prompt != "" {
+		system = append(system, llm.Message{Role: "system", Content: prompt})
+	}
+	opts := append(b.samplingOptions(c.Sender().ID), llm.WithModel(b.userModel(c.Sender().ID)), llm.WithReasoning(b.reasoningEffort(c.Sender().ID)))
+
+	c.Notify(tele.Typing)
+	stopProgress := b.whileSlow(c, nil, nil)
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range questions {
+		q := &questions[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			messages := append([]llm.Message{}, system...)
+			if instruct := languageInstruct(q.text); instruct != "" {
+				messages = append(messages, llm.Message{Role: "system", Content: instruct})
+			}
+			messages = append(messages, llm.Message{Role: "user", Content: q.text})
+			start := time.Now()
+			q.res, q.err = b.llm.Complete(requestContext(c), apiKey, messages, opts...)
+			elapsed := time.Since(start)
+			b.audit(c.Sender(), "/batch "+q.number+": "+q.text, q.res, elapsed, q.err)
+			if q.err != nil {
+				errorsTotal.WithLabelValues("groq").Inc()
+				return
+			}
+			b.recordRequest(c.Sender(), q.res, elapsed)
+		}()
+	}
+	wg.Wait()
+	stopProgress()
+
+	reply := b.batchReply(c, questions)
+	if len([]rune(reply)) <= maxBatchReply {
+		return c.Send(reply)
+	}
+	return c.Send(&tele.Document{
+		File:     tele.FromReader(bytes.NewReader([]byte(reply))),
+		FileName: fmt.Sprintf("groqy-batch-%s.md", time.Now().Format(time.DateOnly)),
+		Caption:  b.batchTotals(c, questions),
+	})
+}
+
+// batchReply puts each answer under its question's number, failed ones
+// saying why, and ends with the totals.
+func (b *Bot) batchReply(c tele.Context, questions []batchQuestion) string {
+	var sb strings.Builder
+	for _, q := range questions {
+		answer := q.res.Content
+		if q.err != nil {
+			answer = "⚠️ " + errorReply(b.lang(c), q.err)
+		}
+		fmt.Fprintf(&sb, "%s. %s\n%s\n\n", q.number, truncate(q.text, auditPreviewLen), strings.TrimSpace(answer))
+	}
+	sb.WriteString(b.batchTotals(c, questions))
+	return sb.String()
+}
+
+// batchTotals is how many questions were answered, the tokens they took and
+// what they cost when every model answering has a price.
+func (b *Bot) batchTotals(c tele.Context, questions []batchQuestion) string {
+	answered, promptTokens, completionTokens := 0, 0, 0
+	cost, priced := 0.0, true
+	for _, q := range questions {
+		if q.err != nil {
+			continue
+		}
+		answered++
+		promptTokens += q.res.PromptTokens
+		completionTokens += q.res.CompletionTokens
+		if price, ok := b.prices[q.res.Model]; ok {
+			cost += price.Cost(q.res.PromptTokens, q.res.CompletionTokens)
+		} else {
+			priced = false
+		}
+	}
+	totals := b.t(c, "%d of %d answered, %d prompt + %d completion tokens", answered, len(questions), promptTokens, completionTokens)
+	if priced && answered > 0 {
+		totals += fmt.Sprintf(", $%.5f", cost)
+	}
+	return totals
+}
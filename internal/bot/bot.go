@@ -0,0 +1,324 @@
+// Package bot wires the Telegram handlers to the store and the LLM client.
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/i18n"
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/sandbox"
+	"github.com/musaubrian/groqy/internal/search"
+	"github.com/musaubrian/groqy/internal/store"
+	"github.com/musaubrian/groqy/internal/tracing"
+	"github.com/musaubrian/groqy/internal/web"
+	tele "gopkg.in/telebot.v3"
+)
+
+type Command struct {
+	Name        string
+	Description string
+	Handler     tele.HandlerFunc
+	// Middleware runs before Handler, after the bot-wide middleware.
+	Middleware []tele.MiddlewareFunc
+	// Translations are the description by language code for the command
+	// menu.
+	Translations map[string]string
+	// Private commands are left out of the menu in groups.
+	Private bool
+	// Admin commands are only run for admins and left out of the menu.
+	Admin bool
+	// Account commands manage the user's access and data, and can't be
+	// turned off by a flag.
+	Account bool
+}
+
+type Bot struct {
+	// config is swapped whole by reload, read it through cfg.
+	config atomic.Pointer[config.Config]
+	db     store.Store
+	llm    llm.Client
+	// tracker measures the models' latency and errors for auto routing.
+	tracker *llm.Tracker
+	tele    *tele.Bot
+	pool    *WorkerPool
+	// commands are published to Telegram's command menu on Start.
+	commands []Command
+	gate     *backoffGate
+	abuse    *abuseTracker
+
+	prices map[string]llm.Price
+	alert  budgetAlert
+	flags  flagCache
+	// sentry is nil when panics aren't reported to Sentry.
+	sentry *sentry
+	// traces is nil when tracing is off.
+	traces *tracing.Exporter
+	// s3 is nil when backups aren't uploaded.
+	s3 *s3
+	// cache is nil when answers aren't cached.
+	cache *responseCache
+
+	// embedder is nil when long-term memory is disabled.
+	embedder llm.Embedder
+	// images is nil when /imagine is disabled.
+	images llm.ImageGenerator
+	// speech is nil when text-to-speech is disabled.
+	speech llm.SpeechSynthesizer
+	// search is nil when the web search tool is disabled.
+	search  search.Searcher
+	fetcher *web.Fetcher
+	// sandbox is nil when the code runner tool is disabled.
+	sandbox *sandbox.Runner
+	// tools are offered to the model when answering.
+	tools []tool
+
+	// pendingEdits holds the IDs of users whose next message replaces
+	// their last prompt.
+	pendingEdits sync.Map
+	// pendingAuth holds the IDs of users whose next message is their token.
+	pendingAuth sync.Map
+	// pendingForwards maps a user ID to the forwards their next message
+	// asks about.
+	pendingForwards sync.Map
+	// awaitingImport holds the IDs of users whose next document is an
+	// export for /import.
+	awaitingImport sync.Map
+	// pendingImports maps a user ID to the export they're importing from.
+	pendingImports sync.Map
+	// generations maps a user ID to the cancel func of their streaming answer.
+	generations sync.Map
+	// summarizing keeps one summarization per user running at a time.
+	summarizing sync.Map
+	// routes maps an auto model option to the model it last picked.
+	routes sync.Map
+}
+
+// New registers the bot's handlers on tb. Nothing runs until Start.
+func New(tb *tele.Bot, cfg config.Config, db store.Store, client llm.Client) (*Bot, error) {
+	prices, err := llm.ParsePrices(cfg.ModelPrices)
+	if err != nil {
+		return nil, fmt.Errorf("MODEL_PRICES: %v", err)
+	}
+
+	tracker := llm.NewTracker(client)
+	b := &Bot{
+		db:      db,
+		llm:     tracker,
+		tracker: tracker,
+		tele:    tb,
+		pool:    NewWorkerPool(cfg.MaxConcurrency),
+		gate:    newBackoffGate(cfg.RateLimitQueue),
+		abuse:   newAbuseTracker(),
+
+		prices:  prices,
+		fetcher: web.NewFetcher(maxPageSize),
+	}
+	b.config.Store(&cfg)
+	if cfg.SentryDSN != "" {
+		if b.sentry, err = newSentry(cfg.SentryDSN); err != nil {
+			return nil, fmt.Errorf("SENTRY_DSN: %v", err)
+		}
+	}
+	if cfg.TracingEndpoint != "" {
+		headers, err := tracing.ParseHeaders(cfg.TracingHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS: %v", err)
+		}
+		b.traces = tracing.NewExporter(cfg.TracingEndpoint, cfg.TracingService, headers)
+		tracing.Enable(b.traces)
+	}
+	if cfg.BackupBucket != "" {
+		if b.s3, err = newS3(cfg.BackupEndpoint, cfg.BackupRegion, cfg.BackupBucket, cfg.BackupAccessKey, cfg.BackupSecretKey); err != nil {
+			return nil, fmt.Errorf("BACKUP_S3_BUCKET: %v", err)
+		}
+	}
+	if cfg.CacheTTL > 0 {
+		b.cache = newResponseCache(db, cfg.CacheTTL, cfg.CacheSize)
+	}
+	if cfg.EmbeddingsToken != "" {
+		b.embedder = llm.NewOpenAIEmbedder(cfg.EmbeddingsURL, cfg.EmbeddingsModel, cfg.EmbeddingsToken)
+	}
+	if cfg.ImagesToken != "" {
+		b.images = llm.NewOpenAIImages(cfg.ImagesURL, cfg.ImagesModel, cfg.ImagesToken)
+	} else if gen, ok := client.(llm.ImageGenerator); ok {
+		b.images = gen
+	}
+	if len(cfg.FallbackModels) > 0 {
+		if b.llm, err = newFailover(cfg, tracker); err != nil {
+			return nil, fmt.Errorf("FALLBACK_MODELS: %v", err)
+		}
+	}
+	if err := validRedaction(cfg.Redaction); err != nil {
+		return nil, fmt.Errorf("REDACTION: %v", err)
+	}
+	if cfg.SpeechToken != "" {
+		b.speech = llm.NewOpenAISpeech(cfg.SpeechURL, cfg.SpeechModel, cfg.SpeechVoice, cfg.SpeechToken)
+	}
+	if cfg.SearchProvider != "" {
+		if b.search, err = search.New(cfg.SearchProvider, cfg.SearchURL, cfg.SearchToken); err != nil {
+			return nil, fmt.Errorf("SEARCH_PROVIDER: %v", err)
+		}
+		b.tools = append(b.tools, b.searchTool())
+	}
+	if cfg.CodeSandbox {
+		b.sandbox = sandbox.NewRunner(cfg.SandboxPythonImage, cfg.SandboxGoImage, cfg.SandboxTimeout)
+		b.tools = append(b.tools, b.codeTool())
+	}
+	b.register()
+	return b, nil
+}
+
+func (b *Bot) register() {
+	auth := []tele.MiddlewareFunc{b.withAuth}
+	queued := []tele.MiddlewareFunc{b.withAuth, b.withQuota, b.withQueue}
+	commands := []Command{
+		{Name: "/auth", Description: "Provide token to allow usage", Handler: b.authHandler, Private: true, Account: true},
+		{Name: "/new", Description: "Start a new chat, optionally with a title", Handler: b.newChatHandler, Middleware: auth, Private: true},
+		{Name: "/chats", Description: "List and switch between your chats", Handler: b.chatsHandler, Middleware: auth, Private: true},
+		{Name: "/search", Description: "Search your conversations", Handler: b.searchHandler, Middleware: auth},
+		{Name: "/pin", Description: "Pin the answer you reply to", Handler: b.pinHandler, Middleware: auth},
+		{Name: "/pins", Description: "List or search your pinned answers", Handler: b.pinsHandler, Middleware: auth},
+		{Name: "/forget", Description: "Clear uploaded documents", Handler: b.forgetHandler, Middleware: auth},
+		{Name: "/share", Description: "Get a read-only link to this chat, /share off revokes them", Handler: b.shareHandler, Middleware: auth, Private: true},
+		{Name: "/export", Description: "Download your conversation history as json or md", Handler: b.exportHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/import", Description: "Bring over conversations from a ChatGPT export", Handler: b.importHandler, Middleware: auth, Private: true},
+		{Name: "/apikey", Description: "Use your own Groq API key", Handler: b.apiKeyHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/gateway", Description: "Get a key for the OpenAI-compatible API", Handler: b.gatewayKeyHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/remind", Description: "Schedule a prompt, e.g. /remind 9am daily \"summarize Go news\"", Handler: b.remindHandler, Middleware: auth},
+		{Name: "/digest", Description: "Get a daily digest of your conversations", Handler: b.digestHandler, Middleware: auth, Private: true},
+		{Name: "/quiet", Description: "Hold digests, reminders and announcements during quiet hours", Handler: b.quietHandler, Middleware: auth, Private: true},
+		{Name: "/reminders", Description: "List and cancel your reminders", Handler: b.remindersHandler, Middleware: auth},
+		{Name: "/ask", Description: "Ask a one-off question outside your conversation", Handler: b.askHandler, Middleware: queued},
+		{Name: "/compare", Description: "Ask several models the same question and compare their answers", Handler: b.compareHandler, Middleware: queued},
+		{Name: "/batch", Description: "Ask a numbered list of questions at once, each answered on its own", Handler: b.batchHandler, Middleware: queued},
+		{Name: "/nocache", Description: "Ask for a fresh answer instead of a cached one", Handler: b.noCacheHandler, Middleware: queued},
+		{Name: "/summarize", Description: "Summarize a web page", Handler: b.summarizeHandler, Middleware: queued},
+		{Name: "/extract", Description: "Pull the dates, amounts and names out of some text", Handler: b.extractHandler, Middleware: queued},
+		{Name: "/translate", Description: "Translate the replied-to message into a language", Handler: b.translateHandler, Middleware: queued},
+		{Name: "/template", Description: "Save and reuse prompt templates", Handler: b.templateHandler, Middleware: queued},
+		{Name: "/chatprompt", Description: "Set instructions for this group (group admins)", Handler: b.chatPromptHandler, Middleware: auth},
+		{Name: "/imagine", Description: "Generate an image from a prompt", Handler: b.imagineHandler, Middleware: []tele.MiddlewareFunc{b.withAuth, b.withQueue}},
+		{Name: "/style", Description: "Pick how answers are written: plain, markdown, concise, verbose or eli5", Handler: b.styleHandler, Middleware: auth},
+		{Name: "/reasoning", Description: "Show or hide reasoning models' thinking, and set their effort", Handler: b.reasoningHandler, Middleware: auth},
+		{Name: "/sampling", Description: "Tune stop sequences, repetition penalties and the seed", Handler: b.samplingHandler, Middleware: auth},
+		{Name: "/code", Description: "Toggle code mode: tagged code blocks, long code as files", Handler: b.codeHandler, Middleware: auth},
+		{Name: "/speak", Description: "Toggle voice note replies", Handler: b.speakHandler, Middleware: auth},
+		{Name: "/tts", Description: "Read text or the replied-to message out loud", Handler: b.ttsHandler, Middleware: queued},
+		{Name: "/language", Description: "Change the language I reply in", Handler: b.languageHandler, Account: true},
+		{Name: "/plans", Description: "See your plan and buy a bigger one with Telegram Stars", Handler: b.plansHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/whoami", Description: "Show your account, model and quotas", Handler: b.whoamiHandler, Account: true},
+		{Name: "/retention", Description: "Choose how long your conversations are kept", Handler: b.retentionHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/purge", Description: "Delete all your conversations now, keeping your account", Handler: b.purgeHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/unlink", Description: "Delete your account and all your data", Handler: b.unlinkHandler, Middleware: auth, Private: true, Account: true},
+		{Name: "/cost", Description: "Estimated spend, everyone's too for admins", Handler: b.costHandler, Middleware: auth},
+		{Name: "/export_feedback", Description: "Download rated answers as a JSONL dataset (admin)", Handler: b.exportFeedbackHandler, Admin: true},
+		{Name: "/backup", Description: "Back up the database (admin)", Handler: b.backupHandler, Admin: true},
+		{Name: "/reload", Description: "Reload the configuration (admin)", Handler: b.reloadHandler, Admin: true},
+		{Name: "/violations", Description: "Review moderation violations (admin)", Handler: b.violationsHandler, Admin: true},
+		{Name: "/unthrottle", Description: "Lift a user's automatic cooldown (admin)", Handler: b.unthrottleHandler, Admin: true},
+		{Name: "/audit", Description: "Review a user's recent requests (admin)", Handler: b.auditHandler, Admin: true},
+		{Name: "/stats", Description: "Usage, latency and error stats (admin)", Handler: b.statsHandler, Admin: true},
+		{Name: "/diag", Description: "Check the Groq key, database and Telegram (admin)", Handler: b.diagHandler, Admin: true},
+		{Name: "/broadcast", Description: "Send an announcement to every user (admin)", Handler: b.broadcastHandler, Admin: true},
+		{Name: "/flags", Description: "Turn features on or off per user or for a share of users (admin)", Handler: b.flagsHandler, Admin: true},
+	}
+
+	b.tele.Use(b.middleware()...)
+
+	b.tele.Handle("/start", b.startHandler)
+	b.tele.Handle(&btnOnboardAuth, b.onboardAuthHandler)
+	b.tele.Handle(&btnOnboardSkip, b.onboardSkipHandler)
+	b.tele.Handle(&btnOnboardSetup, b.onboardSetupHandler, b.withAuth)
+	b.tele.Handle(&btnOnboardModel, b.onboardModelHandler, b.withAuth)
+	b.tele.Handle(&btnOnboardPersona, b.onboardPersonaHandler, b.withAuth)
+
+	for i, cmd := range commands {
+		commands[i].Translations = i18n.Translations(cmd.Description)
+		middleware := cmd.Middleware
+		if cmd.Admin {
+			middleware = append([]tele.MiddlewareFunc{b.withAdmin}, middleware...)
+		} else if !cmd.Account {
+			middleware = append([]tele.MiddlewareFunc{b.withFeature(strings.TrimPrefix(cmd.Name, "/"))}, middleware...)
+		}
+		b.tele.Handle(cmd.Name, cmd.Handler, middleware...)
+	}
+	b.commands = commands
+
+	b.tele.Handle(tele.OnText, b.textHandler, b.withPendingAuth, b.withCommandHints, b.withAuth, b.withForwards, b.withQuota, b.withQueue)
+	b.tele.Handle(&btnRegenerate, b.regenerateHandler, b.withAuth, b.withQuota, b.withQueue)
+	b.tele.Handle(&btnRate, b.rateHandler, b.withAuth)
+	b.tele.Handle(&btnFollowUp, b.followUpHandler, b.withAuth, b.withQuota, b.withQueue)
+	b.tele.Handle(&btnCancelReminder, b.cancelReminderHandler, b.withAuth)
+	b.tele.Handle(&btnStop, b.stopHandler, b.withAuth)
+	b.tele.Handle(&btnSwitchChat, b.switchChatHandler, b.withAuth)
+	b.tele.Handle(&btnShowPin, b.showPinHandler, b.withAuth)
+	b.tele.Handle(&btnJump, b.jumpHandler, b.withAuth)
+	b.tele.Handle(&btnUnpin, b.unpinHandler, b.withAuth)
+	b.tele.Handle(&btnUnlink, b.confirmUnlinkHandler, b.withAuth)
+	b.tele.Handle(&btnPurge, b.confirmPurgeHandler, b.withAuth)
+	b.tele.Handle(&btnEditPrompt, b.editPromptButtonHandler, b.withAuth)
+	b.tele.Handle(&btnImport, b.importPressHandler, b.withAuth)
+	b.tele.Handle(&btnBuyPlan, b.buyPlanHandler, b.withAuth)
+	b.tele.Handle(tele.OnCheckout, b.checkoutHandler)
+	b.tele.Handle(tele.OnPayment, b.paymentHandler)
+	b.tele.Handle(tele.OnEdited, b.editedHandler, b.withAuth, b.withQuota, b.withQueue)
+	b.tele.Handle(tele.OnDocument, b.documentHandler, b.withAuth, b.withImports, b.withQuota, b.withQueue)
+	b.tele.Handle(tele.OnSticker, b.stickerHandler, b.withAuth, b.withQuota, b.withQueue)
+}
+
+// Start runs the background jobs and the HTTP servers that are configured,
+// then polls Telegram until the process exits.
+func (b *Bot) Start() {
+	if b.cfg().MetricsAddr != "" {
+		startMetricsServer(b.cfg().MetricsAddr, func() float64 {
+			n, err := b.db.ActiveUsers(time.Now().Add(-24 * time.Hour))
+			if err != nil {
+				slog.Error("Could not count active users", "err", err)
+			}
+			return float64(n)
+		})
+	}
+	if b.cfg().HealthAddr != "" {
+		b.startHealthServer(b.cfg().HealthAddr)
+	}
+	if b.cfg().GatewayAddr != "" {
+		b.startGateway(b.cfg().GatewayAddr)
+	}
+	if b.cfg().ShareAddr != "" {
+		b.startShareServer(b.cfg().ShareAddr)
+	}
+	if b.cfg().AuditLog {
+		go b.pruneAuditLog()
+	}
+	go b.pruneUserData()
+	if b.cfg().SessionTTL > 0 {
+		go b.runSessionJanitor()
+	}
+	if b.cache != nil {
+		go b.cache.prune()
+	}
+	if b.traces != nil {
+		go b.traces.Run()
+	}
+	if b.s3 != nil && b.cfg().BackupInterval > 0 {
+		go b.runBackups()
+	}
+	go b.recoverJobs()
+	go b.runReminders()
+	go b.runDigests()
+	go b.runHeld()
+	go b.reloadOnHangup()
+	go b.selfCheck()
+
+	if err := b.publishCommands(); err != nil {
+		slog.Error("Could not set the command menu", "err", err)
+	}
+	b.tele.Poller = tele.NewMiddlewarePoller(b.tele.Poller, b.filterReactions)
+	b.tele.Start()
+}
@@ -0,0 +1,271 @@
+package bot_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/bot/bottest"
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm/llmtest"
+	"github.com/musaubrian/groqy/internal/store"
+)
+
+// step sends a message, or taps a button under the bot's last message, and
+// expects want in one of the replies.
+type step struct {
+	send string
+	// press is the button's unique, followed by |data for buttons with data.
+	press string
+	// under is the prompt whose answer has the button, the bot's last
+	// message when empty.
+	under string
+	want  string
+}
+
+var auth = step{send: "/auth " + bottest.AuthToken, want: "Authenticated"}
+
+func TestFlows(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+		steps     []step
+		// exchanges is how many exchanges are stored afterwards.
+		exchanges int
+		check     func(t *testing.T, db store.Store, exchanges []store.Exchange)
+	}{
+		{
+			name: "start",
+			steps: []step{
+				{send: "/start", want: "Hi Ada"},
+				{press: "onboard_auth", want: "Send me your token"},
+				{send: bottest.AuthToken, want: "You're in"},
+			},
+			check: func(t *testing.T, db store.Store, _ []store.Exchange) {
+				if _, err := db.GetUser(bottest.User.ID); err != nil {
+					t.Errorf("user wasn't stored: %v", err)
+				}
+			},
+		},
+		{
+			name:      "chat",
+			steps:     []step{auth, {send: "hello there", want: "hello there"}},
+			exchanges: 1,
+			check: func(t *testing.T, _ store.Store, exchanges []store.Exchange) {
+				if ex := exchanges[0]; ex.Prompt != "hello there" || ex.Response != "hello there" {
+					t.Errorf("stored %q answered %q, want the echo of hello there", ex.Prompt, ex.Response)
+				}
+			},
+		},
+		{
+			name:      "rate",
+			steps:     []step{auth, {send: "hello there", want: "hello there"}, {press: "rate|1"}},
+			exchanges: 1,
+			check: func(t *testing.T, _ store.Store, exchanges []store.Exchange) {
+				if exchanges[0].Feedback != 1 {
+					t.Errorf("feedback is %d, want 1 after 👍", exchanges[0].Feedback)
+				}
+			},
+		},
+		{
+			name: "regenerate",
+			steps: []step{
+				auth,
+				{send: "hello there", want: "hello there"},
+				{press: "rate|-1"},
+				{press: "regenerate", want: "hello there"},
+			},
+			exchanges: 1,
+			check: func(t *testing.T, _ store.Store, exchanges []store.Exchange) {
+				if exchanges[0].Feedback != 0 {
+					t.Errorf("feedback is %d, want the new answer unrated", exchanges[0].Feedback)
+				}
+			},
+		},
+		{
+			name: "regenerate an earlier answer",
+			steps: []step{
+				auth,
+				{send: "one", want: "one"},
+				{send: "two", want: "two"},
+				{press: "rate|1"},
+				{press: "regenerate", under: "one", want: "one"},
+			},
+			exchanges: 2,
+			check: func(t *testing.T, _ store.Store, exchanges []store.Exchange) {
+				if exchanges[1].Prompt != "two" || exchanges[1].Feedback != 1 {
+					t.Errorf("the latest exchange is %q rated %d, want two left as it was", exchanges[1].Prompt, exchanges[1].Feedback)
+				}
+			},
+		},
+		{
+			name: "purge",
+			steps: []step{
+				auth,
+				{send: "hello there", want: "hello there"},
+				{send: "/purge", want: "can't be undone"},
+				{press: "purge", want: "gone"},
+			},
+			check: func(t *testing.T, db store.Store, _ []store.Exchange) {
+				deletions, err := db.Deletions(bottest.User.ID)
+				if err != nil || len(deletions) != 1 || deletions[0].Reason != "purge" {
+					t.Errorf("deletions are %+v, %v, want the purge recorded", deletions, err)
+				}
+			},
+		},
+		{
+			name: "quota",
+			configure: func(c *config.Config) {
+				c.Plans = []config.Plan{{Name: "pro", Stars: 100, Days: 30}}
+				c.FreeMessageQuota = 2
+			},
+			steps: []step{
+				auth,
+				{send: "one", want: "one"},
+				{send: "two", want: "two"},
+				{send: "three", want: "You've used your 2 messages"},
+			},
+			exchanges: 2,
+		},
+		{
+			name: "quota outside chat",
+			configure: func(c *config.Config) {
+				c.Plans = []config.Plan{{Name: "pro", Stars: 100, Days: 30}}
+				c.FreeMessageQuota = 2
+			},
+			steps: []step{
+				auth,
+				{send: "/ask one", want: "one"},
+				{send: "/batch\n1. two\n2. three", want: "That takes 2 messages and you have 1 left"},
+				{send: "two", want: "two"},
+				{send: "/ask three", want: "You've used your 2 messages"},
+			},
+			exchanges: 1,
+		},
+		{
+			name: "quota after purge",
+			configure: func(c *config.Config) {
+				c.Plans = []config.Plan{{Name: "pro", Stars: 100, Days: 30}}
+				c.FreeMessageQuota = 2
+			},
+			steps: []step{
+				auth,
+				{send: "one", want: "one"},
+				{send: "/purge", want: "can't be undone"},
+				{press: "purge", want: "gone"},
+				{send: "two", want: "two"},
+				{send: "three", want: "You've used your 2 messages"},
+			},
+			exchanges: 1,
+		},
+		{
+			name:      "flags",
+			configure: func(c *config.Config) { c.Admins = []string{"ada"} },
+			steps: []step{
+				auth,
+				{send: "/ask one", want: "one"},
+				{send: "/flags ask off 42", want: "ask is off for 42"},
+				{send: "/ask two", want: "/ask isn't available to you yet"},
+				{send: "/flags whoami off", want: "No flag called whoami"},
+				{send: "/whoami", want: "Model:"},
+			},
+		},
+		{
+			name:      "gateway key",
+			configure: func(c *config.Config) { c.GatewayAddr = "localhost:0" },
+			steps: []step{
+				auth,
+				{send: "/gateway", want: "Usage: /gateway new"},
+				{send: "/gateway new", want: "groqy_"},
+				{send: "/gateway", want: "You have a gateway key"},
+				{send: "/gateway revoke", want: "Revoked"},
+				{send: "/gateway", want: "Usage: /gateway new"},
+			},
+		},
+		{
+			name: "reminder in the user's timezone",
+			steps: []step{
+				auth,
+				{send: "/quiet 11pm-7am Pacific/Kiritimati", want: "Quiet hours set"},
+				{send: `/remind 9am daily "news"`, want: "09:00 +14"},
+			},
+			check: func(t *testing.T, db store.Store, _ []store.Exchange) {
+				reminders, err := db.GetReminders(bottest.User.ID)
+				if err != nil || len(reminders) != 1 {
+					t.Fatalf("got %+v, %v, want the reminder", reminders, err)
+				}
+				zone, _ := time.LoadLocation("Pacific/Kiritimati")
+				if next := reminders[0].NextRun.In(zone); next.Hour() != 9 || next.Minute() != 0 {
+					t.Errorf("next run at %s, want 9am in Kiritimati", next)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configure []func(*config.Config)
+			if tt.configure != nil {
+				configure = append(configure, tt.configure)
+			}
+			h, err := bottest.New(llmtest.Echo, configure...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			for _, s := range tt.steps {
+				var replies []string
+				if s.press != "" {
+					unique, data, _ := strings.Cut(s.press, "|")
+					replies = h.Press(bottest.User, answerID(t, h, s.under), unique, data)
+				} else {
+					replies = h.Send(bottest.User, s.send)
+				}
+				if s.want != "" && !contains(replies, s.want) {
+					t.Fatalf("%s%s: got %q, want a reply with %q", s.send, s.press, replies, s.want)
+				}
+			}
+
+			exchanges, err := h.DB.AllExchanges(bottest.User.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(exchanges) != tt.exchanges {
+				t.Fatalf("%d exchanges stored, want %d", len(exchanges), tt.exchanges)
+			}
+			if tt.check != nil {
+				tt.check(t, h.DB, exchanges)
+			}
+		})
+	}
+}
+
+// answerID is the message the answer to prompt was sent as, or the bot's
+// last message when prompt is empty.
+func answerID(t *testing.T, h *bottest.Harness, prompt string) int {
+	t.Helper()
+	if prompt == "" {
+		return h.LastMessageID()
+	}
+	exchanges, err := h.DB.AllExchanges(bottest.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ex := range exchanges {
+		if ex.Prompt == prompt {
+			return ex.MessageID
+		}
+	}
+	t.Fatalf("no exchange for %q", prompt)
+	return 0
+}
+
+func contains(replies []string, want string) bool {
+	for _, r := range replies {
+		if strings.Contains(r, want) {
+			return true
+		}
+	}
+	return false
+}
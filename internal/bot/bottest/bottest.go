@@ -0,0 +1,173 @@
+// Package bottest runs a Bot end to end against a fake Telegram Bot API and
+// a fake Groq, so tests can send it updates and check what it replied and
+// stored:
+//
+//	h, err := bottest.New(llmtest.Echo)
+//	...
+//	defer h.Close()
+//	h.Send(bottest.User, "/auth "+bottest.AuthToken)
+//	replies := h.Send(bottest.User, "hello")
+//	exchanges, err := h.DB.AllExchanges(bottest.User.ID)
+package bottest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/bot"
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/llm/llmtest"
+	"github.com/musaubrian/groqy/internal/store"
+	"github.com/musaubrian/groqy/internal/telegramtest"
+	tele "gopkg.in/telebot.v3"
+)
+
+// AuthToken is the token /auth accepts.
+const AuthToken = "test-auth-token"
+
+// User is a Telegram user to send updates as.
+var User = tele.User{ID: 42, FirstName: "Ada", Username: "ada", LanguageCode: "en"}
+
+// Harness is a Bot wired to fake servers and an in-memory database.
+type Harness struct {
+	Bot      *bot.Bot
+	Tele     *tele.Bot
+	Telegram *telegramtest.Server
+	Groq     *httptest.Server
+	DB       store.Store
+
+	mu      sync.Mutex
+	updates int
+}
+
+// Config is the configuration New starts from: the defaults with the
+// tokens pointed at the fakes, whatever the environment says.
+func Config() config.Config {
+	return config.Config{
+		BotToken:  telegramtest.Token,
+		GroqToken: llmtest.Key,
+		AuthToken: AuthToken,
+		Provider:  "groq",
+
+		ContextWindow:      10,
+		SummarizeThreshold: 3000,
+		MaxConcurrency:     4,
+		DigestTime:         "21:00",
+		ProgressDelay:      5 * time.Second,
+		RateLimitQueue:     20,
+		JobRetryWindow:     time.Hour,
+		CacheSize:          1000,
+		LogFormat:          "text",
+		LogLevel:           "info",
+		TracingService:     "groqy",
+		AuditRetention:     30 * 24 * time.Hour,
+		Redaction:          "standard",
+		ImageQuota:         5,
+		SearchResults:      5,
+		SandboxTimeout:     20 * time.Second,
+		ModerationModel:    "llama-guard-3-8b",
+		AbuseCooldown:      5 * time.Minute,
+		AbuseBurst:         20,
+		AbuseRepeats:       5,
+		AbuseViolations:    3,
+		FreeMessageQuota:   20,
+	}
+}
+
+// New starts the fake servers and sets up a Bot whose completions are
+// reply(request). The config is Config's, configure can change it further.
+func New(reply func(llm.RequestBody) string, configure ...func(*config.Config)) (*Harness, error) {
+	h := &Harness{Telegram: telegramtest.NewServer(), Groq: llmtest.NewServer(reply)}
+
+	cfg := Config()
+	for _, f := range configure {
+		f(&cfg)
+	}
+
+	var err error
+	if h.DB, err = store.NewMemory(); err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.Tele, err = tele.NewBot(tele.Settings{
+		URL:         h.Telegram.URL,
+		Token:       telegramtest.Token,
+		Synchronous: true,
+		OnError:     func(error, tele.Context) {},
+	})
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("could not reach the fake Telegram: %v", err)
+	}
+	if h.Bot, err = bot.New(h.Tele, cfg, h.DB, llmtest.NewClient(h.Groq)); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Close stops the fake servers.
+func (h *Harness) Close() {
+	h.Telegram.Close()
+	h.Groq.Close()
+}
+
+// Send handles a private message from the user and returns the texts the
+// bot sent or edited while handling it.
+func (h *Harness) Send(from tele.User, text string) []string {
+	return h.process(func(id int) tele.Update {
+		return tele.Update{ID: id, Message: &tele.Message{
+			ID:       id,
+			Sender:   &from,
+			Chat:     &tele.Chat{ID: from.ID, Type: tele.ChatPrivate, Username: from.Username},
+			Text:     text,
+			Unixtime: time.Now().Unix(),
+		}}
+	})
+}
+
+// Press taps the inline button with unique and data under the bot's
+// message messageID, returning what the bot sent or edited. Buttons
+// without data take an empty data.
+func (h *Harness) Press(from tele.User, messageID int, unique, data string) []string {
+	if data != "" {
+		data = "|" + data
+	}
+	return h.process(func(id int) tele.Update {
+		return tele.Update{ID: id, Callback: &tele.Callback{
+			ID:     fmt.Sprint(id),
+			Sender: &from,
+			Message: &tele.Message{
+				ID:     messageID,
+				Sender: h.Tele.Me,
+				Chat:   &tele.Chat{ID: from.ID, Type: tele.ChatPrivate},
+			},
+			Data: "\f" + unique + data,
+		}}
+	})
+}
+
+// LastMessageID is the ID of the last message the bot sent.
+func (h *Harness) LastMessageID() int {
+	calls := h.Telegram.Calls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Method == "sendMessage" {
+			return calls[i].MessageID
+		}
+	}
+	return 0
+}
+
+func (h *Harness) process(update func(id int) tele.Update) []string {
+	h.mu.Lock()
+	h.updates++
+	id := h.updates
+	h.mu.Unlock()
+
+	before := len(h.Telegram.Calls())
+	h.Tele.ProcessUpdate(update(id))
+	return h.Telegram.Sent(before)
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// noCacheKey is set on the context of requests that skip the cache.
+	noCacheKey         = "nocache"
+	cachePruneInterval = time.Hour
+)
+
+var cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "groqy_cache_lookups_total",
+	Help: "Response cache lookups, by result (hit or miss).",
+}, []string{"result"})
+
+// responseCache keeps answers to prompts asked without any context, the
+// most recently used in memory and all of them in the store until they're
+// older than ttl.
+type responseCache struct {
+	db   store.Store
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newResponseCache(db store.Store, ttl time.Duration, size int) *responseCache {
+	return &responseCache{
+		db:      db,
+		ttl:     ttl,
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (rc *responseCache) get(hash string) (store.CachedResponse, bool) {
+	rc.mu.Lock()
+	if el, ok := rc.entries[hash]; ok {
+		r := el.Value.(store.CachedResponse)
+		if time.Since(r.CreatedAt) < rc.ttl {
+			rc.order.MoveToFront(el)
+			rc.mu.Unlock()
+			return r, true
+		}
+		rc.order.Remove(el)
+		delete(rc.entries, hash)
+	}
+	rc.mu.Unlock()
+
+	r, err := rc.db.GetCachedResponse(hash, time.Now().Add(-rc.ttl))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Could not load cached response", "err", err)
+		}
+		return r, false
+	}
+	rc.remember(r)
+	return r, true
+}
+
+// put caches res for hash, scrubbed at the redaction level as everything
+// else stored is.
+func (rc *responseCache) put(hash string, res llm.Completion, redaction string) {
+	r := store.CachedResponse{PromptHash: hash, Model: res.Model, Response: redact(redaction, res.Content), CreatedAt: time.Now()}
+	if err := rc.db.SaveCachedResponse(r); err != nil {
+		slog.Error("Could not cache response", "err", err)
+	}
+	rc.remember(r)
+}
+
+func (rc *responseCache) remember(r store.CachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.entries[r.PromptHash]; ok {
+		el.Value = r
+		rc.order.MoveToFront(el)
+		return
+	}
+	rc.entries[r.PromptHash] = rc.order.PushFront(r)
+	for rc.order.Len() > rc.size {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(store.CachedResponse).PromptHash)
+	}
+}
+
+// prune periodically deletes expired responses from the store.
+func (rc *responseCache) prune() {
+	for {
+		n, err := rc.db.DeleteCachedResponsesBefore(time.Now().Add(-rc.ttl))
+		if err != nil {
+			slog.Error("Could not prune response cache", "err", err)
+		} else if n > 0 {
+			slog.Info("Pruned response cache", "responses", n)
+		}
+		time.Sleep(cachePruneInterval)
+	}
+}
+
+// normalizePrompt makes prompts that only differ in case, spacing or
+// closing punctuation the same.
+func normalizePrompt(prompt string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(strings.ToLower(prompt)), " "), "?!. ")
+}
+
+// cacheKey returns the cache key for answering userMessage with messages,
+// empty when the answer can't come from the cache: caching is off, the
+// user asked to skip it, tuned the sampling with /sampling, or the prompt
+// carries context like history, memories or documents that makes the
+// answer theirs alone.
+func (b *Bot) cacheKey(c tele.Context, userMessage string, messages []llm.Message) string {
+	userID := c.Sender().ID
+	if b.cache == nil || c.Get(noCacheKey) != nil || len(b.samplingOptions(userID)) > 0 {
+		return ""
+	}
+	p := b.userPersona(userID)
+	generic := map[string]bool{b.instructions(userID): true, p.Instruct: true, languageInstruct(userMessage): true}
+	for _, m := range messages[:len(messages)-1] {
+		if m.Role != "system" || !generic[m.Content] {
+			return ""
+		}
+	}
+	if messages[len(messages)-1].Content != userMessage {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(b.workspace(userID).Name + "\x00" + b.userModel(userID) + "\x00" + b.reasoningEffort(userID) + "\x00" + p.Name + "\x00" + b.instructions(userID) + "\x00" + normalizePrompt(userMessage)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (b *Bot) noCacheHandler(c tele.Context) error {
+	prompt := strings.TrimSpace(c.Message().Payload)
+	if prompt == "" {
+		return c.Send(b.t(c, "Usage: /nocache <prompt>, to get a fresh answer instead of a cached one"))
+	}
+	c.Set(noCacheKey, true)
+	return b.chatHandler(c, prompt)
+}
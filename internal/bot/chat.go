@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+func (b *Bot) textHandler(c tele.Context) error {
+	if _, err := b.lookupUser(requestContext(c), c.Sender()); err != nil {
+		return c.Send(b.t(c, "Can't seem to find you ") + c.Sender().FirstName)
+	}
+
+	if _, editing := b.pendingEdits.LoadAndDelete(c.Sender().ID); editing {
+		return b.editPromptHandler(c, c.Text())
+	}
+	if isLink(c.Text()) {
+		return b.summarizeLink(c, strings.TrimSpace(c.Text()))
+	}
+
+	return b.chatHandler(c, c.Text())
+}
+
+func (b *Bot) chatHandler(tc tele.Context, userMessage string) error {
+	userID := tc.Sender().ID
+	chatID := b.activeChat(tc)
+
+	if !b.allowPrompt(tc, userMessage) {
+		return nil
+	}
+	b.expireSession(tc)
+
+	history, err := b.conversationContext(tc, chatID)
+	if err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		slog.ErrorContext(requestContext(tc), "Could not load conversation history", "err", err)
+	}
+
+	messages := b.buildMessages(tc, userMessage, history)
+	res, msg, err := b.streamAnswer(tc, userMessage, messages, b.cacheKey(tc, userMessage, messages))
+	if err != nil {
+		if msg == nil {
+			return err
+		}
+		return nil
+	}
+
+	ex := store.Exchange{
+		UserID:           userID,
+		ChatID:           chatID,
+		Prompt:           userMessage,
+		Response:         res.Content,
+		Model:            res.Model,
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		MessageID:        msg.ID,
+		PromptMessageID:  tc.Message().ID,
+	}
+	if len(history) > 0 {
+		ex.ParentID = history[len(history)-1].ID
+	}
+	if b.speaking(userID) {
+		b.sendVoice(tc, res.Content)
+	}
+
+	b.redactExchange(&ex)
+	if err := b.db.WithContext(requestContext(tc)).SaveExchange(&ex); err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		slog.ErrorContext(requestContext(tc), "Could not save exchange", "err", err)
+		return nil
+	}
+
+	ctx := requestContext(tc)
+	go func() {
+		if err := b.suggestFollowUps(ctx, tc, msg, ex); err != nil {
+			slog.ErrorContext(ctx, "Could not suggest follow-ups", "err", err)
+		}
+		if b.embedder != nil {
+			if err := b.remember(ex); err != nil {
+				slog.ErrorContext(ctx, "Could not store memory", "err", err)
+			}
+		}
+		if err := b.autoTitle(ctx, tc.Sender(), ex); err != nil {
+			slog.ErrorContext(ctx, "Could not title chat", "err", err)
+		}
+		if err := b.maybeSummarize(ctx, tc.Sender(), chatID); err != nil {
+			slog.ErrorContext(ctx, "Could not summarize conversation", "err", err)
+		}
+	}()
+	return nil
+}
+
+// conversationContext picks the exchanges to continue from. Replying to one
+// of the bot's earlier answers branches off from that answer's thread,
+// anything else continues the latest conversation in the chat. In groups
+// only replies carry context over, each reply thread on its own.
+func (b *Bot) conversationContext(c tele.Context, chatID string) ([]store.Exchange, error) {
+	window := b.cfg().ContextWindow
+	db := b.db.WithContext(requestContext(c))
+
+	if inGroup(c) {
+		_, ex, err := b.groupThread(c)
+		if err != nil || ex == nil {
+			return nil, err
+		}
+		return db.Thread(ex.ID, window)
+	}
+
+	if reply := replyTo(c.Message()); reply != nil && reply.Sender != nil && reply.Sender.ID == c.Bot().Me.ID {
+		ex, err := db.ExchangeByMessage(c.Sender().ID, reply.ID)
+		if err == nil {
+			return db.Thread(ex.ID, window)
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	return db.RecentExchanges(c.Sender().ID, chatID, window)
+}
+
+// contextMessages turns stored exchanges, oldest first, into chat messages.
+func contextMessages(exchanges []store.Exchange) []llm.Message {
+	messages := make([]llm.Message, 0, len(exchanges)*2)
+	for _, ex := range exchanges {
+		messages = append(messages,
+			llm.Message{Role: "user", Content: ex.Prompt},
+			llm.Message{Role: "assistant", Content: ex.Response},
+		)
+	}
+	return messages
+}
+
+// answer builds the full prompt for userMessage on top of history and sends
+// it to Groq.
+func (b *Bot) answer(tc tele.Context, userMessage string, history []store.Exchange, opts ...llm.Option) (llm.Completion, error) {
+	messages, _, err := b.fitPrompt(tc, b.buildMessages(tc, userMessage, history))
+	if err != nil {
+		return llm.Completion{}, err
+	}
+	opts = append(b.modelOptions(tc.Sender().ID), opts...)
+	return b.complete(tc, userMessage, func(apiKey string) (llm.Completion, error) {
+		return b.llm.Complete(requestContext(tc), apiKey, messages, opts...)
+	})
+}
+
+// buildMessages assembles the system instructions, recalled context and
+// history for userMessage.
+func (b *Bot) buildMessages(tc tele.Context, userMessage string, history []store.Exchange) []llm.Message {
+	userID := tc.Sender().ID
+
+	messages := []llm.Message{{Role: "system", Content: b.instructions(userID)}}
+	if prompt := b.chatPrompt(tc); prompt != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: prompt})
+	}
+	if p := b.userPersona(userID); p.Instruct != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: p.Instruct})
+	}
+	if instruct := languageInstruct(userMessage); instruct != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: instruct})
+	}
+
+	if summary, err := b.db.GetSummary(userID, b.activeChat(tc)); err == nil {
+		messages = append(messages, llm.Message{Role: "system", Content: summaryPrefix + summary.Content})
+	} else if err != sql.ErrNoRows {
+		slog.ErrorContext(requestContext(tc), "Could not load conversation summary", "err", err)
+	}
+
+	if b.embedder != nil {
+		memories, err := b.recall(userID, userMessage, history)
+		if err != nil {
+			errorsTotal.WithLabelValues("embeddings").Inc()
+			slog.ErrorContext(requestContext(tc), "Could not recall memories", "err", err)
+		}
+		if memories != "" {
+			messages = append(messages, llm.Message{Role: "system", Content: memories})
+		}
+	}
+	messages = append(messages, contextMessages(history)...)
+
+	docContext, err := b.documentContext(userID, userMessage)
+	if err != nil {
+		slog.ErrorContext(requestContext(tc), "Could not load document context", "err", err)
+	}
+	return append(messages, llm.Message{Role: "user", Content: docContext + userMessage + b.quotedContext(tc)})
+}
+
+// complete resolves the sender's Groq key, runs call with it and records
+// metrics and the audit entry for the request. Calls on the shared key that
+// get rate limited back the whole bot off and retry from the queue.
+func (b *Bot) complete(tc tele.Context, userMessage string, call func(apiKey string) (llm.Completion, error)) (llm.Completion, error) {
+	apiKey, err := b.groqKeyFor(tc.Sender())
+	if err != nil {
+		return llm.Completion{}, err
+	}
+
+	stopProgress := func() {}
+	if tc.Get(progressKey) == nil {
+		stopProgress = b.whileSlow(tc, nil, nil)
+	}
+	start := time.Now()
+	res, err := call(apiKey)
+	var limited *llm.RateLimitError
+	for retries := 0; errors.As(err, &limited) && apiKey == b.cfg().GroqToken && retries < maxRateLimitRetries; retries++ {
+		b.gate.Backoff(limited.RetryAfter)
+		if err := b.gate.Wait(b.queuedNotice(tc)); err != nil {
+			break
+		}
+		res, err = call(apiKey)
+	}
+	stopProgress()
+	b.audit(tc.Sender(), userMessage, res, time.Since(start), err)
+	if err != nil {
+		errorsTotal.WithLabelValues("groq").Inc()
+		slog.ErrorContext(requestContext(tc), "Groq request failed", "err", err)
+		return res, err
+	}
+	b.recordRequest(tc.Sender(), res, time.Since(start))
+	return res, nil
+}
+
+// errorReply turns an error from answer into something to tell the user in
+// their language.
+func errorReply(lang string, err error) string {
+	var limited *llm.RateLimitError
+	switch {
+	case errors.Is(err, errNoAPIKey):
+		return tr(lang, "You need your own Groq key to use this bot, set it with /apikey <key>")
+	case errors.Is(err, llm.ErrInvalidKey):
+		return tr(lang, "Groq rejected the API key, check it with /apikey")
+	case errors.Is(err, llm.ErrModelNotFound):
+		return tr(lang, "That model isn't available on Groq right now")
+	case errors.Is(err, llm.ErrUnavailable):
+		return tr(lang, "Groq is having trouble right now, try again in a bit")
+	case errors.As(err, &limited):
+		return tr(lang, "Groq is rate limited, try again in %s", limited.RetryAfter.Round(time.Second))
+	case errors.Is(err, errBudgetSpent):
+		return tr(lang, "Your workspace has spent its budget for today, try again tomorrow")
+	case errors.Is(err, errPromptTooLarge):
+		return tr(lang, "That's too long for the model even on its own, try something shorter")
+	case errors.Is(err, llm.ErrBadRequest):
+		var apiErr *llm.APIError
+		if errors.As(err, &apiErr) {
+			return tr(lang, "Groq couldn't handle that request: ") + apiErr.Message
+		}
+		return tr(lang, "Groq couldn't handle that request: ") + err.Error()
+	}
+	return tr(lang, "An error occured")
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"database/sql"
+	"log/slog"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxChatPromptLength = 2000
+
+const chatPromptUsage = `Usage, in a group:
+/chatprompt set <instructions> (group admins)
+/chatprompt show
+/chatprompt clear (group admins)
+
+The instructions are given to the model for every answer in this group. In a forum topic, they're the topic's, followed on top of the group's.`
+
+func (b *Bot) chatPromptHandler(c tele.Context) error {
+	if !inGroup(c) {
+		return c.Send(b.t(c, "/chatprompt sets instructions for a group, use it there"))
+	}
+	sub, text := cutWord(c.Message().Payload)
+	if thread := topicID(c); thread != 0 {
+		return b.topicPromptHandler(c, sub, text, thread)
+	}
+	chatID := c.Chat().ID
+
+	switch sub {
+	case "show":
+		prompt, err := b.db.GetChatPrompt(chatID)
+		if err == sql.ErrNoRows {
+			return c.Send(b.t(c, "This group has no instructions, a group admin can set them with /chatprompt set <instructions>"))
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load the group's instructions: ") + err.Error())
+		}
+		return c.Send(prompt)
+	case "set", "clear":
+	default:
+		return c.Send(b.t(c, chatPromptUsage))
+	}
+
+	admin, err := b.isGroupAdmin(c)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not check who the group admins are: ") + err.Error())
+	}
+	if !admin {
+		return c.Send(b.t(c, "Only group admins can change the group's instructions"))
+	}
+
+	if sub == "clear" {
+		deleted, err := b.db.DeleteChatPrompt(chatID)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not clear the group's instructions: ") + err.Error())
+		}
+		if !deleted {
+			return c.Send(b.t(c, "This group has no instructions to clear"))
+		}
+		return c.Send(b.t(c, "Cleared the group's instructions"))
+	}
+
+	if text == "" {
+		return c.Send(b.t(c, chatPromptUsage))
+	}
+	if len([]rune(text)) > maxChatPromptLength {
+		return c.Send(b.t(c, "Group instructions can be up to %d characters", maxChatPromptLength))
+	}
+	if err := b.db.SetChatPrompt(chatID, text, c.Sender().ID); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save the group's instructions: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Saved, I'll follow these instructions in this group"))
+}
+
+// topicPromptHandler is /chatprompt in a forum topic, acting on the topic's
+// instructions.
+func (b *Bot) topicPromptHandler(c tele.Context, sub, text string, thread int) error {
+	chatID := c.Chat().ID
+	switch sub {
+	case "show":
+		prompt, err := b.db.GetTopicPrompt(chatID, thread)
+		if err == sql.ErrNoRows {
+			return c.Send(b.t(c, "This topic has no instructions, a group admin can set them with /chatprompt set <instructions>"))
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load the topic's instructions: ") + err.Error())
+		}
+		return c.Send(prompt)
+	case "set", "clear":
+	default:
+		return c.Send(b.t(c, chatPromptUsage))
+	}
+
+	admin, err := b.isGroupAdmin(c)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not check who the group admins are: ") + err.Error())
+	}
+	if !admin {
+		return c.Send(b.t(c, "Only group admins can change the topic's instructions"))
+	}
+
+	if sub == "clear" {
+		deleted, err := b.db.DeleteTopicPrompt(chatID, thread)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not clear the topic's instructions: ") + err.Error())
+		}
+		if !deleted {
+			return c.Send(b.t(c, "This topic has no instructions to clear"))
+		}
+		return c.Send(b.t(c, "Cleared the topic's instructions"))
+	}
+
+	if text == "" {
+		return c.Send(b.t(c, chatPromptUsage))
+	}
+	if len([]rune(text)) > maxChatPromptLength {
+		return c.Send(b.t(c, "Group instructions can be up to %d characters", maxChatPromptLength))
+	}
+	if err := b.db.SetTopicPrompt(chatID, thread, text, c.Sender().ID); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save the topic's instructions: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Saved, I'll follow these instructions in this topic"))
+}
+
+// isGroupAdmin reports whether the sender administers the group, bot
+// admins counting everywhere.
+func (b *Bot) isGroupAdmin(c tele.Context) (bool, error) {
+	if b.isAdmin(c) {
+		return true, nil
+	}
+	member, err := c.Bot().ChatMemberOf(c.Chat(), c.Sender())
+	if err != nil {
+		return false, err
+	}
+	return member.Role == tele.Administrator || member.Role == tele.Creator, nil
+}
+
+// chatPrompt is the system prompt set for the group c is in, followed by
+// the forum topic's, "" outside groups and in groups without one.
+func (b *Bot) chatPrompt(c tele.Context) string {
+	if !inGroup(c) {
+		return ""
+	}
+	prompt, err := b.db.GetChatPrompt(c.Chat().ID)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(requestContext(c), "Could not load the group's instructions", "err", err)
+	}
+	thread := topicID(c)
+	if thread == 0 {
+		return prompt
+	}
+	topic, err := b.db.GetTopicPrompt(c.Chat().ID, thread)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(requestContext(c), "Could not load the topic's instructions", "err", err)
+	}
+	if prompt != "" && topic != "" {
+		return prompt + "\n\n" + topic
+	}
+	return prompt + topic
+}
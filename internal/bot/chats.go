@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	chatPreference = "chat"
+	// mainChat is the data of the main chat's button, its ID is empty.
+	mainChat      = "main"
+	maxTitleLen   = 60
+	titleInstruct = "Write a title of at most six words for a conversation that starts with the message below. Reply with the title only, no quotes or punctuation at the end."
+)
+
+var btnSwitchChat = tele.Btn{Unique: "switch_chat"}
+
+// activeChat returns the ID of the chat the user is talking in, empty for
+// their main chat. In groups it's the reply thread instead.
+func (b *Bot) activeChat(c tele.Context) string {
+	if inGroup(c) {
+		id, _, err := b.groupThread(c)
+		if err != nil {
+			slog.ErrorContext(requestContext(c), "Could not find reply thread", "err", err)
+		}
+		return id
+	}
+	id, err := b.db.GetPreference(c.Sender().ID, chatPreference)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(requestContext(c), "Could not load active chat", "err", err)
+	}
+	return id
+}
+
+func (b *Bot) newChatHandler(c tele.Context) error {
+	title := truncate(strings.TrimSpace(c.Message().Payload), maxTitleLen)
+	chat, err := b.db.CreateChat(c.Sender().ID, title)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not create the chat: ") + err.Error())
+	}
+	if err := b.db.SetPreference(c.Sender().ID, chatPreference, chat.ID); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not switch to the chat: ") + err.Error())
+	}
+	if title == "" {
+		return c.Send(b.t(c, "Started a new chat, it gets a title from your first message.\nSee /chats to switch back"))
+	}
+	return c.Send(b.t(c, "Started %q.\nSee /chats to switch back", title))
+}
+
+func (b *Bot) chatsHandler(c tele.Context) error {
+	text, menu, err := b.chatsList(c)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your chats: ") + err.Error())
+	}
+	return c.Send(text, menu)
+}
+
+func (b *Bot) switchChatHandler(c tele.Context) error {
+	id := c.Callback().Data
+	if id == mainChat {
+		id = ""
+	} else if _, err := b.db.GetChat(c.Sender().ID, id); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That chat is gone")})
+	}
+	if err := b.db.SetPreference(c.Sender().ID, chatPreference, id); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not switch chats")})
+	}
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Switched")})
+
+	text, menu, err := b.chatsList(c)
+	if err != nil {
+		return err
+	}
+	return c.Edit(text, menu)
+}
+
+func (b *Bot) chatsList(c tele.Context) (string, *tele.ReplyMarkup, error) {
+	chats, err := b.db.GetChats(c.Sender().ID)
+	if err != nil {
+		return "", nil, err
+	}
+	active := b.activeChat(c)
+
+	menu := &tele.ReplyMarkup{}
+	label := func(id, title string) string {
+		if id == active {
+			return "✅ " + title
+		}
+		return title
+	}
+	rows := []tele.Row{menu.Row(menu.Data(label("", b.t(c, "Main chat")), btnSwitchChat.Unique, mainChat))}
+	for _, chat := range chats {
+		title := chat.Title
+		if title == "" {
+			title = b.t(c, "Untitled %s", chat.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		rows = append(rows, menu.Row(menu.Data(label(chat.ID, title), btnSwitchChat.Unique, chat.ID)))
+	}
+	menu.Inline(rows...)
+	return b.t(c, "Your chats, tap one to switch.\nStart another with /new [title]"), menu, nil
+}
+
+// autoTitle names an untitled chat after the first exchange in it.
+func (b *Bot) autoTitle(ctx context.Context, user *tele.User, ex store.Exchange) error {
+	if ex.ChatID == "" || ex.ParentID != "" {
+		return nil
+	}
+	chat, err := b.db.GetChat(user.ID, ex.ChatID)
+	if err == sql.ErrNoRows {
+		// Group reply threads aren't chats of their own.
+		return nil
+	}
+	if err != nil || chat.Title != "" {
+		return err
+	}
+
+	apiKey, err := b.groqKeyFor(user)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	res, err := b.llm.Complete(ctx, apiKey, []llm.Message{
+		{Role: "system", Content: titleInstruct},
+		{Role: "user", Content: truncate(ex.Prompt, 500)},
+	})
+	if err != nil {
+		return err
+	}
+	b.recordUsage(user, res, time.Since(start))
+
+	title := truncate(strings.Trim(strings.TrimSpace(res.Content), `"'.`), maxTitleLen)
+	if title == "" {
+		return nil
+	}
+	return b.db.SetChatTitle(chat.ID, title)
+}
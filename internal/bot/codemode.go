@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	codePreference = "code"
+	// maxInlineCode is how long a code block may be before it's sent as a
+	// file, and maxCodeAnswer how long the whole answer may be before all
+	// of them are.
+	maxInlineCode = 3000
+	maxCodeAnswer = 4000
+)
+
+// codeStyle replaces the format style in /code mode.
+var codeStyle = style{Name: "code", Group: "format", Instruct: "Put all code in fenced code blocks tagged with their language, e.g. ```go or ```python, and give complete, runnable code rather than fragments. Keep the explanation around it short, formatted with Telegram's Markdown only: *bold*, _italic_ and `inline code`."}
+
+// codeBlockRe matches a fenced code block and its language tag.
+var codeBlockRe = regexp.MustCompile("(?s)```([\\w+#.-]*)[ \\t]*\\n(.*?)```")
+
+// codeExtensions are the file extensions of the languages models tag code
+// blocks with.
+var codeExtensions = map[string]string{
+	"go": "go", "golang": "go", "python": "py", "py": "py", "javascript": "js", "js": "js",
+	"typescript": "ts", "ts": "ts", "rust": "rs", "rs": "rs", "java": "java", "kotlin": "kt",
+	"swift": "swift", "c": "c", "cpp": "cpp", "c++": "cpp", "csharp": "cs", "c#": "cs", "cs": "cs",
+	"ruby": "rb", "rb": "rb", "php": "php", "bash": "sh", "sh": "sh", "shell": "sh", "zsh": "sh",
+	"sql": "sql", "html": "html", "css": "css", "json": "json", "yaml": "yaml", "yml": "yaml",
+	"toml": "toml", "dockerfile": "dockerfile", "lua": "lua", "haskell": "hs", "elixir": "ex",
+}
+
+// codeMode reports whether the user's answers are written as code.
+func (b *Bot) codeMode(userID int64) bool {
+	value, _ := b.db.GetPreference(userID, codePreference)
+	return value == "on"
+}
+
+// codeHandler toggles /code mode.
+func (b *Bot) codeHandler(c tele.Context) error {
+	on := !b.codeMode(c.Sender().ID)
+	value := "off"
+	if on {
+		value = "on"
+	}
+	if err := b.db.SetPreference(c.Sender().ID, codePreference, value); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	if on {
+		return c.Send(b.t(c, "Code mode on: code comes in tagged code blocks, long files as attachments. /code again to go back to your style"))
+	}
+	return c.Send(b.t(c, "Code mode off, back to your style"))
+}
+
+// codeFile is a code block sent as an attachment.
+type codeFile struct {
+	name string
+	code string
+}
+
+// codeAttachments takes the code blocks too long for a message out of
+// answer, leaving a note where each was, and returns them as files. When
+// the answer is too long with all of them in, every block goes.
+func (b *Bot) codeAttachments(c tele.Context, answer string) (string, []codeFile) {
+	all := len([]rune(answer)) > maxCodeAnswer
+	var files []codeFile
+	answer = codeBlockRe.ReplaceAllStringFunc(answer, func(block string) string {
+		m := codeBlockRe.FindStringSubmatch(block)
+		if !all && len([]rune(m[2])) <= maxInlineCode {
+			return block
+		}
+		name := "code"
+		if len(files) > 0 {
+			name = fmt.Sprintf("code-%d", len(files)+1)
+		}
+		name += "." + codeExtension(m[1], m[2])
+		files = append(files, codeFile{name: name, code: m[2]})
+		return b.t(c, "📎 %s, sent as a file", name)
+	})
+	return answer, files
+}
+
+// codeExtension is the file extension for a block tagged lang, guessed from
+// the code itself when it isn't tagged.
+func codeExtension(lang, code string) string {
+	if ext, ok := codeExtensions[strings.ToLower(lang)]; ok {
+		return ext
+	}
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(code), "package "):
+		return "go"
+	case strings.HasPrefix(code, "#!/bin/"):
+		return "sh"
+	case strings.Contains(code, "def ") && strings.Contains(code, ":\n"):
+		return "py"
+	case strings.Contains(code, "fn main()"):
+		return "rs"
+	}
+	return "txt"
+}
+
+// sendCodeFiles sends the blocks codeAttachments took out of an answer.
+func (b *Bot) sendCodeFiles(c tele.Context, files []codeFile) {
+	for _, f := range files {
+		doc := &tele.Document{File: tele.FromReader(bytes.NewReader([]byte(f.code))), FileName: f.name}
+		if _, err := send(c, doc); err != nil {
+			slog.ErrorContext(requestContext(c), "Could not send code file", "file", f.name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	maxCompared = 3
+	// maxComparedAnswer leaves room in a message for the label.
+	maxComparedAnswer = 3800
+)
+
+// comparison is one model's answer in /compare.
+type comparison struct {
+	model   string
+	res     llm.Completion
+	elapsed time.Duration
+	err     error
+}
+
+// compareModels are the models /compare asks, COMPARE_MODELS or the ones
+// users can pick, less those the user's plan doesn't have.
+func (b *Bot) compareModels(userID int64) []string {
+	candidates := b.cfg().CompareModels
+	if len(candidates) == 0 {
+		candidates = models
+	}
+	var picked []string
+	for _, model := range candidates {
+		if b.modelAllowed(userID, model) && !slices.Contains(picked, model) {
+			picked = append(picked, model)
+		}
+		if len(picked) == maxCompared {
+			break
+		}
+	}
+	return picked
+}
+
+// compareHandler asks the compared models the same question at once and
+// replies with each answer, its latency, tokens and cost.
+func (b *Bot) compareHandler(c tele.Context) error {
+	prompt := strings.TrimSpace(c.Message().Payload)
+	if prompt == "" {
+		return c.Send(b.t(c, "Usage: /compare <prompt>, answered by several models side by side"))
+	}
+	compared := b.compareModels(c.Sender().ID)
+	if len(compared) < 2 {
+		return c.Send(b.t(c, "There aren't two models you can use to compare"))
+	}
+	if !b.fitsQuota(c, len(compared)) {
+		return nil
+	}
+	if !b.allowPrompt(c, prompt) {
+		return nil
+	}
+	apiKey, err := b.groqKeyFor(c.Sender())
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+
+	messages := []llm.Message{{Role: "system", Content: b.instructions(c.Sender().ID)}}
+	if instruct := languageInstruct(prompt); instruct != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: instruct})
+	}
+	messages = append(messages, llm.Message{Role: "user", Content: prompt})
+
+	c.Notify(tele.Typing)
+	stopProgress := b.whileSlow(c, nil, nil)
+	results := make([]comparison, len(compared))
+	var wg sync.WaitGroup
+	for i, model := range compared {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			res, err := b.llm.Complete(requestContext(c), apiKey, messages, append(b.samplingOptions(c.Sender().ID), llm.WithModel(model), llm.WithReasoning(b.reasoningEffort(c.Sender().ID)))...)
+			results[i] = comparison{model: model, res: res, elapsed: time.Since(start), err: err}
+		}()
+	}
+	wg.Wait()
+	stopProgress()
+
+	for _, r := range results {
+		b.audit(c.Sender(), "/compare "+r.model+": "+prompt, r.res, r.elapsed, r.err)
+		if r.err != nil {
+			errorsTotal.WithLabelValues("groq").Inc()
+			c.Send(fmt.Sprintf("🤖 %s\n\n%s", r.model, errorReply(b.lang(c), r.err)))
+			continue
+		}
+		b.recordRequest(c.Sender(), r.res, r.elapsed)
+		c.Send(b.comparisonLabel(c, r) + "\n\n" + truncate(r.res.Content, maxComparedAnswer))
+	}
+	return nil
+}
+
+// comparisonLabel heads an answer with the model, and the one that stood
+// in for it when a fallback answered.
+func (b *Bot) comparisonLabel(c tele.Context, r comparison) string {
+	label := "🤖 " + r.model
+	if r.res.Fallback {
+		label += b.t(c, " (answered by %s)", r.res.Model)
+	}
+	label += b.t(c, "\n%.1fs, %d prompt + %d completion tokens", r.elapsed.Seconds(), r.res.PromptTokens, r.res.CompletionTokens)
+	if price, ok := b.prices[r.res.Model]; ok {
+		label += fmt.Sprintf(", $%.5f", price.Cost(r.res.PromptTokens, r.res.CompletionTokens))
+	}
+	return label
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const topSpendersShown = 5
+
+// budgetAlert remembers the day the budget alert last went out, so it is
+// sent once a day.
+type budgetAlert struct {
+	mu  sync.Mutex
+	day string
+}
+
+// recordRequest accounts for a completion the user asked for, which also
+// counts toward their daily message quota.
+func (b *Bot) recordRequest(user *tele.User, res llm.Completion, elapsed time.Duration) {
+	b.saveUsage(user, res, elapsed, true)
+}
+
+// recordUsage accounts for the tokens of a completion the bot made on its
+// own, like moderation or titles.
+func (b *Bot) recordUsage(user *tele.User, res llm.Completion, elapsed time.Duration) {
+	b.saveUsage(user, res, elapsed, false)
+}
+
+func (b *Bot) saveUsage(user *tele.User, res llm.Completion, elapsed time.Duration, request bool) {
+	observeCompletion(res, elapsed)
+
+	err := b.db.SaveUsage(store.Usage{
+		UserID:           user.ID,
+		Username:         user.Username,
+		Workspace:        b.workspace(user.ID).Name,
+		Model:            res.Model,
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		Request:          request,
+	})
+	if err != nil {
+		slog.Error("Could not record usage", "err", err)
+		return
+	}
+	b.checkBudget()
+}
+
+func (b *Bot) cost(t store.UsageTotal) (float64, bool) {
+	price, ok := b.prices[t.Model]
+	return price.Cost(t.PromptTokens, t.CompletionTokens), ok
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// spend adds up the cost of totals, for one user unless userID is zero.
+// Models without a price are listed rather than guessed at.
+func (b *Bot) spend(totals []store.UsageTotal, userID int64) (float64, []string) {
+	sum := 0.0
+	unpriced := map[string]bool{}
+	for _, t := range totals {
+		if userID != 0 && t.UserID != userID {
+			continue
+		}
+		c, ok := b.cost(t)
+		if !ok {
+			unpriced[t.Model] = true
+		}
+		sum += c
+	}
+	var models []string
+	for m := range unpriced {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return sum, models
+}
+
+// checkBudget alerts the admin chat the first time the day's spend goes over
+// DAILY_BUDGET.
+func (b *Bot) checkBudget() {
+	if b.cfg().DailyBudget <= 0 || b.cfg().AlertChatID == 0 {
+		return
+	}
+
+	today := time.Now().Format(time.DateOnly)
+	b.alert.mu.Lock()
+	defer b.alert.mu.Unlock()
+	if b.alert.day == today {
+		return
+	}
+
+	totals, err := b.db.UsageTotals(startOfDay(time.Now()))
+	if err != nil {
+		slog.Error("Could not load usage", "err", err)
+		return
+	}
+	spent, _ := b.spend(totals, 0)
+	if spent < b.cfg().DailyBudget {
+		return
+	}
+
+	b.alert.day = today
+	text := fmt.Sprintf("⚠️ Spend today is $%.2f, over the daily budget of $%.2f", spent, b.cfg().DailyBudget)
+	if _, err := b.tele.Send(&tele.Chat{ID: b.cfg().AlertChatID}, text); err != nil {
+		slog.Error("Could not send budget alert", "err", err)
+	}
+}
+
+func (b *Bot) costHandler(c tele.Context) error {
+	now := time.Now()
+	month, err := b.db.UsageTotals(now.AddDate(0, 0, -30))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load usage: ") + err.Error())
+	}
+	today, err := b.db.UsageTotals(startOfDay(now))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load usage: ") + err.Error())
+	}
+
+	userID := c.Sender().ID
+	mineToday, _ := b.spend(today, userID)
+	mineMonth, unpriced := b.spend(month, userID)
+
+	var sb strings.Builder
+	sb.WriteString(b.t(c, "Your estimated spend: $%.4f today, $%.4f in the last 30 days", mineToday, mineMonth) + "\n")
+
+	if b.isAdmin(c) {
+		allToday, _ := b.spend(today, 0)
+		allMonth, allUnpriced := b.spend(month, 0)
+		unpriced = allUnpriced
+		sb.WriteString("\n" + b.t(c, "Everyone: $%.4f today, $%.4f in the last 30 days", allToday, allMonth) + "\n")
+		if b.cfg().DailyBudget > 0 {
+			sb.WriteString(b.t(c, "Daily budget: $%.2f (%.0f%% used)", b.cfg().DailyBudget, 100*allToday/b.cfg().DailyBudget) + "\n")
+		}
+
+		perUser := map[string]float64{}
+		for _, t := range month {
+			cost, _ := b.cost(t)
+			perUser[t.Username] += cost
+		}
+		users := make([]string, 0, len(perUser))
+		for u := range perUser {
+			users = append(users, u)
+		}
+		sort.Slice(users, func(i, j int) bool { return perUser[users[i]] > perUser[users[j]] })
+		if len(users) > 0 {
+			sb.WriteString("\n" + b.t(c, "Top spenders, last 30 days:") + "\n")
+		}
+		for i := 0; i < len(users) && i < topSpendersShown; i++ {
+			sb.WriteString(fmt.Sprintf("%d. @%s  $%.4f\n", i+1, users[i], perUser[users[i]]))
+		}
+
+		if len(b.cfg().Workspaces) > 0 {
+			sb.WriteString("\n" + b.t(c, "Workspaces, today / last 30 days:") + "\n")
+		}
+		for _, ws := range b.cfg().Workspaces {
+			sb.WriteString(fmt.Sprintf("%s  $%.4f / $%.4f", ws.Name, b.workspaceSpend(today, ws.Name), b.workspaceSpend(month, ws.Name)))
+			if ws.DailyBudget > 0 {
+				sb.WriteString("  " + b.t(c, "(budget $%.2f a day)", ws.DailyBudget))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(unpriced) > 0 && b.isAdmin(c) {
+		sb.WriteString("\n" + b.t(c, "No price known for %s, set MODEL_PRICES to include them", strings.Join(unpriced, ", ")))
+	} else if len(unpriced) > 0 {
+		sb.WriteString("\n" + b.t(c, "No price known for %s, so it isn't counted", strings.Join(unpriced, ", ")))
+	}
+	return c.Send(sb.String())
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// diagnostic is the result of one of the self-checks.
+type diagnostic struct {
+	// Name is the key of the check in /readyz, Label how /diag shows it.
+	Name  string
+	Label string
+	// Detail says what was found when the check passed.
+	Detail string
+	Err    error
+	Took   time.Duration
+}
+
+// diagnose checks that the Groq key is accepted, the database schema is up
+// to date and Telegram can be reached.
+func (b *Bot) diagnose(ctx context.Context) []diagnostic {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	return []diagnostic{
+		timed("db", "Database", func() (string, error) { return b.checkDatabase(ctx) }),
+		timed("telegram", "Telegram", b.checkTelegram),
+		timed("groq", "Groq", func() (string, error) { return b.checkGroq(ctx) }),
+	}
+}
+
+func timed(name, label string, check func() (string, error)) diagnostic {
+	start := time.Now()
+	detail, err := check()
+	return diagnostic{Name: name, Label: label, Detail: detail, Err: err, Took: time.Since(start)}
+}
+
+func (b *Bot) checkDatabase(ctx context.Context) (string, error) {
+	if err := b.db.Ping(ctx); err != nil {
+		return "", err
+	}
+	applied, latest, err := b.db.SchemaVersion()
+	if err != nil {
+		return "", fmt.Errorf("could not read the schema version: %v", err)
+	}
+	if applied < latest {
+		return "", fmt.Errorf("schema at version %d of %d, the migrations didn't run", applied, latest)
+	}
+	if applied > latest {
+		return "", fmt.Errorf("schema at version %d, newer than this build's %d", applied, latest)
+	}
+	return fmt.Sprintf("schema version %d", applied), nil
+}
+
+func (b *Bot) checkTelegram() (string, error) {
+	if _, err := b.tele.Raw("getMe", nil); err != nil {
+		return "", err
+	}
+	return "@" + b.tele.Me.Username, nil
+}
+
+func (b *Bot) checkGroq(ctx context.Context) (string, error) {
+	key := b.cfg().GroqToken
+	if key == "" {
+		return "no GROQ_TOKEN, users need their own keys", nil
+	}
+	if err := b.llm.CheckKey(ctx, key); err != nil {
+		return "", err
+	}
+	return "key accepted", nil
+}
+
+// selfCheck logs the diagnostics when the bot starts.
+func (b *Bot) selfCheck() {
+	failed := 0
+	for _, d := range b.diagnose(context.Background()) {
+		if d.Err != nil {
+			failed++
+			slog.Error("Self-check failed", "check", d.Name, "took", d.Took, "err", d.Err)
+			continue
+		}
+		slog.Info("Self-check passed", "check", d.Name, "detail", d.Detail, "took", d.Took)
+	}
+	if failed > 0 {
+		slog.Warn("Self-check found problems, /diag runs it again", "failed", failed)
+	}
+}
+
+// diagHandler runs the self-checks again and reports them.
+func (b *Bot) diagHandler(c tele.Context) error {
+	c.Notify(tele.Typing)
+	var sb strings.Builder
+	for _, d := range b.diagnose(requestContext(c)) {
+		if d.Err != nil {
+			sb.WriteString(fmt.Sprintf("❌ %s: %v (%s)\n", b.t(c, d.Label), d.Err, d.Took.Round(time.Millisecond)))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("✅ %s: %s (%s)\n", b.t(c, d.Label), d.Detail, d.Took.Round(time.Millisecond)))
+	}
+	sb.WriteString("\n" + b.t(c, "Provider: %s", b.cfg().Provider))
+	return c.Send(sb.String())
+}
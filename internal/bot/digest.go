@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	digestTick = time.Minute
+	// digestExchanges caps how much of a busy day goes into the digest, the
+	// latest exchanges winning.
+	digestExchanges   = 50
+	digestExchangeLen = 600
+	digestInstruct    = "You write a user's daily digest of their conversations with an assistant. Summarize the conversations below as a short list of the topics covered, with what was concluded and anything left open. Address the user directly. Plain text, no markdown. Reply in the language with the code %q."
+)
+
+const digestUsage = `Usage: /digest on [time] [timezone], e.g. /digest on 9pm Europe/Berlin
+/digest off stops it`
+
+// parseDigest reads the optional time and IANA timezone of /digest on, in
+// either order, falling back to DIGEST_TIME and DIGEST_TIMEZONE.
+func (b *Bot) parseDigest(args []string) (hour, minute int, zone *time.Location, err error) {
+	clock, name := b.cfg().DigestTime, b.cfg().DigestTimezone
+	for _, arg := range args {
+		if reminderTimeRe.MatchString(strings.ToLower(arg)) {
+			clock = arg
+		} else {
+			name = arg
+		}
+	}
+	if hour, minute, err = parseClock(clock); err != nil {
+		return 0, 0, nil, err
+	}
+	zone = time.Local
+	if name != "" {
+		if zone, err = time.LoadLocation(name); err != nil {
+			return 0, 0, nil, fmt.Errorf("unknown timezone %q, try one like Europe/Berlin or America/New_York", name)
+		}
+	}
+	return hour, minute, zone, nil
+}
+
+func (b *Bot) digestHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) == 0 {
+		dg, err := b.db.GetDigest(c.Sender().ID)
+		if err == sql.ErrNoRows {
+			return c.Send(b.t(c, "You don't get a daily digest") + "\n\n" + b.t(c, digestUsage))
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load your digest: ") + err.Error())
+		}
+		return c.Send(b.t(c, "You get a digest of your day at %02d:%02d %s", dg.Hour, dg.Minute, dg.Timezone) + "\n\n" + b.t(c, digestUsage))
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off":
+		if _, err := b.db.DeleteDigest(c.Sender().ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+		}
+		return c.Send(b.t(c, "No more daily digests"))
+	case "on":
+	default:
+		return c.Send(b.t(c, digestUsage))
+	}
+
+	hour, minute, zone, err := b.parseDigest(args[1:])
+	if err != nil {
+		return c.Send(err.Error() + "\n\n" + b.t(c, digestUsage))
+	}
+	dg := store.Digest{
+		UserID:   c.Sender().ID,
+		Username: c.Sender().Username,
+		Hour:     hour,
+		Minute:   minute,
+		Timezone: zone.String(),
+		NextRun:  nextRun(hour, minute, "daily", time.Now().In(zone)),
+	}
+	if err := b.db.SaveDigest(dg); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Every day at %02d:%02d %s I'll send you a digest of the day's conversations, the first one %s", hour, minute, dg.Timezone, dg.NextRun.In(zone).Format("Mon Jan 2 15:04")))
+}
+
+// runDigests sends the digests that are due until the process exits.
+func (b *Bot) runDigests() {
+	for range time.Tick(digestTick) {
+		due, err := b.db.DueDigests(time.Now())
+		if err != nil {
+			slog.Error("Could not load due digests", "err", err)
+			continue
+		}
+		for _, dg := range due {
+			b.sendDigest(dg)
+		}
+	}
+}
+
+// sendDigest summarizes the user's conversations since the previous digest
+// and schedules the next one. Days without any are skipped quietly.
+func (b *Bot) sendDigest(dg store.Digest) {
+	zone, err := time.LoadLocation(dg.Timezone)
+	if err != nil {
+		zone = time.Local
+	}
+	defer func() {
+		if err := b.db.SetDigestNextRun(dg.UserID, nextRun(dg.Hour, dg.Minute, "daily", time.Now().In(zone))); err != nil {
+			slog.Error("Could not reschedule digest", "user_id", dg.UserID, "err", err)
+		}
+	}()
+
+	exchanges, err := b.db.ExchangesSince(dg.UserID, dg.NextRun.Add(-24*time.Hour))
+	if err != nil {
+		slog.Error("Could not load the day's exchanges", "user_id", dg.UserID, "err", err)
+		return
+	}
+	if len(exchanges) == 0 {
+		return
+	}
+	if len(exchanges) > digestExchanges {
+		exchanges = exchanges[len(exchanges)-digestExchanges:]
+	}
+	var sb strings.Builder
+	for _, ex := range exchanges {
+		sb.WriteString(fmt.Sprintf("User: %s\nAssistant: %s\n\n", truncate(ex.Prompt, digestExchangeLen), truncate(ex.Response, digestExchangeLen)))
+	}
+
+	user := &tele.User{ID: dg.UserID, Username: dg.Username}
+	lang := b.userLanguage(user)
+	apiKey, err := b.groqKeyFor(user)
+	if err != nil {
+		slog.Error("Could not write digest", "user_id", dg.UserID, "err", err)
+		return
+	}
+	start := time.Now()
+	res, err := b.llm.Complete(context.Background(), apiKey, []llm.Message{
+		{Role: "system", Content: fmt.Sprintf(digestInstruct, lang)},
+		{Role: "user", Content: sb.String()},
+	}, llm.WithModel(b.defaultModelFor(dg.UserID)))
+	if err != nil {
+		errorsTotal.WithLabelValues("digest").Inc()
+		slog.Error("Could not write digest", "user_id", dg.UserID, "err", err)
+		return
+	}
+	b.recordUsage(user, res, time.Since(start))
+
+	text := tr(lang, "🗞 Your day, %d messages", len(exchanges)) + "\n\n" + res.Content
+	if _, err := b.deliver(dg.UserID, dg.UserID, "digest", text); err != nil {
+		slog.Error("Could not deliver digest", "user_id", dg.UserID, "err", err)
+	}
+}
@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	maxDocumentSize   = 10 << 20
+	chunkSize         = 1500
+	chunkOverlap      = 200
+	maxContextChunks  = 3
+	minQueryTermLen   = 3
+	documentsInstruct = "Answer the question using the following excerpts from documents the user uploaded. If the excerpts do not contain the answer, say so.\n\n"
+)
+
+func (b *Bot) documentHandler(c tele.Context) error {
+	doc := c.Message().Document
+	if doc == nil {
+		return nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(doc.FileName))
+	if ext != ".txt" && ext != ".md" && ext != ".pdf" {
+		return c.Send(b.t(c, "Only .txt, .md and .pdf files are supported"))
+	}
+	if doc.FileSize > maxDocumentSize {
+		return c.Send(b.t(c, "File is too large, the limit is %dMB", maxDocumentSize>>20))
+	}
+
+	rc, err := c.Bot().File(&doc.File)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not download your file"))
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxDocumentSize+1))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not read your file"))
+	}
+
+	text, err := extractText(ext, data)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not extract text from your file: ") + err.Error())
+	}
+
+	chunks := chunkText(text)
+	if len(chunks) == 0 {
+		return c.Send(b.t(c, "That file doesn't seem to contain any text"))
+	}
+
+	if err := b.db.SaveDocumentChunks(c.Sender().ID, doc.FileName, chunks); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your file: ") + err.Error())
+	}
+
+	return c.Send(b.t(c, "Got %s (%d chunks), ask away.\nUse /forget to clear uploaded documents", doc.FileName, len(chunks)))
+}
+
+func (b *Bot) forgetHandler(c tele.Context) error {
+	if err := b.db.DeleteDocumentChunks(c.Sender().ID); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not clear your documents: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Forgot all your uploaded documents"))
+}
+
+func extractText(ext string, data []byte) (string, error) {
+	if ext != ".pdf" {
+		return string(data), nil
+	}
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	plain, err := r.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+	text, err := io.ReadAll(plain)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// chunkText splits text into overlapping chunks of roughly chunkSize
+// characters, preferring to break on paragraph and line boundaries.
+func chunkText(text string) []string {
+	return splitText(text, chunkSize, chunkOverlap)
+}
+
+// splitText splits text into chunks of roughly size characters that share
+// overlap characters with the previous chunk.
+func splitText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	runes := []rune(text)
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			window := string(runes[start:end])
+			if i := strings.LastIndex(window, "\n\n"); i > size/2 {
+				end = start + len([]rune(window[:i]))
+			} else if i := strings.LastIndexAny(window, "\n."); i > size/2 {
+				end = start + len([]rune(window[:i+1]))
+			}
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		start = max(end-overlap, start+1)
+	}
+	return chunks
+}
+
+func queryTerms(s string) map[string]bool {
+	terms := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		if len(w) >= minQueryTermLen {
+			terms[w] = true
+		}
+	}
+	return terms
+}
+
+// relevantChunks scores every stored chunk by how many distinct terms of
+// the question it contains and returns the best few.
+func relevantChunks(chunks []store.DocumentChunk, question string) []store.DocumentChunk {
+	terms := queryTerms(question)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		chunk store.DocumentChunk
+		score int
+	}
+	var results []scored
+	for _, chunk := range chunks {
+		content := strings.ToLower(chunk.Content)
+		score := 0
+		for term := range terms {
+			if strings.Contains(content, term) {
+				score++
+			}
+		}
+		if score > 0 {
+			results = append(results, scored{chunk, score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	var best []store.DocumentChunk
+	for i := 0; i < len(results) && i < maxContextChunks; i++ {
+		best = append(best, results[i].chunk)
+	}
+	return best
+}
+
+func (b *Bot) documentContext(userID int64, question string) (string, error) {
+	chunks, err := b.db.GetDocumentChunks(userID)
+	if err != nil || len(chunks) == 0 {
+		return "", err
+	}
+
+	relevant := relevantChunks(chunks, question)
+	if len(relevant) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(documentsInstruct)
+	for _, chunk := range relevant {
+		sb.WriteString(fmt.Sprintf("[%s, part %d]\n%s\n\n", chunk.FileName, chunk.ChunkIndex+1, chunk.Content))
+	}
+	sb.WriteString("Question: ")
+	return sb.String(), nil
+}
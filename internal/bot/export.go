@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+type exportedExchange struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Model            string    `json:"model"`
+	Prompt           string    `json:"prompt"`
+	Response         string    `json:"response"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+}
+
+func (b *Bot) exportHandler(c tele.Context) error {
+	format := "json"
+	if args := c.Args(); len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+	if format == "markdown" {
+		format = "md"
+	}
+	if format != "json" && format != "md" {
+		return c.Send(b.t(c, "Usage: /export [json|md]"))
+	}
+
+	exchanges, err := b.db.AllExchanges(c.Sender().ID)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your history: ") + err.Error())
+	}
+	if len(exchanges) == 0 {
+		return c.Send(b.t(c, "You don't have any conversation history yet"))
+	}
+
+	var data []byte
+	if format == "json" {
+		data, err = exportJSON(exchanges)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not export your history: ") + err.Error())
+		}
+	} else {
+		data = exportMarkdown(exchanges)
+	}
+
+	doc := &tele.Document{
+		File:     tele.FromReader(bytes.NewReader(data)),
+		FileName: fmt.Sprintf("groqy-history-%s.%s", time.Now().Format(time.DateOnly), format),
+		Caption:  b.t(c, "%d exchanges", len(exchanges)),
+	}
+	return c.Send(doc)
+}
+
+func exportJSON(exchanges []store.Exchange) ([]byte, error) {
+	out := make([]exportedExchange, 0, len(exchanges))
+	for _, ex := range exchanges {
+		out = append(out, exportedExchange{
+			Timestamp:        ex.CreatedAt,
+			Model:            ex.Model,
+			Prompt:           ex.Prompt,
+			Response:         ex.Response,
+			PromptTokens:     ex.PromptTokens,
+			CompletionTokens: ex.CompletionTokens,
+		})
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func exportMarkdown(exchanges []store.Exchange) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Conversation history\n\n")
+	for _, ex := range exchanges {
+		fmt.Fprintf(&buf, "## %s\n\n", ex.CreatedAt.Format(time.RFC1123))
+		fmt.Fprintf(&buf, "_Model: %s, tokens: %d prompt / %d completion_\n\n", ex.Model, ex.PromptTokens, ex.CompletionTokens)
+		fmt.Fprintf(&buf, "**You:**\n\n%s\n\n**Assistant:**\n\n%s\n\n---\n\n", ex.Prompt, ex.Response)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	extractInstruct = `Extract the dates, amounts of money and names mentioned in the user's text. Answer with a JSON object of exactly this shape and nothing else:
+{"dates": [{"value": "the date as written", "iso": "YYYY-MM-DD or empty if unknown", "context": "what it is the date of"}],
+ "amounts": [{"value": "the number", "currency": "ISO currency code or empty", "context": "what the amount is for"}],
+ "names": [{"value": "the name", "kind": "person, organization or place"}]}
+Use empty arrays for anything not mentioned. Don't invent values that aren't in the text.`
+
+	// extractAttempts allows one retry when the answer doesn't fit the
+	// schema, telling the model what was wrong.
+	extractAttempts = 2
+	maxCellWidth    = 32
+)
+
+var nameKinds = []string{"person", "organization", "place"}
+
+type extraction struct {
+	Dates []struct {
+		Value   string `json:"value"`
+		ISO     string `json:"iso"`
+		Context string `json:"context"`
+	} `json:"dates"`
+	Amounts []struct {
+		Value    json.Number `json:"value"`
+		Currency string      `json:"currency"`
+		Context  string      `json:"context"`
+	} `json:"amounts"`
+	Names []struct {
+		Value string `json:"value"`
+		Kind  string `json:"kind"`
+	} `json:"names"`
+}
+
+// parseExtraction decodes the model's answer, refusing anything that
+// doesn't match the schema of extractInstruct.
+func parseExtraction(answer string) (extraction, error) {
+	var ex extraction
+	dec := json.NewDecoder(strings.NewReader(answer))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	if err := dec.Decode(&ex); err != nil {
+		return ex, err
+	}
+	for i, d := range ex.Dates {
+		if d.Value == "" {
+			return ex, fmt.Errorf("dates[%d].value is empty", i)
+		}
+		if _, err := time.Parse(time.DateOnly, d.ISO); d.ISO != "" && err != nil {
+			return ex, fmt.Errorf("dates[%d].iso %q is not YYYY-MM-DD", i, d.ISO)
+		}
+	}
+	for i, a := range ex.Amounts {
+		if _, err := a.Value.Float64(); err != nil {
+			return ex, fmt.Errorf("amounts[%d].value %q is not a number", i, a.Value)
+		}
+	}
+	for i, n := range ex.Names {
+		if n.Value == "" {
+			return ex, fmt.Errorf("names[%d].value is empty", i)
+		}
+		if !slices.Contains(nameKinds, n.Kind) {
+			return ex, fmt.Errorf("names[%d].kind %q is not one of %s", i, n.Kind, strings.Join(nameKinds, ", "))
+		}
+	}
+	return ex, nil
+}
+
+func (b *Bot) extractHandler(c tele.Context) error {
+	text := strings.TrimSpace(c.Message().Payload)
+	if reply := replyTo(c.Message()); reply != nil {
+		text = reply.Text
+		if text == "" {
+			text = reply.Caption
+		}
+	}
+	if text == "" {
+		return c.Send(b.t(c, "Usage: /extract <text>, or reply to a message with /extract, to pull out its dates, amounts and names"))
+	}
+	if !b.allowPrompt(c, text) {
+		return nil
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: extractInstruct},
+		{Role: "user", Content: text},
+	}
+	var ex extraction
+	var err error
+	for attempt := 0; attempt < extractAttempts; attempt++ {
+		var res llm.Completion
+		res, err = b.complete(c, "/extract "+text, func(apiKey string) (llm.Completion, error) {
+			return b.llm.Complete(requestContext(c), apiKey, messages, llm.WithModel(b.userModel(c.Sender().ID)), llm.WithJSON(), llm.WithTemperature(0))
+		})
+		if err != nil {
+			return c.Send(errorReply(b.lang(c), err))
+		}
+		if ex, err = parseExtraction(res.Content); err == nil {
+			break
+		}
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: res.Content},
+			llm.Message{Role: "user", Content: "That JSON doesn't match the shape asked for: " + err.Error() + ". Answer again with the corrected JSON object only."},
+		)
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: The model's answer didn't have the expected fields: ") + err.Error())
+	}
+	if len(ex.Dates)+len(ex.Amounts)+len(ex.Names) == 0 {
+		return c.Send(b.t(c, "I couldn't find any dates, amounts or names in that"))
+	}
+	return c.Send(b.renderExtraction(c, ex), tele.ModeHTML)
+}
+
+// renderExtraction lays the extraction out as monospace tables.
+func (b *Bot) renderExtraction(c tele.Context, ex extraction) string {
+	var out strings.Builder
+	section := func(title string, headers []string, rows [][]string) {
+		if len(rows) == 0 {
+			return
+		}
+		out.WriteString("<b>" + html.EscapeString(title) + "</b>\n")
+		out.WriteString("<pre>" + html.EscapeString(renderTable(headers, rows)) + "</pre>\n")
+	}
+
+	var rows [][]string
+	for _, d := range ex.Dates {
+		rows = append(rows, []string{d.Value, d.ISO, d.Context})
+	}
+	section(b.t(c, "Dates"), []string{b.t(c, "Date"), "ISO", b.t(c, "What")}, rows)
+
+	rows = nil
+	for _, a := range ex.Amounts {
+		rows = append(rows, []string{a.Value.String(), a.Currency, a.Context})
+	}
+	section(b.t(c, "Amounts"), []string{b.t(c, "Amount"), b.t(c, "Currency"), b.t(c, "What")}, rows)
+
+	rows = nil
+	for _, n := range ex.Names {
+		rows = append(rows, []string{n.Value, b.t(c, n.Kind)})
+	}
+	section(b.t(c, "Names"), []string{b.t(c, "Name"), b.t(c, "Kind")}, rows)
+	return strings.TrimSpace(out.String())
+}
+
+// renderTable aligns rows under headers, cutting cells to maxCellWidth and
+// leaving out columns that are empty in every row.
+func renderTable(headers []string, rows [][]string) string {
+	var columns []int
+	for i := range headers {
+		for _, row := range rows {
+			if i < len(row) && row[i] != "" {
+				columns = append(columns, i)
+				break
+			}
+		}
+	}
+
+	cell := func(row []string, i int) string {
+		if i >= len(row) {
+			return ""
+		}
+		return truncate(strings.Join(strings.Fields(row[i]), " "), maxCellWidth)
+	}
+	widths := make([]int, len(headers))
+	for _, i := range columns {
+		widths[i] = utf8.RuneCountInString(headers[i])
+		for _, row := range rows {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell(row, i)))
+		}
+	}
+
+	var buf bytes.Buffer
+	line := func(row []string) {
+		var cells []string
+		for _, i := range columns {
+			v := cell(row, i)
+			cells = append(cells, v+strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+		}
+		buf.WriteString(strings.TrimRight(strings.Join(cells, " │ "), " ") + "\n")
+	}
+	line(headers)
+	var rule []string
+	for _, i := range columns {
+		rule = append(rule, strings.Repeat("─", widths[i]))
+	}
+	buf.WriteString(strings.Join(rule, "─┼─") + "\n")
+	for _, row := range rows {
+		line(row)
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm"
+)
+
+// newFailover wraps client with the fallback chain of FALLBACK_MODELS.
+// Plain entries are models of the same provider, openai:<model> ones go to
+// the OpenAI-compatible API at OPENAI_URL.
+func newFailover(cfg config.Config, client llm.Client) (*llm.Failover, error) {
+	var openai llm.Client
+	var routes []llm.Route
+	for _, entry := range cfg.FallbackModels {
+		provider, model, ok := strings.Cut(entry, ":")
+		if !ok {
+			routes = append(routes, llm.Route{Provider: cfg.Provider, Model: entry, Client: client})
+			continue
+		}
+		if provider != "openai" {
+			return nil, fmt.Errorf("unknown provider %q in %q", provider, entry)
+		}
+		if cfg.OpenAIToken == "" {
+			return nil, fmt.Errorf("%q needs OPENAI_TOKEN", entry)
+		}
+		if openai == nil {
+			openai = &llm.Groq{BaseURL: strings.TrimSuffix(cfg.OpenAIURL, "/"), HTTPClient: llm.NewGroq().HTTPClient}
+		}
+		routes = append(routes, llm.Route{Provider: provider, Model: model, Client: openai, APIKey: cfg.OpenAIToken})
+	}
+
+	failover := llm.NewFailover(client, cfg.Provider, routes)
+	failover.OnFailover = func(from, to string, err error) {
+		failovers.WithLabelValues(from, to).Inc()
+		slog.Warn("Failed over to a fallback model", "from", from, "to", to, "err", err)
+	}
+	return failover, nil
+}
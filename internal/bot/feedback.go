@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var btnRate = tele.Btn{Unique: "rate"}
+
+// feedbackReactions are the reactions on answers that count as feedback.
+var feedbackReactions = map[string]int{"👍": 1, "👎": -1}
+
+// feedbackExample is a line of the fine-tuning dataset.
+type feedbackExample struct {
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+	Rating   int    `json:"rating"`
+}
+
+// rate keeps feedback on the exchange answered with messageID, reporting
+// whether the user has one.
+func (b *Bot) rate(userID int64, messageID, feedback int) (bool, error) {
+	rated, err := b.db.SetFeedback(userID, messageID, feedback)
+	if err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		return false, err
+	}
+	if rated {
+		feedbackTotal.WithLabelValues(feedbackLabel(feedback)).Inc()
+	}
+	return rated, nil
+}
+
+func feedbackLabel(feedback int) string {
+	switch feedback {
+	case 1:
+		return "up"
+	case -1:
+		return "down"
+	}
+	return "cleared"
+}
+
+// rateHandler keeps the 👍 or 👎 tapped under an answer.
+func (b *Bot) rateHandler(c tele.Context) error {
+	feedback, err := strconv.Atoi(c.Callback().Data)
+	if err != nil || (feedback != 1 && feedback != -1) {
+		return c.Respond()
+	}
+	rated, err := b.rate(c.Sender().ID, c.Callback().Message.ID, feedback)
+	if err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save feedback", "err", err)
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not save your feedback")})
+	}
+	if !rated {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "I can't find that answer anymore")})
+	}
+	return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Thanks for the feedback")})
+}
+
+// filterReactions takes message reactions out of the updates, as telebot
+// doesn't route them to handlers.
+func (b *Bot) filterReactions(upd *tele.Update) bool {
+	if upd.MessageReaction == nil {
+		return true
+	}
+	go b.reactionHandler(upd.MessageReaction)
+	return false
+}
+
+// reactionHandler keeps 👍 and 👎 on answers as feedback on the exchange.
+// Any other reaction, or taking it back, clears it.
+func (b *Bot) reactionHandler(r *tele.MessageReaction) {
+	if r.User == nil {
+		return
+	}
+	feedback := 0
+	for _, reaction := range r.NewReaction {
+		if rating, ok := feedbackReactions[reaction.Emoji]; ok {
+			feedback = rating
+		}
+	}
+	if _, err := b.rate(r.User.ID, r.MessageID, feedback); err != nil {
+		slog.Error("Could not save feedback", "user", r.User.ID, "err", err)
+	}
+}
+
+// exportFeedbackHandler sends every rated answer as a JSONL dataset of
+// prompt, response and rating, for fine-tuning.
+func (b *Bot) exportFeedbackHandler(c tele.Context) error {
+	exchanges, err := b.db.RatedExchanges()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load the feedback: ") + err.Error())
+	}
+	if len(exchanges) == 0 {
+		return c.Send(b.t(c, "Nobody has rated an answer yet"))
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ex := range exchanges {
+		if err := enc.Encode(feedbackExample{Prompt: ex.Prompt, Response: ex.Response, Rating: ex.Feedback}); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not export the feedback: ") + err.Error())
+		}
+	}
+	return c.Send(&tele.Document{
+		File:     tele.FromReader(&buf),
+		FileName: fmt.Sprintf("groqy-feedback-%s.jsonl", time.Now().Format(time.DateOnly)),
+		Caption:  b.t(c, "%d rated answers", len(exchanges)),
+	})
+}
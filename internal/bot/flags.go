@@ -0,0 +1,304 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+// flagCacheTTL is how long the flags are kept before they're read again,
+// for those changed by another instance.
+const flagCacheTTL = time.Minute
+
+// features are what flags gate besides commands, with what they cover.
+var features = map[string]string{
+	"tools":      "every tool the model may call",
+	"web_search": "searching the web while answering",
+	"code":       "running code while answering, and /code",
+	"vision":     "looking at the images in stickers",
+	"tts":        "voice replies and /tts",
+	"follow_ups": "follow-up questions suggested under answers",
+}
+
+const flagsUsage = `Usage:
+/flags lists the flags that are set
+/flags <flag> shows one and who it's turned on or off for
+/flags <flag> on|off|<percent>%% rolls it out to everyone, no one or that share of users
+/flags <flag> on|off <user id|@username> turns it on or off for one user
+/flags <flag> reset [user id|@username] goes back to on for everyone, or the rollout for the user
+
+A flag is one of %s or a command without its slash, e.g. compare.`
+
+// flagCache keeps the flags and overrides, read every command otherwise.
+// /flags drops it when it changes them.
+type flagCache struct {
+	mu        sync.Mutex
+	loaded    time.Time
+	rollouts  map[string]int
+	overrides map[string]map[int64]bool
+}
+
+// get returns the rollouts and overrides by flag, reading them again once
+// they're older than flagCacheTTL.
+func (fc *flagCache) get(db store.Store) (map[string]int, map[string]map[int64]bool, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if time.Since(fc.loaded) < flagCacheTTL {
+		return fc.rollouts, fc.overrides, nil
+	}
+	flags, err := db.Flags()
+	if err != nil {
+		return nil, nil, err
+	}
+	overrides, err := db.FlagOverrides("")
+	if err != nil {
+		return nil, nil, err
+	}
+	fc.rollouts = map[string]int{}
+	for _, f := range flags {
+		fc.rollouts[f.Name] = f.Percent
+	}
+	fc.overrides = map[string]map[int64]bool{}
+	for _, o := range overrides {
+		if fc.overrides[o.Name] == nil {
+			fc.overrides[o.Name] = map[int64]bool{}
+		}
+		fc.overrides[o.Name][o.UserID] = o.Enabled
+	}
+	fc.loaded = time.Now()
+	return fc.rollouts, fc.overrides, nil
+}
+
+func (fc *flagCache) reset() {
+	fc.mu.Lock()
+	fc.loaded = time.Time{}
+	fc.mu.Unlock()
+}
+
+// featureEnabled reports whether the feature is on for the user: their
+// override if they have one, otherwise whether they're in its rollout.
+// Features without a rollout are on for everyone, as they are when the
+// flags can't be read.
+func (b *Bot) featureEnabled(name string, userID int64) bool {
+	rollouts, overrides, err := b.flags.get(b.db)
+	if err != nil {
+		slog.Error("Could not load feature flags", "err", err)
+		return true
+	}
+	if enabled, ok := overrides[name][userID]; ok {
+		return enabled
+	}
+	percent, ok := rollouts[name]
+	if !ok {
+		return true
+	}
+	return rolloutBucket(name, userID) < percent
+}
+
+// rolloutBucket places the user from 0 to 99 for the feature, the same
+// every time so raising a rollout only ever adds users.
+func rolloutBucket(name string, userID int64) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", name, userID)
+	return int(h.Sum32() % 100)
+}
+
+// withFeature turns away the users the command's flag is off for.
+func (b *Bot) withFeature(name string) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if !b.featureEnabled(name, c.Sender().ID) {
+				return c.Send(b.t(c, "/%s isn't available to you yet", name))
+			}
+			return next(c)
+		}
+	}
+}
+
+// flagNames are the flags /flags takes: the features and the commands that
+// aren't for admins, which can't be turned off for the admins themselves,
+// or for managing the user's account.
+func (b *Bot) flagNames() []string {
+	var names []string
+	for name := range features {
+		names = append(names, name)
+	}
+	for _, cmd := range b.commands {
+		if _, ok := features[strings.TrimPrefix(cmd.Name, "/")]; !ok && !cmd.Admin && !cmd.Account {
+			names = append(names, strings.TrimPrefix(cmd.Name, "/"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *Bot) flagsHandler(c tele.Context) error {
+	args := c.Args()
+	featureNames := make([]string, 0, len(features))
+	for name := range features {
+		featureNames = append(featureNames, name)
+	}
+	sort.Strings(featureNames)
+	usage := b.t(c, flagsUsage, strings.Join(featureNames, ", "))
+	if len(args) == 0 {
+		return b.listFlags(c, usage)
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(args[0], "/"))
+	known := false
+	for _, n := range b.flagNames() {
+		known = known || n == name
+	}
+	if !known {
+		return c.Send(b.t(c, "No flag called %s", name) + "\n\n" + usage)
+	}
+
+	switch len(args) {
+	case 1:
+		return b.showFlag(c, name)
+	case 2:
+		if strings.ToLower(args[1]) == "reset" {
+			if err := b.db.DeleteFlag(name); err != nil {
+				return c.Send(b.t(c, "ERROR: Could not reset the flag: ") + err.Error())
+			}
+			b.flags.reset()
+			return c.Send(b.t(c, "%s is on for everyone again", name))
+		}
+		percent, ok := parseRollout(args[1])
+		if !ok {
+			return c.Send(usage)
+		}
+		if err := b.db.SetFlag(name, percent, c.Sender().ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not save the flag: ") + err.Error())
+		}
+		b.flags.reset()
+		return b.showFlag(c, name)
+	}
+
+	userID, err := b.userArg(args[2])
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not find %s: ", args[2]) + err.Error())
+	}
+	switch strings.ToLower(args[1]) {
+	case "reset":
+		deleted, err := b.db.DeleteFlagOverride(name, userID)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not reset the flag: ") + err.Error())
+		}
+		if !deleted {
+			return c.Send(b.t(c, "%s isn't overridden for %s", name, args[2]))
+		}
+	case "on", "off":
+		if err := b.db.SetFlagOverride(name, userID, strings.ToLower(args[1]) == "on"); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not save the flag: ") + err.Error())
+		}
+	default:
+		return c.Send(usage)
+	}
+	b.flags.reset()
+	if b.featureEnabled(name, userID) {
+		return c.Send(b.t(c, "%s is on for %s", name, args[2]))
+	}
+	return c.Send(b.t(c, "%s is off for %s", name, args[2]))
+}
+
+// parseRollout reads on, off or a percentage.
+func parseRollout(s string) (int, bool) {
+	switch strings.ToLower(s) {
+	case "on":
+		return 100, true
+	case "off":
+		return 0, true
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, false
+	}
+	return percent, true
+}
+
+// userArg reads a user ID or @username.
+func (b *Bot) userArg(arg string) (int64, error) {
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return id, nil
+	}
+	u, err := b.db.GetUserByUsername(strings.TrimPrefix(arg, "@"))
+	if err != nil {
+		return 0, err
+	}
+	return u.UserID, nil
+}
+
+func (b *Bot) listFlags(c tele.Context, usage string) error {
+	flags, err := b.db.Flags()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load the flags: ") + err.Error())
+	}
+	overrides, err := b.db.FlagOverrides("")
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load the flags: ") + err.Error())
+	}
+	counts := map[string]int{}
+	for _, o := range overrides {
+		counts[o.Name]++
+	}
+	if len(flags) == 0 && len(overrides) == 0 {
+		return c.Send(b.t(c, "Every flag is on for everyone") + "\n\n" + usage)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.t(c, "Flags set, the rest are on for everyone:") + "\n")
+	listed := map[string]bool{}
+	for _, f := range flags {
+		listed[f.Name] = true
+		sb.WriteString(fmt.Sprintf("%s: %d%%", f.Name, f.Percent))
+		if n := counts[f.Name]; n > 0 {
+			sb.WriteString(b.t(c, ", %d overrides", n))
+		}
+		sb.WriteString("\n")
+	}
+	for _, o := range overrides {
+		if !listed[o.Name] {
+			listed[o.Name] = true
+			sb.WriteString(o.Name + ": 100%" + b.t(c, ", %d overrides", counts[o.Name]) + "\n")
+		}
+	}
+	return c.Send(sb.String() + "\n" + usage)
+}
+
+func (b *Bot) showFlag(c tele.Context, name string) error {
+	percent := 100
+	if f, err := b.db.GetFlag(name); err == nil {
+		percent = f.Percent
+	} else if err != sql.ErrNoRows {
+		return c.Send(b.t(c, "ERROR: Could not load the flag: ") + err.Error())
+	}
+	overrides, err := b.db.FlagOverrides(name)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load the flag: ") + err.Error())
+	}
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	if about, ok := features[name]; ok {
+		sb.WriteString(", " + b.t(c, about))
+	}
+	sb.WriteString("\n" + b.t(c, "Rolled out to %d%% of users", percent) + "\n")
+	for _, o := range overrides {
+		if o.Enabled {
+			sb.WriteString(b.t(c, "on for %d", o.UserID) + "\n")
+		} else {
+			sb.WriteString(b.t(c, "off for %d", o.UserID) + "\n")
+		}
+	}
+	return c.Send(strings.TrimSpace(sb.String()))
+}
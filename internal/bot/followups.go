@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	maxFollowUps = 3
+	// maxFollowUpLen keeps the buttons readable on a phone.
+	maxFollowUpLen = 80
+	// followUpContext is how much of the exchange the suggestions are
+	// written from.
+	followUpContext  = 1500
+	followUpInstruct = `Suggest two or three short follow-up questions the user might ask next about the exchange below, written as the user would ask them, each under 60 characters. Use the language of the exchange. Reply with JSON like {"questions": ["..."]}.`
+)
+
+var btnFollowUp = tele.Btn{Unique: "follow_up"}
+
+// suggestFollowUps adds buttons with questions to ask next under the answer
+// in msg, when FOLLOW_UPS is on. Groups don't get them, as anyone could tap
+// them.
+func (b *Bot) suggestFollowUps(ctx context.Context, tc tele.Context, msg *tele.Message, ex store.Exchange) error {
+	if !b.cfg().FollowUps || inGroup(tc) || !b.featureEnabled("follow_ups", tc.Sender().ID) {
+		return nil
+	}
+	apiKey, err := b.groqKeyFor(tc.Sender())
+	if err != nil {
+		return err
+	}
+	model := b.cfg().FollowUpModel
+	if model == "" {
+		model = llm.DefaultModel
+	}
+	start := time.Now()
+	res, err := b.llm.Complete(ctx, apiKey, []llm.Message{
+		{Role: "system", Content: followUpInstruct},
+		{Role: "user", Content: "User: " + truncate(ex.Prompt, followUpContext) + "\nAssistant: " + truncate(ex.Response, followUpContext)},
+	}, llm.WithModel(model), llm.WithJSON())
+	if err != nil {
+		return err
+	}
+	b.recordUsage(tc.Sender(), res, time.Since(start))
+
+	questions := parseFollowUps(res.Content)
+	if len(questions) == 0 {
+		return nil
+	}
+	menu := b.answerMenu(tc)
+	for i, q := range questions {
+		btn := menu.Data("💬 "+q, btnFollowUp.Unique, strconv.Itoa(i))
+		menu.InlineKeyboard = append(menu.InlineKeyboard, []tele.InlineButton{*btn.Inline()})
+	}
+	_, err = tc.Bot().EditReplyMarkup(msg, menu)
+	return err
+}
+
+// parseFollowUps reads the model's questions, dropping empty ones and
+// shortening long ones.
+func parseFollowUps(content string) []string {
+	var out struct {
+		Questions []string `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return nil
+	}
+	var questions []string
+	for _, q := range out.Questions {
+		if q = strings.TrimSpace(q); q != "" {
+			questions = append(questions, truncate(q, maxFollowUpLen))
+		}
+		if len(questions) == maxFollowUps {
+			break
+		}
+	}
+	return questions
+}
+
+// followUpQuestion is the question on the tapped button of msg, read back
+// from the button itself so suggestions outlive restarts.
+func followUpQuestion(msg *tele.Message, data string) string {
+	if msg == nil || msg.ReplyMarkup == nil {
+		return ""
+	}
+	for _, row := range msg.ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Data == "\f"+btnFollowUp.Unique+"|"+data {
+				return strings.TrimPrefix(btn.Text, "💬 ")
+			}
+		}
+	}
+	return ""
+}
+
+// followUpHandler asks the tapped suggestion as the user's next message.
+// The suggestions go away once one is asked.
+func (b *Bot) followUpHandler(c tele.Context) error {
+	question := followUpQuestion(c.Message(), c.Callback().Data)
+	if question == "" {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That suggestion is gone")})
+	}
+	c.Respond()
+	c.Bot().EditReplyMarkup(c.Message(), b.answerMenu(c))
+	c.Send("💬 " + question)
+	return b.chatHandler(c, question)
+}
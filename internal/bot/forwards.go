@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// forwardTTL is how long forwarded messages wait for the question
+	// about them.
+	forwardTTL = 10 * time.Minute
+	// forwardsKept caps how many forwarded messages are held per user.
+	forwardsKept = 10
+)
+
+// forwards are the messages a user forwarded, waiting for their question.
+type forwards struct {
+	mu    sync.Mutex
+	texts []string
+	last  time.Time
+}
+
+// forwarded reports whether msg is a forward of someone's message.
+func forwarded(msg *tele.Message) bool {
+	return msg != nil && (msg.Origin != nil || msg.IsForwarded() || msg.OriginalSenderName != "")
+}
+
+// forwardSender names who wrote a forwarded message, "" when Telegram
+// doesn't say.
+func forwardSender(msg *tele.Message) string {
+	if o := msg.Origin; o != nil {
+		switch {
+		case o.Sender != nil:
+			return displayName(o.Sender)
+		case o.SenderUsername != "":
+			return o.SenderUsername
+		case o.SenderChat != nil:
+			return o.SenderChat.Title
+		case o.Chat != nil:
+			return o.Chat.Title
+		}
+	}
+	switch {
+	case msg.OriginalSender != nil:
+		return displayName(msg.OriginalSender)
+	case msg.OriginalChat != nil:
+		return msg.OriginalChat.Title
+	}
+	return msg.OriginalSenderName
+}
+
+func displayName(u *tele.User) string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// withForwards holds forwarded messages until the user asks about them, so
+// a batch of them doesn't queue up as separate prompts.
+func (b *Bot) withForwards(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		msg := c.Message()
+		if !forwarded(msg) {
+			return next(c)
+		}
+		text := msg.Text
+		if text == "" {
+			text = msg.Caption
+		}
+		if name := forwardSender(msg); name != "" {
+			text = fmt.Sprintf("From %s:\n%s", name, text)
+		}
+
+		v, _ := b.pendingForwards.LoadOrStore(c.Sender().ID, &forwards{})
+		held := v.(*forwards)
+		held.mu.Lock()
+		if time.Since(held.last) >= forwardTTL {
+			held.texts = nil
+		}
+		fresh := len(held.texts) == 0
+		if len(held.texts) < forwardsKept {
+			held.texts = append(held.texts, text)
+		}
+		held.last = time.Now()
+		held.mu.Unlock()
+		if !fresh {
+			return nil
+		}
+		return c.Send(b.t(c, "📨 Got it. What would you like to know about the forwarded message?"))
+	}
+}
+
+// quotedContext is what the user's message refers to: the messages they
+// forwarded before it, the part of a message they quoted or the message
+// they replied to, if it isn't one of the bot's answers. It's added after
+// the prompt so fitting the context cuts it before the question.
+func (b *Bot) quotedContext(c tele.Context) string {
+	msg := c.Message()
+	if msg == nil || c.Callback() != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	if v, ok := b.pendingForwards.LoadAndDelete(c.Sender().ID); ok {
+		held := v.(*forwards)
+		held.mu.Lock()
+		if time.Since(held.last) < forwardTTL {
+			sb.WriteString("\n\nThe user is asking about these forwarded messages:")
+			for _, text := range held.texts {
+				sb.WriteString("\n\"\"\"\n" + text + "\n\"\"\"")
+			}
+		}
+		held.mu.Unlock()
+	}
+
+	reply := replyTo(msg)
+	fromBot := reply != nil && reply.Sender != nil && reply.Sender.ID == c.Bot().Me.ID
+	switch {
+	case msg.Quote != nil && msg.Quote.Text != "" && fromBot:
+		sb.WriteString("\n\nThe user is quoting this part of your earlier answer:\n\"\"\"\n" + msg.Quote.Text + "\n\"\"\"")
+	case msg.Quote != nil && msg.Quote.Text != "":
+		sb.WriteString("\n\nThe user is asking about this quoted message" + quotedFrom(reply) + ":\n\"\"\"\n" + msg.Quote.Text + "\n\"\"\"")
+	case reply != nil && !fromBot:
+		text := reply.Text
+		if text == "" {
+			text = reply.Caption
+		}
+		if text != "" {
+			sb.WriteString("\n\nThe user is replying to this message" + quotedFrom(reply) + ":\n\"\"\"\n" + text + "\n\"\"\"")
+		}
+	}
+	return sb.String()
+}
+
+func quotedFrom(reply *tele.Message) string {
+	if reply == nil {
+		return ""
+	}
+	name := forwardSender(reply)
+	if name == "" && reply.Sender != nil {
+		name = displayName(reply.Sender)
+	}
+	if name == "" {
+		return ""
+	}
+	return " from " + name
+}
@@ -0,0 +1,299 @@
+package bot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/logging"
+	"github.com/musaubrian/groqy/internal/tracing"
+	"github.com/oklog/ulid/v2"
+	tele "gopkg.in/telebot.v3"
+)
+
+// gatewayRequest is the part of an OpenAI chat completion request the
+// gateway understands. The user is the one the bearer key belongs to, so
+// "user" is ignored.
+type gatewayRequest struct {
+	Model       string        `json:"model"`
+	Messages    []llm.Message `json:"messages"`
+	Temperature *float64      `json:"temperature"`
+	Stream      bool          `json:"stream"`
+}
+
+type gatewayUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// startGateway serves an OpenAI-compatible API on addr that answers through
+// the bot's LLM client, queue and usage accounting.
+func (b *Bot) startGateway(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", b.gatewayModels)
+	mux.HandleFunc("/v1/chat/completions", b.gatewayChat)
+
+	serveHTTP("Gateway", addr, mux)
+}
+
+func (b *Bot) gatewayModels(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"data":   []any{map[string]string{"id": b.defaultModel(), "object": "model", "owned_by": "groqy"}},
+	})
+}
+
+func (b *Bot) gatewayChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		gatewayError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+
+	var req gatewayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		gatewayError(w, http.StatusBadRequest, "invalid_request_error", "could not parse request: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 {
+		gatewayError(w, http.StatusBadRequest, "invalid_request_error", "messages is required")
+		return
+	}
+
+	user, err := b.gatewayUser(r)
+	if err != nil {
+		gatewayError(w, http.StatusUnauthorized, "authentication_error", err.Error())
+		return
+	}
+
+	if req.Model == "" {
+		req.Model = b.defaultModelFor(user.ID)
+	}
+	if !b.modelAllowed(user.ID, req.Model) {
+		gatewayError(w, http.StatusForbidden, "permission_error", req.Model+" is not on your plan")
+		return
+	}
+	left, quota, err := b.userQuotaLeft(user)
+	if err != nil {
+		gatewayError(w, http.StatusInternalServerError, "api_error", "could not check your message quota: "+err.Error())
+		return
+	}
+	if quota > 0 && left == 0 {
+		gatewayError(w, http.StatusTooManyRequests, "insufficient_quota", fmt.Sprintf("you've used your %d messages for today", quota))
+		return
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+	if d := b.gatewayCooldown(r.Context(), user, prompt); d > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(d.Seconds())))
+		gatewayError(w, http.StatusTooManyRequests, "rate_limit_error", "you're sending too much, take a break for "+roundCooldown(d).String())
+		return
+	}
+	opts := []llm.Option{llm.WithModel(req.Model)}
+	if req.Temperature != nil {
+		opts = append(opts, llm.WithTemperature(*req.Temperature))
+	}
+
+	apiKey, err := b.groqKeyFor(user)
+	if err != nil {
+		gatewayError(w, http.StatusForbidden, "permission_error", err.Error())
+		return
+	}
+	if apiKey == b.cfg().GroqToken {
+		if err := b.gate.Wait(func(int) {}); err != nil {
+			gatewayError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+			return
+		}
+	}
+
+	requestID := ulid.Make().String()
+	// Callers that trace send a traceparent, which the Groq request carries
+	// on.
+	ctx := logging.WithRequestID(tracing.Extract(r.Context(), r.Header), requestID)
+	ctx, span := tracing.Start(ctx, "gateway_request", tracing.Server)
+	defer span.End()
+	span.Set("user.id", user.ID)
+	span.Set("gen_ai.request.model", req.Model)
+	span.Set("stream", req.Stream)
+	id := "chatcmpl-" + requestID
+	created := time.Now().Unix()
+
+	if !req.Stream {
+		var res llm.Completion
+		err := b.pool.Run(func() (err error) {
+			res, err = b.gatewayComplete(user, apiKey, prompt, func() (llm.Completion, error) {
+				return b.llm.Complete(ctx, apiKey, req.Messages, opts...)
+			})
+			return err
+		})
+		if err != nil {
+			span.Fail(err)
+			gatewayCompletionError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   res.Model,
+			"choices": []any{map[string]any{
+				"index":         0,
+				"message":       llm.Message{Role: "assistant", Content: res.Content},
+				"finish_reason": "stop",
+			}},
+			"usage": usage(res),
+		})
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	chunk := func(delta map[string]string, finish any, extra map[string]any) {
+		c := map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   req.Model,
+			"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		data, _ := json.Marshal(c)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	err = b.pool.Run(func() error {
+		res, err := b.gatewayComplete(user, apiKey, prompt, func() (llm.Completion, error) {
+			chunk(map[string]string{"role": "assistant"}, nil, nil)
+			return b.llm.Stream(ctx, apiKey, req.Messages, func(delta string) {
+				chunk(map[string]string{"content": delta}, nil, nil)
+			}, opts...)
+		})
+		if err == nil {
+			chunk(map[string]string{}, "stop", map[string]any{"usage": usage(res)})
+		}
+		return err
+	})
+	if err != nil {
+		span.Fail(err)
+		data, _ := json.Marshal(map[string]any{"error": map[string]string{"message": err.Error()}})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// gatewayKeyPrefix marks gateway keys so they aren't mistaken for Groq's.
+const gatewayKeyPrefix = "groqy_"
+
+// gatewayUser authenticates a request by its bearer token, which has to be
+// a gateway key the user made with /gateway. The key alone says who the
+// request is billed to.
+func (b *Bot) gatewayUser(r *http.Request) (*tele.User, error) {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(key, gatewayKeyPrefix) {
+		return nil, errors.New("invalid gateway key, make one with /gateway new")
+	}
+	userID, err := b.db.GatewayKeyUser(sha256Hex([]byte(key)))
+	if err != nil {
+		return nil, errors.New("invalid gateway key, make one with /gateway new")
+	}
+	dbUser, err := b.db.GetUser(userID)
+	if err != nil || !b.authorized(dbUser) {
+		return nil, errors.New("the key's user has not authenticated with the bot")
+	}
+	user := &tele.User{ID: dbUser.UserID, Username: dbUser.Username}
+	if !b.permitted(user) {
+		return nil, errNotPermitted
+	}
+	return user, nil
+}
+
+// gatewayKeyHandler shows whether the sender has a gateway key, makes them
+// one with /gateway new, replacing the old, or removes it with /gateway
+// revoke. Only the key's hash is stored, so it's shown just the once.
+func (b *Bot) gatewayKeyHandler(c tele.Context) error {
+	if b.cfg().GatewayAddr == "" {
+		return c.Send(b.t(c, "The API gateway is not enabled on this bot"))
+	}
+
+	args := c.Args()
+	switch {
+	case len(args) == 0:
+		created, err := b.db.GatewayKeyCreated(c.Sender().ID)
+		if err == sql.ErrNoRows {
+			return c.Send(b.t(c, "Usage: /gateway new to get a key for the API gateway"))
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: %v", err))
+		}
+		return c.Send(b.t(c, "You have a gateway key from %s.\nUse /gateway new to replace it or /gateway revoke to remove it", created.Format("2006-01-02")))
+	case len(args) == 1 && args[0] == "revoke":
+		if err := b.db.DeleteGatewayKey(c.Sender().ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not revoke your gateway key: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Revoked your gateway key"))
+	case len(args) != 1 || args[0] != "new":
+		return c.Send(b.t(c, "Usage: /gateway new or /gateway revoke"))
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not make a gateway key: ") + err.Error())
+	}
+	key := gatewayKeyPrefix + token
+	if err := b.db.SaveGatewayKey(c.Sender().ID, sha256Hex([]byte(key))); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not make a gateway key: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Your gateway key, send it as the bearer token. It won't be shown again and replaces any key you had:\n\n%s", key))
+}
+
+// gatewayComplete records the audit entry and metrics for a gateway request.
+func (b *Bot) gatewayComplete(user *tele.User, apiKey, prompt string, call func() (llm.Completion, error)) (llm.Completion, error) {
+	start := time.Now()
+	res, err := call()
+	b.audit(user, prompt, res, time.Since(start), err)
+	if err != nil {
+		errorsTotal.WithLabelValues("gateway").Inc()
+		var limited *llm.RateLimitError
+		if errors.As(err, &limited) && apiKey == b.cfg().GroqToken {
+			b.gate.Backoff(limited.RetryAfter)
+		}
+		return res, err
+	}
+	b.recordRequest(user, res, time.Since(start))
+	return res, nil
+}
+
+func usage(res llm.Completion) gatewayUsage {
+	return gatewayUsage{
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		TotalTokens:      res.PromptTokens + res.CompletionTokens,
+	}
+}
+
+func gatewayCompletionError(w http.ResponseWriter, err error) {
+	var limited *llm.RateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", fmt.Sprint(int(limited.RetryAfter.Seconds())))
+		gatewayError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+		return
+	}
+	gatewayError(w, http.StatusBadGateway, "api_error", err.Error())
+}
+
+func gatewayError(w http.ResponseWriter, code int, kind, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"message": message, "type": kind}})
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+func inGroup(c tele.Context) bool {
+	chat := c.Chat()
+	return chat != nil && (chat.Type == tele.ChatGroup || chat.Type == tele.ChatSuperGroup)
+}
+
+func groupPrefix(chatID int64) string {
+	return fmt.Sprintf("group:%d:", chatID)
+}
+
+// groupThread returns the key of the reply thread c belongs to in a group,
+// along with the exchange it continues from. Threads are keyed by their root
+// message, so a message that doesn't reply to one of the bot's answers
+// starts a thread of its own, except in a forum topic, where it continues
+// the topic's latest exchange.
+func (b *Bot) groupThread(c tele.Context) (string, *store.Exchange, error) {
+	msg := c.Message()
+	answerID := 0
+	if c.Callback() != nil {
+		answerID = msg.ID
+	} else if reply := replyTo(msg); reply != nil && reply.Sender != nil && reply.Sender.ID == c.Bot().Me.ID {
+		answerID = reply.ID
+	}
+
+	if answerID != 0 {
+		ex, err := b.db.ExchangeInChats(groupPrefix(c.Chat().ID), answerID)
+		if err == nil {
+			return ex.ChatID, &ex, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", nil, err
+		}
+	}
+	if thread := topicID(c); thread != 0 {
+		key := topicKey(c.Chat().ID, thread)
+		ex, err := b.db.LatestExchange(key)
+		if err == sql.ErrNoRows {
+			return key, nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return key, &ex, nil
+	}
+	return groupPrefix(c.Chat().ID) + fmt.Sprint(msg.ID), nil, nil
+}
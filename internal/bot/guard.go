@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"errors"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+// contextMargin absorbs the estimator being off from the real tokenizer.
+const contextMargin = 256
+
+var errPromptTooLarge = errors.New("prompt is too large for the model")
+
+// trim is what fitContext left out to fit the model's context.
+type trim struct {
+	// Messages is how many older history messages were dropped.
+	Messages int
+	// Tokens is roughly how much was cut off the end of the last message.
+	Tokens int
+}
+
+// fitContext makes messages fit model's context window with room for the
+// answer, first dropping the oldest history, then cutting the end off the
+// last message. It fails when the instructions alone don't fit.
+func fitContext(messages []llm.Message, model string) ([]llm.Message, trim, error) {
+	budget := llm.ContextWindow(model) - llm.MaxTokens(model) - contextMargin
+	var t trim
+	total := llm.EstimateMessages(messages)
+	if total <= budget {
+		return messages, t, nil
+	}
+
+	fitted := make([]llm.Message, 0, len(messages))
+	last := messages[len(messages)-1]
+	for _, m := range messages[:len(messages)-1] {
+		if total > budget && (m.Role == "user" || m.Role == "assistant") {
+			total -= llm.EstimateMessages([]llm.Message{m}) - 3
+			t.Messages++
+			continue
+		}
+		fitted = append(fitted, m)
+	}
+	if total <= budget {
+		return append(fitted, last), t, nil
+	}
+
+	over := total - budget
+	lastTokens := llm.EstimateTokens(last.Content)
+	if over >= lastTokens {
+		return nil, t, errPromptTooLarge
+	}
+	runes := []rune(last.Content)
+	keep := len(runes) * (lastTokens - over) / lastTokens
+	for keep > 0 && llm.EstimateTokens(string(runes[:keep])) > lastTokens-over {
+		keep -= max((keep-1)/20, 1)
+	}
+	t.Tokens = lastTokens - llm.EstimateTokens(string(runes[:keep]))
+	last.Content = string(runes[:keep])
+	return append(fitted, last), t, nil
+}
+
+// fitPrompt fits messages to the sender's model and tells them what had to
+// go, reporting whether anything did.
+func (b *Bot) fitPrompt(c tele.Context, messages []llm.Message) ([]llm.Message, bool, error) {
+	fitted, t, err := fitContext(messages, b.userModel(c.Sender().ID))
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case t.Tokens > 0:
+		c.Send(b.t(c, "✂️ That was too long for the model, I cut about %d tokens off the end of your message", t.Tokens))
+	case t.Messages > 0:
+		c.Send(b.t(c, "✂️ To fit the model I left out the %d oldest messages of this conversation", t.Messages))
+	}
+	return fitted, t != trim{}, nil
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	readinessTimeout = 5 * time.Second
+	readinessCache   = 30 * time.Second
+)
+
+type readiness struct {
+	bot *Bot
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   map[string]string
+}
+
+// serveHTTP runs mux on addr in the background, logging if it stops.
+func serveHTTP(name, addr string, mux *http.ServeMux) {
+	go func() {
+		slog.Info(name+" listening", "addr", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error(name+" server stopped", "err", err)
+		}
+	}()
+}
+
+func (b *Bot) startHealthServer(addr string) {
+	r := &readiness{bot: b}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", r.handle)
+
+	serveHTTP("Health", addr, mux)
+}
+
+func (r *readiness) handle(w http.ResponseWriter, req *http.Request) {
+	results, ready := r.check(req.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// check runs the readiness checks, reusing the last results for a short
+// while so probes don't hit Telegram and Groq on every request.
+func (r *readiness) check(ctx context.Context) (map[string]string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) > readinessCache {
+		r.results = map[string]string{}
+		for _, d := range r.bot.diagnose(ctx) {
+			r.results[d.Name] = status(d.Err)
+		}
+		r.checkedAt = time.Now()
+	}
+
+	for _, s := range r.results {
+		if s != "ok" {
+			return r.results, false
+		}
+	}
+	return r.results, true
+}
+
+func status(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
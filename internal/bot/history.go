@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const searchResultsShown = 8
+
+var btnJump = tele.Btn{Unique: "jump"}
+
+// searchHandler finds the sender's exchanges in this chat that mention
+// every word of the payload, with buttons that jump to the answers.
+func (b *Bot) searchHandler(c tele.Context) error {
+	query := strings.TrimSpace(c.Message().Payload)
+	if query == "" {
+		return c.Send(b.t(c, "Usage: /search <words>, e.g. /search kubernetes ingress"))
+	}
+	prefix := ""
+	if inGroup(c) {
+		prefix = groupPrefix(c.Chat().ID)
+	}
+	results, err := b.db.SearchExchanges(c.Sender().ID, prefix, query, searchResultsShown)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not search your conversations: ") + err.Error())
+	}
+	if len(results) == 0 {
+		return c.Send(b.t(c, "Nothing in your conversations matches %q", query))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.t(c, "🔎 Found in your conversations:") + "\n\n")
+	menu := &tele.ReplyMarkup{}
+	var buttons []tele.Btn
+	for i, r := range results {
+		sb.WriteString(fmt.Sprintf("%d. %s  %s\n   %s\n\n", i+1, r.CreatedAt.Format(time.DateOnly), truncate(r.Prompt, 50), truncate(r.Snippet, 200)))
+		if r.MessageID != 0 {
+			buttons = append(buttons, menu.Data(b.t(c, "↩️ %d", i+1), btnJump.Unique, strconv.Itoa(r.MessageID)))
+		}
+	}
+	menu.Inline(menu.Split(4, buttons)...)
+	return c.Send(strings.TrimSpace(sb.String()), menu)
+}
+
+// jumpHandler replies to the answer a search result points at, so tapping
+// the quote scrolls the chat back to it.
+func (b *Bot) jumpHandler(c tele.Context) error {
+	id, err := strconv.Atoi(c.Callback().Data)
+	if err != nil {
+		return c.Respond()
+	}
+	c.Respond()
+	_, err = send(c, b.t(c, "⬆️ Here it is"), &tele.SendOptions{ReplyTo: &tele.Message{ID: id, Chat: c.Chat()}})
+	if err != nil {
+		return c.Send(b.t(c, "That message is gone from this chat"))
+	}
+	return nil
+}
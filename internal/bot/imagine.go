@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const imageTimeout = 2 * time.Minute
+
+func (b *Bot) imagineHandler(c tele.Context) error {
+	if b.images == nil {
+		return c.Send(b.t(c, "Image generation is not enabled on this bot"))
+	}
+	prompt := strings.TrimSpace(c.Message().Payload)
+	if prompt == "" {
+		return c.Send(b.t(c, "Usage: /imagine <what to draw>"))
+	}
+
+	if quota := b.imageQuota(c.Sender().ID); quota > 0 {
+		n, err := b.db.CountImageGenerations(c.Sender().ID, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not check your image quota: ") + err.Error())
+		}
+		if n >= quota {
+			return c.Send(b.t(c, "You've used your %d images for today, try again tomorrow", quota))
+		}
+	}
+	if !b.allowPrompt(c, prompt) {
+		return nil
+	}
+
+	c.Notify(tele.UploadingPhoto)
+	ctx, cancel := context.WithTimeout(requestContext(c), imageTimeout)
+	defer cancel()
+	data, err := b.images.GenerateImage(ctx, prompt)
+	if err != nil {
+		errorsTotal.WithLabelValues("images").Inc()
+		slog.ErrorContext(requestContext(c), "Could not generate image", "err", err)
+		return c.Send(b.t(c, "ERROR: Could not generate your image"))
+	}
+
+	if err := b.db.SaveImageGeneration(c.Sender().ID, prompt); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not record image generation", "err", err)
+	}
+	return c.Send(&tele.Photo{File: tele.FromReader(bytes.NewReader(data)), Caption: truncate(prompt, 200)})
+}
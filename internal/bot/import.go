@@ -0,0 +1,379 @@
+package bot
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// maxImportSize is the most bots may download from Telegram.
+	maxImportSize = 20 << 20
+	// importTTL is how long a parsed export waits for the user to pick
+	// what to import.
+	importTTL     = 30 * time.Minute
+	importsListed = 10
+	importAll     = "all"
+)
+
+var btnImport = tele.Btn{Unique: "import"}
+
+// importedChat is a conversation read from an export.
+type importedChat struct {
+	Title     string
+	Exchanges []store.Exchange
+}
+
+// pendingImport is an export the user is picking conversations from.
+type pendingImport struct {
+	mu       sync.Mutex
+	chats    []importedChat
+	imported []bool
+	at       time.Time
+}
+
+const importUsage = `Send me your export as a document, with /import as its caption or right after /import:
+- ChatGPT's conversations.json, or the zip it comes in
+- a JSON list of {"prompt", "response", "model", "timestamp"} objects, which is what /export json gives you
+
+You'll pick which conversations to import, each becomes a chat of its own.`
+
+func (b *Bot) importHandler(c tele.Context) error {
+	if reply := replyTo(c.Message()); reply != nil && reply.Document != nil {
+		return b.importDocument(c, reply.Document)
+	}
+	b.awaitingImport.Store(c.Sender().ID, true)
+	return c.Send(b.t(c, importUsage))
+}
+
+// withImports takes the documents sent for /import before they're read as
+// documents to answer from.
+func (b *Bot) withImports(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		doc := c.Message().Document
+		_, awaited := b.awaitingImport.LoadAndDelete(c.Sender().ID)
+		caption := strings.ToLower(strings.TrimSpace(c.Message().Caption))
+		if doc == nil || !awaited && !strings.HasPrefix(caption, "/import") {
+			return next(c)
+		}
+		return b.importDocument(c, doc)
+	}
+}
+
+func (b *Bot) importDocument(c tele.Context, doc *tele.Document) error {
+	if doc.FileSize > maxImportSize {
+		return c.Send(b.t(c, "File is too large, the limit is %dMB", maxImportSize>>20))
+	}
+	rc, err := c.Bot().File(&doc.File)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not download your file"))
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportSize+1))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not read your file"))
+	}
+
+	if bytes.HasPrefix(data, []byte("PK")) {
+		if data, err = unzipConversations(data); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not read your export: ") + err.Error())
+		}
+	}
+	chats, err := parseExport(data)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not read your export: ") + err.Error())
+	}
+	if len(chats) == 0 {
+		return c.Send(b.t(c, "There are no conversations in that file"))
+	}
+	for i := range chats {
+		if chats[i].Title == "" {
+			chats[i].Title = b.t(c, "Imported %s", time.Now().Format(time.DateOnly))
+		}
+	}
+	if len(chats) == 1 {
+		chat, err := b.importChat(c, chats[0])
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not import the conversation: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Imported %q with %d exchanges, you're in it now. /chats switches back", chat.Title, len(chats[0].Exchanges)))
+	}
+
+	p := &pendingImport{chats: chats, imported: make([]bool, len(chats)), at: time.Now()}
+	b.pendingImports.Store(c.Sender().ID, p)
+	return c.Send(b.t(c, "Found %d conversations, latest first. Tap the ones to import:", len(chats)), b.importMenu(c, p))
+}
+
+// importMenu lists the latest conversations not imported yet.
+func (b *Bot) importMenu(c tele.Context, p *pendingImport) *tele.ReplyMarkup {
+	menu := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	left := 0
+	for i, chat := range p.chats {
+		if p.imported[i] {
+			continue
+		}
+		left++
+		if len(rows) < importsListed {
+			label := fmt.Sprintf("📥 %s (%d)", truncate(chat.Title, 40), len(chat.Exchanges))
+			rows = append(rows, menu.Row(menu.Data(label, btnImport.Unique, strconv.Itoa(i))))
+		}
+	}
+	if left > 1 {
+		rows = append(rows, menu.Row(menu.Data(b.t(c, "Import all %d", left), btnImport.Unique, importAll)))
+	}
+	menu.Inline(rows...)
+	return menu
+}
+
+func (b *Bot) importPressHandler(c tele.Context) error {
+	v, ok := b.pendingImports.Load(c.Sender().ID)
+	if !ok || time.Since(v.(*pendingImport).at) > importTTL {
+		b.pendingImports.Delete(c.Sender().ID)
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That import expired, send the file again")})
+	}
+	p := v.(*pendingImport)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var picked []int
+	if data := c.Callback().Data; data == importAll {
+		for i := range p.chats {
+			if !p.imported[i] {
+				picked = append(picked, i)
+			}
+		}
+	} else if i, err := strconv.Atoi(data); err == nil && i >= 0 && i < len(p.chats) && !p.imported[i] {
+		picked = []int{i}
+	}
+	if len(picked) == 0 {
+		return c.Respond()
+	}
+	c.Respond()
+
+	// Import the oldest first, so the latest is the one the user ends up in.
+	var last store.Chat
+	for j := len(picked) - 1; j >= 0; j-- {
+		i := picked[j]
+		chat, err := b.importChat(c, p.chats[i])
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not import the conversation: ") + err.Error())
+		}
+		p.imported[i] = true
+		last = chat
+	}
+
+	menu := b.importMenu(c, p)
+	if len(menu.InlineKeyboard) == 0 {
+		b.pendingImports.Delete(c.Sender().ID)
+		c.Edit(b.t(c, "All imported, see /chats"))
+	} else {
+		c.Edit(menu)
+	}
+	if len(picked) > 1 {
+		return c.Send(b.t(c, "Imported %d conversations, you're in %q now. /chats switches between them", len(picked), last.Title))
+	}
+	return c.Send(b.t(c, "Imported %q, you're in it now. /chats switches back", last.Title))
+}
+
+// importChat saves the conversation as a chat and makes it the active one.
+func (b *Bot) importChat(c tele.Context, imported importedChat) (store.Chat, error) {
+	for i := range imported.Exchanges {
+		b.redactExchange(&imported.Exchanges[i])
+	}
+	chat, err := b.db.ImportChat(c.Sender().ID, truncate(imported.Title, maxTitleLen), imported.Exchanges)
+	if err != nil {
+		return chat, err
+	}
+	return chat, b.db.SetPreference(c.Sender().ID, chatPreference, chat.ID)
+}
+
+// unzipConversations finds conversations.json in a ChatGPT export zip.
+func unzipConversations(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != "conversations.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, 10*maxImportSize))
+	}
+	return nil, errors.New("no conversations.json in the zip")
+}
+
+// parseExport reads a ChatGPT or groqy export, latest conversation first.
+func parseExport(data []byte) ([]importedChat, error) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, errors.New("not a JSON list of conversations")
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	var chats []importedChat
+	var err error
+	switch {
+	case items[0]["mapping"] != nil:
+		chats, err = parseChatGPT(data)
+	case items[0]["prompt"] != nil:
+		chats, err = parseExchangeList(data)
+	default:
+		return nil, errors.New("not a ChatGPT or groqy export")
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(chats, func(i, j int) bool {
+		return chats[i].Exchanges[len(chats[i].Exchanges)-1].CreatedAt.After(chats[j].Exchanges[len(chats[j].Exchanges)-1].CreatedAt)
+	})
+	return chats, nil
+}
+
+// chatGPTConversation is a conversation of ChatGPT's conversations.json,
+// a tree of messages whose branch current_node ends is the one shown.
+type chatGPTConversation struct {
+	Title       string                    `json:"title"`
+	CurrentNode string                    `json:"current_node"`
+	Mapping     map[string]chatGPTMessage `json:"mapping"`
+}
+
+type chatGPTMessage struct {
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		Content struct {
+			Parts []json.RawMessage `json:"parts"`
+		} `json:"content"`
+		CreateTime float64 `json:"create_time"`
+		Metadata   struct {
+			ModelSlug string `json:"model_slug"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+func parseChatGPT(data []byte) ([]importedChat, error) {
+	var conversations []chatGPTConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, err
+	}
+	var chats []importedChat
+	for _, conv := range conversations {
+		// Walk the shown branch up from its last message.
+		var branch []chatGPTMessage
+		for id := conv.CurrentNode; id != "" && len(branch) <= len(conv.Mapping); {
+			node, ok := conv.Mapping[id]
+			if !ok {
+				break
+			}
+			branch = append(branch, node)
+			id = node.Parent
+		}
+
+		var exchanges []store.Exchange
+		var cur store.Exchange
+		for i := len(branch) - 1; i >= 0; i-- {
+			msg := branch[i].Message
+			if msg == nil {
+				continue
+			}
+			var texts []string
+			for _, part := range msg.Content.Parts {
+				var s string
+				if json.Unmarshal(part, &s) == nil && strings.TrimSpace(s) != "" {
+					texts = append(texts, s)
+				}
+			}
+			text := strings.Join(texts, "\n")
+			if text == "" {
+				continue
+			}
+			switch msg.Author.Role {
+			case "user":
+				if cur.Response != "" {
+					exchanges = append(exchanges, cur)
+					cur = store.Exchange{}
+				}
+				if cur.Prompt != "" {
+					cur.Prompt += "\n\n"
+				}
+				cur.Prompt += text
+				if msg.CreateTime > 0 {
+					cur.CreatedAt = time.Unix(int64(msg.CreateTime), 0)
+				}
+			case "assistant":
+				if cur.Prompt == "" {
+					continue
+				}
+				if cur.Response != "" {
+					cur.Response += "\n\n"
+				}
+				cur.Response += text
+				cur.Model = msg.Metadata.ModelSlug
+				if cur.Model == "" {
+					cur.Model = "chatgpt"
+				}
+			}
+		}
+		if cur.Prompt != "" && cur.Response != "" {
+			exchanges = append(exchanges, cur)
+		}
+		if len(exchanges) > 0 {
+			chats = append(chats, importedChat{Title: conv.Title, Exchanges: exchanges})
+		}
+	}
+	return chats, nil
+}
+
+// parseExchangeList reads a list of exchanges like /export json's as one
+// conversation.
+func parseExchangeList(data []byte) ([]importedChat, error) {
+	var list []exportedExchange
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	var exchanges []store.Exchange
+	for _, ex := range list {
+		if ex.Prompt == "" || ex.Response == "" {
+			continue
+		}
+		model := ex.Model
+		if model == "" {
+			model = "imported"
+		}
+		exchanges = append(exchanges, store.Exchange{
+			Prompt:           ex.Prompt,
+			Response:         ex.Response,
+			Model:            model,
+			PromptTokens:     ex.PromptTokens,
+			CompletionTokens: ex.CompletionTokens,
+			CreatedAt:        ex.Timestamp,
+		})
+	}
+	if len(exchanges) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(exchanges, func(i, j int) bool { return exchanges[i].CreatedAt.Before(exchanges[j].CreatedAt) })
+	return []importedChat{{Exchanges: exchanges}}, nil
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+// maxJobAttempts is how many restarts a job is picked up after before it's
+// dropped, so a request that brings the bot down doesn't do it forever.
+const maxJobAttempts = 1
+
+// saveJob records the request behind c until it's answered, returning its
+// ID, "" when it couldn't be recorded.
+func (b *Bot) saveJob(c tele.Context) string {
+	upd := c.Update()
+	msg := c.Message()
+	if upd.ID == 0 || msg == nil {
+		return ""
+	}
+	// The job is kept like an exchange, and scrubbed the same way before
+	// it's stored. A retried request gets the scrubbed text.
+	level := b.cfg().Redaction
+	upd.Message = redactMessage(level, upd.Message)
+	upd.EditedMessage = redactMessage(level, upd.EditedMessage)
+	if cb := upd.Callback; cb != nil {
+		restored := *cb
+		restored.Message = redactMessage(level, cb.Message)
+		// telebot splits the data of the buttons it routes, put it back the
+		// way Telegram sent it so the update routes again.
+		if cb.Unique != "" {
+			restored.Data = "\f" + cb.Unique
+			if cb.Data != "" {
+				restored.Data += "|" + cb.Data
+			}
+		}
+		upd.Callback = &restored
+	}
+	payload, err := json.Marshal(upd)
+	if err != nil {
+		slog.ErrorContext(requestContext(c), "Could not record job", "err", err)
+		return ""
+	}
+	prompt := ""
+	if c.Callback() == nil {
+		prompt = msg.Text
+		if prompt == "" {
+			prompt = msg.Caption
+		}
+		prompt = redact(level, prompt)
+	}
+	j := store.Job{
+		ID:        strconv.Itoa(upd.ID),
+		UserID:    c.Sender().ID,
+		ChatID:    c.Chat().ID,
+		MessageID: msg.ID,
+		Prompt:    prompt,
+		Payload:   string(payload),
+	}
+	if err := b.db.SaveJob(j); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not record job", "err", err)
+		return ""
+	}
+	if b.cfg().ReadReceipts && c.Callback() == nil {
+		b.tele.React(c.Chat(), msg, tele.ReactionOptions{Reactions: []tele.Reaction{{Type: "emoji", Emoji: "👀"}}})
+	}
+	return j.ID
+}
+
+func (b *Bot) finishJob(id string) {
+	if id == "" {
+		return
+	}
+	if err := b.db.DeleteJob(id); err != nil {
+		slog.Error("Could not clear job", "job_id", id, "err", err)
+	}
+}
+
+// recoverJobs picks up the requests a restart cut off: recent ones are
+// answered again, the rest dropped, and either way their users are told.
+func (b *Bot) recoverJobs() {
+	jobs, err := b.db.PendingJobs()
+	if err != nil {
+		slog.Error("Could not load unfinished jobs", "err", err)
+		return
+	}
+	for _, j := range jobs {
+		lang := b.userLanguage(&tele.User{ID: j.UserID})
+		opts := &tele.SendOptions{ReplyTo: &tele.Message{ID: j.MessageID}, AllowWithoutReply: true}
+		about := tr(lang, "your last request")
+		if j.Prompt != "" {
+			about = strconv.Quote(truncate(j.Prompt, 60))
+		}
+
+		var upd tele.Update
+		decodeErr := json.Unmarshal([]byte(j.Payload), &upd)
+		if decodeErr != nil || j.Attempts >= maxJobAttempts || time.Since(j.CreatedAt) > b.cfg().JobRetryWindow {
+			if decodeErr != nil {
+				slog.Error("Could not read unfinished job", "job_id", j.ID, "err", decodeErr)
+			}
+			jobsRecoveredTotal.WithLabelValues("dropped").Inc()
+			b.finishJob(j.ID)
+			if _, err := b.tele.Send(&tele.Chat{ID: j.ChatID}, tr(lang, "⚠️ I restarted before answering %s and couldn't pick it back up, please send it again", about), opts); err != nil {
+				slog.Error("Could not tell user about dropped job", "user_id", j.UserID, "err", err)
+			}
+			continue
+		}
+
+		if err := b.db.SetJobAttempts(j.ID, j.Attempts+1); err != nil {
+			slog.Error("Could not record job attempt", "job_id", j.ID, "err", err)
+			continue
+		}
+		jobsRecoveredTotal.WithLabelValues("retried").Inc()
+		if _, err := b.tele.Send(&tele.Chat{ID: j.ChatID}, tr(lang, "🔁 I restarted while working on %s, answering it now", about), opts); err != nil {
+			slog.Error("Could not tell user about retried job", "user_id", j.UserID, "err", err)
+		}
+		b.tele.ProcessUpdate(upd)
+	}
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+// minDetectLength keeps detection off short messages like "ok" or "thanks",
+// where it is mostly guessing.
+const minDetectLength = 20
+
+const translateInstruct = "You are a translator. Translate the user's text into %s. Reply with the translation only, no notes or explanations."
+
+// detectLanguage returns the name of the language text is written in, or ""
+// when the text is too short or detection isn't reliable.
+func detectLanguage(text string) string {
+	if len([]rune(strings.TrimSpace(text))) < minDetectLength {
+		return ""
+	}
+	info := whatlanggo.Detect(text)
+	if !info.IsReliable() {
+		return ""
+	}
+	return info.Lang.String()
+}
+
+// languageInstruct asks the model to answer in the language of the user's
+// message.
+func languageInstruct(userMessage string) string {
+	lang := detectLanguage(userMessage)
+	if lang == "" {
+		return ""
+	}
+	return fmt.Sprintf("The user is writing in %s, reply in %s.", lang, lang)
+}
+
+// translateHandler translates the replied-to message, or the text after the
+// language, into the requested language.
+func (b *Bot) translateHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(b.t(c, "Usage: reply to a message with /translate <language>, or /translate <language> <text>"))
+	}
+	lang := args[0]
+
+	text := strings.Join(args[1:], " ")
+	if reply := replyTo(c.Message()); reply != nil {
+		text = reply.Text
+		if text == "" {
+			text = reply.Caption
+		}
+	}
+	if text == "" {
+		return c.Send(b.t(c, "Nothing to translate, reply to a message or add some text"))
+	}
+
+	apiKey, err := b.groqKeyFor(c.Sender())
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+
+	start := time.Now()
+	res, err := b.llm.Complete(requestContext(c), apiKey, []llm.Message{
+		{Role: "system", Content: fmt.Sprintf(translateInstruct, lang)},
+		{Role: "user", Content: text},
+	})
+	b.audit(c.Sender(), "/translate "+lang+": "+text, res, time.Since(start), err)
+	if err != nil {
+		errorsTotal.WithLabelValues("groq").Inc()
+		return c.Send(errorReply(b.lang(c), err))
+	}
+	b.recordRequest(c.Sender(), res, time.Since(start))
+	return c.Send(res.Content)
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	"github.com/musaubrian/groqy/internal/web"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	maxPageSize = 5 << 20
+	// pageChunkSize keeps each part of a long page well inside the
+	// smaller models' context.
+	pageChunkSize = 12000
+	maxPageChunks = 6
+
+	pageSummaryInstruct  = "Summarize the web page below for the user in a few short paragraphs or bullet points, keeping the key facts, figures and conclusions. Plain text, no markdown."
+	partSummaryInstruct  = "Below is part %d of %d of a web page. Summarize it briefly, keeping the key facts, figures and conclusions."
+	mergeSummaryInstruct = "Below are summaries of consecutive parts of one web page. Combine them into one summary of the page in a few short paragraphs or bullet points. Plain text, no markdown."
+)
+
+var linkRe = regexp.MustCompile(`^https?://\S+$`)
+
+// isLink reports whether the message is nothing but a URL.
+func isLink(text string) bool {
+	return linkRe.MatchString(strings.TrimSpace(text))
+}
+
+func (b *Bot) summarizeHandler(c tele.Context) error {
+	link := strings.TrimSpace(c.Message().Payload)
+	if !isLink(link) {
+		return c.Send(b.t(c, "Usage: /summarize <url>, or just send me a link"))
+	}
+	return b.summarizeLink(c, link)
+}
+
+// summarizeLink fetches the page at link and answers with its summary,
+// kept in the conversation so the user can ask about it afterwards.
+func (b *Bot) summarizeLink(c tele.Context, link string) error {
+	msg, err := send(c, b.t(c, "📖 Reading the page…"))
+	if err != nil {
+		return err
+	}
+
+	page, err := b.fetcher.Fetch(requestContext(c), link)
+	switch {
+	case errors.Is(err, web.ErrDisallowed):
+		_, err = c.Bot().Edit(msg, b.t(c, "That site's robots.txt doesn't let me read this page"))
+		return err
+	case errors.Is(err, web.ErrTooLarge):
+		_, err = c.Bot().Edit(msg, b.t(c, "That page is too large, the limit is %dMB", maxPageSize>>20))
+		return err
+	case errors.Is(err, web.ErrUnsupported):
+		_, err = c.Bot().Edit(msg, b.t(c, "I can only summarize web pages and plain text"))
+		return err
+	case err != nil:
+		_, err = c.Bot().Edit(msg, b.t(c, "ERROR: Could not fetch the page: ")+err.Error())
+		return err
+	}
+	if strings.TrimSpace(page.Text) == "" {
+		_, err = c.Bot().Edit(msg, b.t(c, "That page doesn't seem to contain any text"))
+		return err
+	}
+
+	res, err := b.summarizePage(c, link, page)
+	if err != nil {
+		_, err = c.Bot().Edit(msg, errorReply(b.lang(c), err))
+		return err
+	}
+	text := res.Content
+	if page.Title != "" {
+		text = page.Title + "\n\n" + text
+	}
+	if _, err := c.Bot().Edit(msg, text); err != nil {
+		return err
+	}
+
+	ex := store.Exchange{
+		UserID:           c.Sender().ID,
+		ChatID:           b.activeChat(c),
+		Prompt:           link,
+		Response:         text,
+		Model:            res.Model,
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		MessageID:        msg.ID,
+		PromptMessageID:  c.Message().ID,
+	}
+	b.redactExchange(&ex)
+	if err := b.db.SaveExchange(&ex); err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		slog.ErrorContext(requestContext(c), "Could not save exchange", "err", err)
+	}
+	return nil
+}
+
+// summarizePage summarizes short pages in one go. Longer ones are
+// summarized a part at a time and the parts merged, pages longer than
+// maxPageChunks parts being cut off.
+func (b *Bot) summarizePage(c tele.Context, link string, page web.Page) (llm.Completion, error) {
+	model := llm.WithModel(b.userModel(c.Sender().ID))
+	summarize := func(instruct, text string) (llm.Completion, error) {
+		return b.complete(c, link, func(apiKey string) (llm.Completion, error) {
+			return b.llm.Complete(requestContext(c), apiKey, []llm.Message{
+				{Role: "system", Content: instruct},
+				{Role: "user", Content: text},
+			}, model)
+		})
+	}
+
+	parts := splitText(page.Text, pageChunkSize, 0)
+	if len(parts) == 1 {
+		return summarize(pageSummaryInstruct, parts[0])
+	}
+	if len(parts) > maxPageChunks {
+		parts = parts[:maxPageChunks]
+	}
+
+	var summaries strings.Builder
+	var promptTokens, completionTokens int
+	for i, part := range parts {
+		res, err := summarize(fmt.Sprintf(partSummaryInstruct, i+1, len(parts)), part)
+		if err != nil {
+			return res, err
+		}
+		promptTokens += res.PromptTokens
+		completionTokens += res.CompletionTokens
+		fmt.Fprintf(&summaries, "Part %d:\n%s\n\n", i+1, res.Content)
+	}
+	res, err := summarize(mergeSummaryInstruct, summaries.String())
+	res.PromptTokens += promptTokens
+	res.CompletionTokens += completionTokens
+	return res, err
+}
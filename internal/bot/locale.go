@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/i18n"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	languagePreference = "language"
+	// langKey is where withLocale keeps the sender's language on the context.
+	langKey = "lang"
+)
+
+// withLocale works out the sender's language once per update for t.
+func (b *Bot) withLocale(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if sender := c.Sender(); sender != nil {
+			c.Set(langKey, b.userLanguage(sender))
+		}
+		return next(c)
+	}
+}
+
+// userLanguage is the language set with /language, the one the user's
+// Telegram client is in otherwise.
+func (b *Bot) userLanguage(user *tele.User) string {
+	lang, err := b.db.GetPreference(user.ID, languagePreference)
+	if err != nil && err != sql.ErrNoRows {
+		slog.Error("Could not load language", "err", err)
+	}
+	if i18n.Supported(lang) {
+		return lang
+	}
+	return i18n.Normalize(user.LanguageCode)
+}
+
+// lang is the sender's language.
+func (b *Bot) lang(c tele.Context) string {
+	lang, ok := c.Get(langKey).(string)
+	if !ok && c.Sender() != nil {
+		lang = b.userLanguage(c.Sender())
+	}
+	return lang
+}
+
+// t translates msg into the sender's language and formats it with args
+// like fmt.Sprintf.
+func (b *Bot) t(c tele.Context, msg string, args ...any) string {
+	return tr(b.lang(c), msg, args...)
+}
+
+// tr translates msg into lang and formats it with args.
+func tr(lang, msg string, args ...any) string {
+	msg = i18n.T(lang, msg)
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func (b *Bot) languageHandler(c tele.Context) error {
+	lang := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	switch {
+	case lang == "":
+		return c.Send(b.t(c, "Usage: /language <%s>, or /language auto to follow your Telegram app", strings.Join(i18n.Languages(), "|")))
+	case lang == "auto":
+		lang = ""
+	case !i18n.Supported(lang):
+		return c.Send(b.t(c, "I don't speak %q yet, pick one of %s", lang, strings.Join(i18n.Languages(), ", ")))
+	}
+
+	if err := b.db.SetPreference(c.Sender().ID, languagePreference, lang); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	lang = b.userLanguage(c.Sender())
+	c.Set(langKey, lang)
+	return c.Send(tr(lang, "Done, I'll reply in English from now on"))
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+)
+
+const (
+	maxRecalledMemories = 3
+	minMemorySimilarity = 0.3
+	memoryInstruct      = "Here are some possibly relevant exchanges from earlier conversations with this user, use them only if they help:\n\n"
+)
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return v
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// remember embeds a finished exchange and stores it as a memory.
+func (b *Bot) remember(ex store.Exchange) error {
+	content := fmt.Sprintf("User: %s\nAssistant: %s", ex.Prompt, ex.Response)
+	vec, err := b.embedder.Embed(content)
+	if err != nil {
+		return err
+	}
+	return b.db.SaveMemory(store.Memory{
+		UserID:         ex.UserID,
+		ConversationID: ex.ID,
+		Content:        content,
+		Embedding:      encodeVector(vec),
+	})
+}
+
+// recall returns a system prompt with the user's past exchanges most similar
+// to the prompt, skipping the ones already in the rolling context window.
+func (b *Bot) recall(userID int64, prompt string, inContext []store.Exchange) (string, error) {
+	memories, err := b.db.GetMemories(userID)
+	if err != nil || len(memories) == 0 {
+		return "", err
+	}
+
+	query, err := b.embedder.Embed(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	skip := map[string]bool{}
+	for _, ex := range inContext {
+		skip[ex.ID] = true
+	}
+
+	type scored struct {
+		memory store.Memory
+		score  float64
+	}
+	var results []scored
+	for _, m := range memories {
+		if skip[m.ConversationID] {
+			continue
+		}
+		if score := cosineSimilarity(query, decodeVector(m.Embedding)); score >= minMemorySimilarity {
+			results = append(results, scored{m, score})
+		}
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	var sb strings.Builder
+	sb.WriteString(memoryInstruct)
+	for i := 0; i < len(results) && i < maxRecalledMemories; i++ {
+		sb.WriteString(fmt.Sprintf("[%s]\n%s\n\n", results[i].memory.CreatedAt.Format(time.DateOnly), results[i].memory.Content))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// maxCommandDescription is Telegram's limit on a command's description.
+const maxCommandDescription = 256
+
+// publishCommands sets the command menus Telegram clients show, one for
+// private chats and one for groups, in every language the commands are
+// translated to.
+func (b *Bot) publishCommands() error {
+	languages := map[string]bool{"": true}
+	for _, cmd := range b.commands {
+		for lang := range cmd.Translations {
+			languages[lang] = true
+		}
+	}
+
+	scopes := []struct {
+		scope   tele.CommandScope
+		private bool
+	}{
+		{tele.CommandScope{Type: tele.CommandScopeAllPrivateChats}, true},
+		{tele.CommandScope{Type: tele.CommandScopeAllGroupChats}, false},
+	}
+	for _, s := range scopes {
+		for lang := range languages {
+			if err := b.tele.SetCommands(menuCommands(b.commands, s.private, lang), s.scope, lang); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// menuCommands lists the commands offered in a private chat or a group, with
+// descriptions in lang where there's a translation.
+func menuCommands(commands []Command, private bool, lang string) []tele.Command {
+	var menu []tele.Command
+	for _, cmd := range commands {
+		if cmd.Admin || (cmd.Private && !private) {
+			continue
+		}
+		description := cmd.Description
+		if t, ok := cmd.Translations[lang]; ok {
+			description = t
+		}
+		menu = append(menu, tele.Command{
+			Text:        strings.TrimPrefix(cmd.Name, "/"),
+			Description: truncate(description, maxCommandDescription-1),
+		})
+	}
+	return menu
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	messagesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_messages_handled_total",
+		Help: "Updates handled, by handler.",
+	}, []string{"handler"})
+
+	groqLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "groqy_groq_request_duration_seconds",
+		Help:    "Latency of Groq chat completion requests.",
+		Buckets: []float64{.25, .5, 1, 2, 4, 8, 16, 32},
+	}, []string{"model"})
+
+	tokensConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_tokens_total",
+		Help: "Tokens consumed, by model and kind (prompt or completion).",
+	}, []string{"model", "kind"})
+
+	failovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_failovers_total",
+		Help: "Answers from a fallback model, by the model asked for and the one that answered.",
+	}, []string{"from", "to"})
+
+	feedbackTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_feedback_total",
+		Help: "Reactions on answers, by rating (up, down or cleared).",
+	}, []string{"rating"})
+
+	paymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_payments_total",
+		Help: "Plans bought with Telegram Stars, by plan.",
+	}, []string{"plan"})
+
+	jobsRecoveredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_jobs_recovered_total",
+		Help: "Requests left unanswered by a restart, by whether they were retried or dropped.",
+	}, []string{"outcome"})
+
+	throttledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "groqy_throttled_total",
+		Help: "Users put in a cooldown for abuse.",
+	})
+
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "groqy_errors_total",
+		Help: "Errors, by type.",
+	}, []string{"type"})
+)
+
+// startMetricsServer exposes /metrics on addr. activeUsers backs the
+// active users gauge and is evaluated on every scrape.
+func startMetricsServer(addr string, activeUsers func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "groqy_active_users",
+		Help: "Distinct users with at least one exchange in the last 24 hours.",
+	}, activeUsers)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	serveHTTP("Metrics", addr, mux)
+}
+
+func observeCompletion(res llm.Completion, elapsed time.Duration) {
+	groqLatency.WithLabelValues(res.Model).Observe(elapsed.Seconds())
+	tokensConsumed.WithLabelValues(res.Model, "prompt").Add(float64(res.PromptTokens))
+	tokensConsumed.WithLabelValues(res.Model, "completion").Add(float64(res.CompletionTokens))
+}
+
+// withMetrics counts every update by the handler it goes to.
+func withMetrics(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		messagesHandled.WithLabelValues(endpointName(c)).Inc()
+		err := next(c)
+		if err != nil {
+			errorsTotal.WithLabelValues("handler").Inc()
+		}
+		return err
+	}
+}
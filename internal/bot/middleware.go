@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/logging"
+	"github.com/musaubrian/groqy/internal/tracing"
+	"github.com/oklog/ulid/v2"
+	tele "gopkg.in/telebot.v3"
+)
+
+// middleware is the chain every handler runs behind, outermost first.
+// Handler specific steps like withAuth and withQueue are passed to Handle
+// on top of it.
+func (b *Bot) middleware() []tele.MiddlewareFunc {
+	return []tele.MiddlewareFunc{withRequestID, withTracing, withTopic, b.withRecovery, withLogging, withMetrics, b.withLocale}
+}
+
+// requestIDKey holds the ID of the update on its context.
+const requestIDKey = "request_id"
+
+// withRequestID gives every update an ID that its log lines and the calls
+// made for it carry.
+func withRequestID(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		c.Set(requestIDKey, ulid.Make().String())
+		return next(c)
+	}
+}
+
+// spanKey holds the context carrying the span the update's work is traced
+// under.
+const spanKey = "span"
+
+// withTracing traces every update in a telegram_update span.
+func withTracing(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		ctx, span := tracing.Start(context.Background(), "telegram_update", tracing.Server)
+		if span == nil {
+			return next(c)
+		}
+		defer span.End()
+		c.Set(spanKey, ctx)
+		err := next(c)
+		span.Set("handler", endpointName(c))
+		span.Set("update.id", c.Update().ID)
+		if sender := c.Sender(); sender != nil {
+			span.Set("user.id", sender.ID)
+		}
+		if chat := c.Chat(); chat != nil {
+			span.Set("chat.type", string(chat.Type))
+		}
+		span.Fail(err)
+		return err
+	}
+}
+
+// startSpan starts a span of the update's work, the parent of the spans
+// started under requestContext(c) until the returned func ends it.
+func startSpan(c tele.Context, name string) func(error) {
+	parent, ok := c.Get(spanKey).(context.Context)
+	if !ok {
+		return func(error) {}
+	}
+	ctx, span := tracing.Start(parent, name, tracing.Internal)
+	c.Set(spanKey, ctx)
+	return func(err error) {
+		span.Fail(err)
+		span.End()
+		c.Set(spanKey, parent)
+	}
+}
+
+// requestContext carries the ID of the update c is for and the span its
+// work is traced under.
+func requestContext(c tele.Context) context.Context {
+	ctx, ok := c.Get(spanKey).(context.Context)
+	if !ok {
+		ctx = context.Background()
+	}
+	id, _ := c.Get(requestIDKey).(string)
+	return logging.WithRequestID(ctx, id)
+}
+
+// endpointName names the handler an update goes to: the command, the
+// button's unique name or the kind of message.
+func endpointName(c tele.Context) string {
+	if cb := c.Callback(); cb != nil {
+		if cb.Unique != "" {
+			return cb.Unique
+		}
+		return "callback"
+	}
+	if c.Update().EditedMessage != nil {
+		return "edited"
+	}
+	if c.PreCheckoutQuery() != nil {
+		return "checkout"
+	}
+	msg := c.Message()
+	if msg == nil {
+		return "unknown"
+	}
+	if msg.Document != nil {
+		return "document"
+	}
+	if msg.Payment != nil {
+		return "payment"
+	}
+	if strings.HasPrefix(msg.Text, "/") {
+		name, _, _ := strings.Cut(strings.Fields(msg.Text)[0], "@")
+		return name
+	}
+	return "text"
+}
+
+// withLogging logs every update at debug level with how long it took, and
+// the handler's error instead of leaving it to telebot.
+func withLogging(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		start := time.Now()
+		err := next(c)
+		var username string
+		if sender := c.Sender(); sender != nil {
+			username = sender.Username
+		}
+		ctx := requestContext(c)
+		slog.DebugContext(ctx, "Handled update", "handler", endpointName(c), "username", username, "duration", time.Since(start).Round(time.Millisecond))
+		if err != nil {
+			slog.ErrorContext(ctx, "Handler failed", "handler", endpointName(c), "err", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const violationsShown = 10
+
+// hazardCategories are the Llama Guard 3 hazard codes.
+var hazardCategories = map[string]string{
+	"S1":  "violent crimes",
+	"S2":  "non-violent crimes",
+	"S3":  "sex-related crimes",
+	"S4":  "child sexual exploitation",
+	"S5":  "defamation",
+	"S6":  "specialized advice",
+	"S7":  "privacy",
+	"S8":  "intellectual property",
+	"S9":  "indiscriminate weapons",
+	"S10": "hate",
+	"S11": "suicide and self-harm",
+	"S12": "sexual content",
+	"S13": "elections",
+	"S14": "code interpreter abuse",
+}
+
+// blockedCategory reports whether the policy refuses a hazard category.
+// MODERATION_BLOCK lists the refused codes, all of them when unset.
+func (b *Bot) blockedCategory(code string) bool {
+	if len(b.cfg().ModerationBlock) == 0 {
+		return true
+	}
+	for _, c := range b.cfg().ModerationBlock {
+		if strings.EqualFold(c, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// moderate classifies prompt with Llama Guard and returns the hazard codes
+// it violates that the policy blocks.
+func (b *Bot) moderate(ctx context.Context, user *tele.User, prompt string) ([]string, error) {
+	apiKey, err := b.groqKeyFor(user)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := b.llm.Complete(ctx, apiKey, []llm.Message{{Role: "user", Content: prompt}}, llm.WithModel(b.cfg().ModerationModel))
+	if err != nil {
+		return nil, err
+	}
+	b.recordUsage(user, res, time.Since(start))
+
+	verdict := strings.Fields(strings.ReplaceAll(res.Content, ",", " "))
+	if len(verdict) == 0 || verdict[0] != "unsafe" {
+		return nil, nil
+	}
+
+	var blocked []string
+	for _, code := range verdict[1:] {
+		if b.blockedCategory(code) {
+			blocked = append(blocked, code)
+		}
+	}
+	return blocked, nil
+}
+
+// allowPrompt runs the moderation stage for c's sender, replying with a
+// refusal and logging the violation when the prompt is blocked. Moderation
+// failures let the prompt through.
+func (b *Bot) allowPrompt(c tele.Context, prompt string) bool {
+	if !b.cfg().Moderation {
+		return true
+	}
+
+	blocked, err := b.moderate(requestContext(c), c.Sender(), prompt)
+	if err != nil {
+		errorsTotal.WithLabelValues("moderation").Inc()
+		slog.ErrorContext(requestContext(c), "Could not moderate prompt", "err", err)
+		return true
+	}
+	if len(blocked) == 0 {
+		return true
+	}
+
+	v := store.Violation{
+		UserID:     c.Sender().ID,
+		Username:   c.Sender().Username,
+		Categories: strings.Join(blocked, ","),
+		Prompt:     redact(b.cfg().Redaction, prompt),
+	}
+	if err := b.db.SaveViolation(v); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not log moderation violation", "err", err)
+	}
+	defer b.noteViolation(c)
+
+	names := make([]string, 0, len(blocked))
+	for _, code := range blocked {
+		name, ok := hazardCategories[strings.ToUpper(code)]
+		if !ok {
+			name = code
+		}
+		names = append(names, name)
+	}
+	c.Send(b.t(c, "Sorry, I can't help with that (%s)", strings.Join(names, ", ")))
+	return false
+}
+
+func (b *Bot) violationsHandler(c tele.Context) error {
+	username := ""
+	if args := c.Args(); len(args) > 0 {
+		username = strings.TrimPrefix(args[0], "@")
+	}
+
+	violations, err := b.db.GetViolations(username, violationsShown)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not read violations: ") + err.Error())
+	}
+	if len(violations) == 0 {
+		return c.Send(b.t(c, "No moderation violations"))
+	}
+
+	var sb strings.Builder
+	for _, v := range violations {
+		sb.WriteString(fmt.Sprintf("%s  @%s  %s\n> %s\n\n",
+			v.CreatedAt.Format(time.DateTime), v.Username, v.Categories, truncate(v.Prompt, auditPreviewLen)))
+	}
+	return c.Send(sb.String())
+}
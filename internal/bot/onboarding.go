@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"database/sql"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	onboardedPreference = "onboarded"
+	modelPreference     = "model"
+	personaPreference   = "persona"
+
+	welcomeText = "Hi %s, I'm a chat assistant running on Groq.\n\nI can answer questions and keep the conversation going, read documents you send me, translate, remind you of things on a schedule, and more, see the command menu for everything.\n\nFirst you need an access token from whoever runs this bot."
+)
+
+// models are the Groq models users can pick as their default.
+var models = []string{llm.DefaultModel, "llama-3.3-70b-versatile", "gemma2-9b-it", "mixtral-8x7b-32768", "deepseek-r1-distill-llama-70b", "qwen-qwq-32b"}
+
+type persona struct {
+	Name     string
+	Label    string
+	Instruct string
+}
+
+var personas = []persona{
+	{Name: "default", Label: "🙂 Default"},
+	{Name: "concise", Label: "✂️ Concise", Instruct: "Answer as briefly as possible, a sentence or two unless asked for more."},
+	{Name: "teacher", Label: "🎓 Teacher", Instruct: "Explain things step by step like a patient teacher, checking the user follows along."},
+	{Name: "coder", Label: "💻 Coder", Instruct: "You are a senior software engineer. Prefer code and precise technical detail over prose."},
+}
+
+var (
+	btnOnboardAuth    = tele.Btn{Unique: "onboard_auth"}
+	btnOnboardSkip    = tele.Btn{Unique: "onboard_skip"}
+	btnOnboardSetup   = tele.Btn{Unique: "onboard_setup"}
+	btnOnboardModel   = tele.Btn{Unique: "onboard_model"}
+	btnOnboardPersona = tele.Btn{Unique: "onboard_persona"}
+)
+
+// userModel is the model the user picked, the default one otherwise.
+func (b *Bot) userModel(userID int64) string {
+	model, _ := b.db.GetPreference(userID, modelPreference)
+	if _, ok := autoTiers[model]; ok {
+		return b.routeModel(userID, model).Model
+	}
+	if !slices.Contains(models, model) || !b.modelAllowed(userID, model) {
+		return b.defaultModelFor(userID)
+	}
+	return model
+}
+
+func (b *Bot) userPersona(userID int64) persona {
+	name, _ := b.db.GetPreference(userID, personaPreference)
+	for _, p := range personas {
+		if p.Name == name {
+			return p
+		}
+	}
+	return personas[0]
+}
+
+func (b *Bot) startHandler(c tele.Context) error {
+	done, err := b.db.GetPreference(c.Sender().ID, onboardedPreference)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(requestContext(c), "Could not load onboarding state", "err", err)
+	}
+	if done != "" {
+		menu := &tele.ReplyMarkup{}
+		menu.Inline(menu.Row(menu.Data(b.t(c, "⚙️ Change model or persona"), btnOnboardSetup.Unique)))
+		return c.Send(b.t(c, "Welcome back, %s. Just send me a message, or see /whoami for your settings", c.Sender().FirstName), menu)
+	}
+
+	if b.checkAuth(c) == nil {
+		return b.sendModelStep(c, false)
+	}
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(
+		menu.Data(b.t(c, "🔑 I have a token"), btnOnboardAuth.Unique),
+		menu.Data(b.t(c, "Later"), btnOnboardSkip.Unique),
+	))
+	return c.Send(b.t(c, welcomeText, c.Sender().FirstName), menu)
+}
+
+func (b *Bot) onboardAuthHandler(c tele.Context) error {
+	b.pendingAuth.Store(c.Sender().ID, true)
+	c.Respond()
+	return c.Send(b.t(c, "Send me your token as the next message"))
+}
+
+func (b *Bot) onboardSkipHandler(c tele.Context) error {
+	c.Respond()
+	if err := b.db.SetPreference(c.Sender().ID, onboardedPreference, "skipped"); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save onboarding state", "err", err)
+	}
+	return c.Edit(b.t(c, "No problem, use /auth yourtoken whenever you're ready"))
+}
+
+// withPendingAuth takes the token of a user who is authenticating from the
+// onboarding flow before anything else sees their message.
+func (b *Bot) withPendingAuth(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if _, pending := b.pendingAuth.LoadAndDelete(c.Sender().ID); !pending {
+			return next(c)
+		}
+		// Don't leave the token lying around in the chat.
+		c.Delete()
+		if _, err := b.authenticate(c, strings.TrimSpace(c.Text())); err != nil {
+			return c.Send(err.Error() + b.t(c, ", try /auth yourtoken"))
+		}
+		return b.sendModelStep(c, false)
+	}
+}
+
+func (b *Bot) onboardSetupHandler(c tele.Context) error {
+	c.Respond()
+	return b.sendModelStep(c, true)
+}
+
+func (b *Bot) sendModelStep(c tele.Context, edit bool) error {
+	current := b.pickedModel(c.Sender().ID)
+	menu := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, model := range append([]string{"auto", "auto-smart"}, models...) {
+		label := model
+		if model == current {
+			label = "✅ " + model
+		} else if !b.modelAllowed(c.Sender().ID, model) {
+			label = "🔒 " + model
+		}
+		rows = append(rows, menu.Row(menu.Data(label, btnOnboardModel.Unique, model)))
+	}
+	menu.Inline(rows...)
+
+	text := b.t(c, "You're in. Which model should answer you by default? Smaller ones are faster, bigger ones smarter. auto picks the fastest one that's working well for each message, auto-smart the fastest of the bigger ones")
+	if edit {
+		return c.Edit(text, menu)
+	}
+	return c.Send(text, menu)
+}
+
+func (b *Bot) onboardModelHandler(c tele.Context) error {
+	model := c.Callback().Data
+	if !validModel(model) {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Unknown model")})
+	}
+	if !b.modelAllowed(c.Sender().ID, model) {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That model comes with a paid plan, see /plans"), ShowAlert: true})
+	}
+	if err := b.db.SetPreference(c.Sender().ID, modelPreference, model); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not save your model")})
+	}
+	c.Respond()
+
+	current := b.userPersona(c.Sender().ID).Name
+	menu := &tele.ReplyMarkup{}
+	var row tele.Row
+	for _, p := range personas {
+		label := b.t(c, p.Label)
+		if p.Name == current {
+			label = "✅ " + label
+		}
+		row = append(row, menu.Data(label, btnOnboardPersona.Unique, p.Name))
+	}
+	menu.Inline(menu.Split(2, row)...)
+	return c.Edit(b.t(c, "Using %s. And how should I talk to you?", model), menu)
+}
+
+func (b *Bot) onboardPersonaHandler(c tele.Context) error {
+	name := c.Callback().Data
+	if !slices.ContainsFunc(personas, func(p persona) bool { return p.Name == name }) {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Unknown persona")})
+	}
+	if err := b.db.SetPreference(c.Sender().ID, personaPreference, name); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not save your persona")})
+	}
+	if err := b.db.SetPreference(c.Sender().ID, onboardedPreference, "done"); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save onboarding state", "err", err)
+	}
+	c.Respond()
+	return c.Edit(b.t(c, "All set: %s, %s. Send me anything to get started, /start again to change these", b.pickedModel(c.Sender().ID), b.t(c, b.userPersona(c.Sender().ID).Label)))
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const pinsShown = 10
+
+var (
+	btnShowPin = tele.Btn{Unique: "show_pin"}
+	btnUnpin   = tele.Btn{Unique: "unpin"}
+)
+
+func (b *Bot) pinHandler(c tele.Context) error {
+	reply := replyTo(c.Message())
+	if reply == nil || reply.Sender == nil || reply.Sender.ID != c.Bot().Me.ID {
+		return c.Send(b.t(c, "Reply to one of my answers with /pin to keep it"))
+	}
+	ex, err := b.db.ExchangeByMessage(c.Sender().ID, reply.ID)
+	if err == sql.ErrNoRows {
+		return c.Send(b.t(c, "I can't find that answer anymore"))
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load that answer: ") + err.Error())
+	}
+
+	pinned, err := b.db.SavePin(ex)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not pin that answer: ") + err.Error())
+	}
+	if !pinned {
+		return c.Send(b.t(c, "That answer is already pinned, see /pins"))
+	}
+	return c.Send(b.t(c, "Pinned. See /pins, or /pins <words> to search them"))
+}
+
+func (b *Bot) pinsHandler(c tele.Context) error {
+	text, menu, err := b.pinsList(c, strings.TrimSpace(c.Message().Payload))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your pins: ") + err.Error())
+	}
+	return c.Send(text, menu)
+}
+
+func (b *Bot) showPinHandler(c tele.Context) error {
+	pins, err := b.db.GetPins(c.Sender().ID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not load that pin")})
+	}
+	for _, p := range pins {
+		if p.ID == c.Callback().Data {
+			c.Respond()
+			return c.Send("📌 " + p.Prompt + "\n\n" + p.Response)
+		}
+	}
+	return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That pin is gone")})
+}
+
+func (b *Bot) unpinHandler(c tele.Context) error {
+	if _, err := b.db.DeletePin(c.Sender().ID, c.Callback().Data); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not unpin that")})
+	}
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Unpinned")})
+
+	text, menu, err := b.pinsList(c, "")
+	if err != nil {
+		return err
+	}
+	return c.Edit(text, menu)
+}
+
+// pinsList lists the user's latest pins, or the ones matching query best.
+func (b *Bot) pinsList(c tele.Context, query string) (string, *tele.ReplyMarkup, error) {
+	pins, err := b.db.GetPins(c.Sender().ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	menu := &tele.ReplyMarkup{}
+	if len(pins) == 0 {
+		return b.t(c, "You have no pins yet, reply to one of my answers with /pin to keep it"), menu, nil
+	}
+	if query != "" {
+		pins = searchPins(pins, query)
+		if len(pins) == 0 {
+			return b.t(c, "None of your pins match %q", query), menu, nil
+		}
+	}
+	if len(pins) > pinsShown {
+		pins = pins[:pinsShown]
+	}
+
+	var sb strings.Builder
+	var rows []tele.Row
+	for i, p := range pins {
+		sb.WriteString(fmt.Sprintf("%d. %s\n   %s\n", i+1, truncate(p.Prompt, 50), truncate(p.Response, 80)))
+		rows = append(rows, menu.Row(
+			menu.Data(b.t(c, "📌 Show %d", i+1), btnShowPin.Unique, p.ID),
+			menu.Data(b.t(c, "❌ Unpin %d", i+1), btnUnpin.Unique, p.ID),
+		))
+	}
+	menu.Inline(rows...)
+	return sb.String(), menu, nil
+}
+
+// searchPins ranks pins by how well they match query, dropping the ones
+// that don't match at all.
+func searchPins(pins []store.Pin, query string) []store.Pin {
+	type scored struct {
+		pin   store.Pin
+		score float64
+	}
+	var matches []scored
+	for _, p := range pins {
+		if score := fuzzyScore(query, p.Prompt+"\n"+p.Response); score > 0 {
+			matches = append(matches, scored{p, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	ranked := make([]store.Pin, 0, len(matches))
+	for _, m := range matches {
+		ranked = append(ranked, m.pin)
+	}
+	return ranked
+}
+
+// fuzzyScore adds up how well each word of query matches text: fully when
+// text contains it, partly when a word of text is a typo away from it.
+func fuzzyScore(query, text string) float64 {
+	text = strings.ToLower(text)
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+
+	score := 0.0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if strings.Contains(text, term) {
+			score++
+			continue
+		}
+		// One typo per four letters.
+		allowed := len([]rune(term)) / 4
+		best := 0.0
+		for _, w := range words {
+			if d := levenshtein(term, w); d <= allowed {
+				best = max(best, 1-float64(d)/float64(len([]rune(term))))
+			}
+		}
+		score += best
+	}
+	return score
+}
+
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// starsCurrency is Telegram Stars, which need no payment provider.
+	starsCurrency = "XTR"
+	planPayload   = "plan:"
+	freePlan      = "free"
+)
+
+var btnBuyPlan = tele.Btn{Unique: "buy_plan"}
+
+func (b *Bot) findPlan(name string) (config.Plan, bool) {
+	for _, p := range b.cfg().Plans {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return config.Plan{}, false
+}
+
+// onPlan reports whether the user's quotas and models come from a plan,
+// which they do when plans are sold and the user isn't in a workspace.
+func (b *Bot) onPlan(userID int64) bool {
+	return len(b.cfg().Plans) > 0 && b.workspace(userID).Name == ""
+}
+
+// plan is the paid plan the user bought and when it runs out, or the free
+// plan and a zero time once it has. A plan since dropped from PLANS keeps
+// its buyers free of limits until it runs out.
+func (b *Bot) plan(userID int64) (config.Plan, time.Time) {
+	cfg := b.cfg()
+	free := config.Plan{Name: freePlan, MessageQuota: cfg.FreeMessageQuota, ImageQuota: cfg.ImageQuota, Models: cfg.FreeModels}
+	sub, err := b.db.GetSubscription(userID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Could not load subscription", "user", userID, "err", err)
+		}
+		return free, time.Time{}
+	}
+	if !time.Now().Before(sub.ExpiresAt) {
+		return free, time.Time{}
+	}
+	if p, ok := b.findPlan(sub.Plan); ok {
+		return p, sub.ExpiresAt
+	}
+	return config.Plan{Name: sub.Plan}, sub.ExpiresAt
+}
+
+// imageQuota is how many images the user may generate a day, zero for no
+// limit.
+func (b *Bot) imageQuota(userID int64) int {
+	if b.onPlan(userID) {
+		p, _ := b.plan(userID)
+		return p.ImageQuota
+	}
+	return b.workspace(userID).ImageQuota
+}
+
+// planModels are the models the user's plan may use, nil for every model.
+func (b *Bot) planModels(userID int64) []string {
+	if !b.onPlan(userID) {
+		return nil
+	}
+	p, _ := b.plan(userID)
+	return p.Models
+}
+
+func (b *Bot) modelAllowed(userID int64, model string) bool {
+	// Auto routing only picks from the allowed models.
+	if _, auto := autoTiers[model]; auto {
+		return true
+	}
+	allowed := b.planModels(userID)
+	return len(allowed) == 0 || slices.Contains(allowed, model)
+}
+
+// quotaLeft is how many of their plan's messages the sender has left today,
+// and the quota itself, zero when they have none. Every completion they ask
+// for counts, whichever command asked for it.
+func (b *Bot) quotaLeft(c tele.Context) (left, quota int, err error) {
+	return b.userQuotaLeft(c.Sender())
+}
+
+// userQuotaLeft is quotaLeft for requests that don't come from a chat,
+// like the gateway's.
+func (b *Bot) userQuotaLeft(user *tele.User) (left, quota int, err error) {
+	userID := user.ID
+	if listed(b.cfg().Admins, user) || !b.onPlan(userID) {
+		return 0, 0, nil
+	}
+	p, _ := b.plan(userID)
+	if p.MessageQuota <= 0 {
+		return 0, 0, nil
+	}
+	n, err := b.db.CountRequests(userID, startOfDay(time.Now()))
+	if err != nil {
+		return 0, p.MessageQuota, err
+	}
+	return max(p.MessageQuota-n, 0), p.MessageQuota, nil
+}
+
+// withQuota stops the sender's requests once they've sent their plan's
+// messages for the day. Admins have no quota.
+func (b *Bot) withQuota(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		left, quota, err := b.quotaLeft(c)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not check your message quota: ") + err.Error())
+		}
+		if quota > 0 && left == 0 {
+			return c.Send(b.t(c, "You've used your %d messages for today, try again tomorrow or get more with /plans", quota))
+		}
+		return next(c)
+	}
+}
+
+// fitsQuota tells the sender off when a command asking for n completions at
+// once, like /batch, would go over what's left of their quota.
+func (b *Bot) fitsQuota(c tele.Context, n int) bool {
+	left, quota, err := b.quotaLeft(c)
+	if err != nil {
+		c.Send(b.t(c, "ERROR: Could not check your message quota: ") + err.Error())
+		return false
+	}
+	if quota > 0 && n > left {
+		c.Send(b.t(c, "That takes %d messages and you have %d left today, ask fewer or get more with /plans", n, left))
+		return false
+	}
+	return true
+}
+
+// describePlan lists what the plan comes with.
+func (b *Bot) describePlan(c tele.Context, p config.Plan) string {
+	parts := []string{b.t(c, "unlimited messages")}
+	if p.MessageQuota > 0 {
+		parts[0] = b.t(c, "%d messages a day", p.MessageQuota)
+	}
+	if b.images != nil {
+		if p.ImageQuota > 0 {
+			parts = append(parts, b.t(c, "%d images a day", p.ImageQuota))
+		} else {
+			parts = append(parts, b.t(c, "unlimited images"))
+		}
+	}
+	if len(p.Models) > 0 {
+		parts = append(parts, b.t(c, "models: %s", strings.Join(p.Models, ", ")))
+	} else {
+		parts = append(parts, b.t(c, "every model"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (b *Bot) plansHandler(c tele.Context) error {
+	if len(b.cfg().Plans) == 0 {
+		return c.Send(b.t(c, "There are no plans to buy on this bot"))
+	}
+	if ws := b.workspace(c.Sender().ID); ws.Name != "" {
+		return c.Send(b.t(c, "You're in the %s workspace, which doesn't use plans", ws.Name))
+	}
+
+	var sb strings.Builder
+	current, expires := b.plan(c.Sender().ID)
+	if expires.IsZero() {
+		sb.WriteString(b.t(c, "You're on the free plan: %s", b.describePlan(c, current)))
+	} else {
+		sb.WriteString(b.t(c, "You're on %s until %s: %s", current.Name, expires.Format("Jan 2 2006"), b.describePlan(c, current)))
+	}
+	sb.WriteString("\n\n" + b.t(c, "Plans, paid with Telegram Stars:"))
+
+	menu := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, p := range b.cfg().Plans {
+		if p.Stars <= 0 {
+			continue
+		}
+		sb.WriteString("\n⭐ " + b.t(c, "%s, %d Stars for %d days: %s", p.Name, p.Stars, p.Days, b.describePlan(c, p)))
+		rows = append(rows, menu.Row(menu.Data(b.t(c, "Buy %s for %d ⭐", p.Name, p.Stars), btnBuyPlan.Unique, p.Name)))
+	}
+	if !expires.IsZero() {
+		sb.WriteString("\n\n" + b.t(c, "Buying a plan adds its days to the ones you have left."))
+	}
+	menu.Inline(rows...)
+	return c.Send(sb.String(), menu)
+}
+
+// buyPlanHandler sends the invoice of the plan tapped in /plans.
+func (b *Bot) buyPlanHandler(c tele.Context) error {
+	p, ok := b.findPlan(c.Callback().Data)
+	if !ok || p.Stars <= 0 {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "That plan isn't sold anymore")})
+	}
+	c.Respond()
+	invoice := &tele.Invoice{
+		Title:       truncate(b.t(c, "%s plan", p.Name), 32),
+		Description: truncate(b.t(c, "%d days of %s", p.Days, b.describePlan(c, p)), 255),
+		Payload:     planPayload + p.Name,
+		Currency:    starsCurrency,
+		Prices:      []tele.Price{{Label: p.Name, Amount: p.Stars}},
+	}
+	if _, err := send(c, invoice); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not send invoice", "plan", p.Name, "err", err)
+		return c.Send(b.t(c, "ERROR: Could not create your invoice: ") + err.Error())
+	}
+	return nil
+}
+
+// checkoutHandler confirms the invoice still matches a plan on sale before
+// Telegram takes the Stars.
+func (b *Bot) checkoutHandler(c tele.Context) error {
+	q := c.PreCheckoutQuery()
+	name, ok := strings.CutPrefix(q.Payload, planPayload)
+	p, found := b.findPlan(name)
+	if !ok || !found || q.Currency != starsCurrency || q.Total != p.Stars {
+		return c.Accept(b.t(c, "That plan changed since the invoice was sent, open /plans again"))
+	}
+	return c.Accept()
+}
+
+// paymentHandler starts the plan the user paid for, or extends it by the
+// plan's days from when the current one runs out.
+func (b *Bot) paymentHandler(c tele.Context) error {
+	pay := c.Message().Payment
+	userID := c.Sender().ID
+	name, _ := strings.CutPrefix(pay.Payload, planPayload)
+	p, ok := b.findPlan(name)
+	if !ok {
+		slog.ErrorContext(requestContext(c), "Payment for an unknown plan", "plan", name, "charge_id", pay.TelegramChargeID)
+		b.refund(userID, pay.TelegramChargeID)
+		return c.Send(b.t(c, "That plan isn't sold anymore, your Stars were refunded"))
+	}
+
+	from := time.Now()
+	if sub, err := b.db.GetSubscription(userID); err == nil && sub.ExpiresAt.After(from) {
+		from = sub.ExpiresAt
+	}
+	sub := store.Subscription{UserID: userID, Plan: p.Name, ExpiresAt: from.AddDate(0, 0, p.Days)}
+	payment := store.Payment{ChargeID: pay.TelegramChargeID, UserID: userID, Plan: p.Name, Stars: pay.Total}
+	if err := b.db.SavePayment(payment, sub); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save payment", "charge_id", pay.TelegramChargeID, "err", err)
+		b.refund(userID, pay.TelegramChargeID)
+		return c.Send(b.t(c, "ERROR: Could not start your plan, your Stars were refunded: ") + err.Error())
+	}
+	paymentsTotal.WithLabelValues(p.Name).Inc()
+	slog.InfoContext(requestContext(c), "Plan bought", "user", userID, "plan", p.Name, "stars", pay.Total, "expires_at", sub.ExpiresAt)
+	return c.Send(b.t(c, "⭐ Thank you! You're on %s until %s", p.Name, sub.ExpiresAt.Format("Jan 2 2006")))
+}
+
+// refund gives back the Stars of a payment that didn't get the user their
+// plan, telling the admins when even that fails.
+func (b *Bot) refund(userID int64, chargeID string) {
+	_, err := b.tele.Raw("refundStarPayment", map[string]string{
+		"user_id":                    strconv.FormatInt(userID, 10),
+		"telegram_payment_charge_id": chargeID,
+	})
+	if err == nil {
+		return
+	}
+	slog.Error("Could not refund payment", "user", userID, "charge_id", chargeID, "err", err)
+	if chatID := b.cfg().AlertChatID; chatID != 0 {
+		b.tele.Send(&tele.Chat{ID: chatID}, fmt.Sprintf("Could not refund payment %s of user %d: %v", chargeID, userID, err))
+	}
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// progressInterval is how often the progress message is updated, slow
+// enough to stay clear of Telegram's edit rate limit.
+const progressInterval = 3 * time.Second
+
+// progressKey is set on the context by handlers that show the progress of
+// their completion themselves.
+const progressKey = "progress"
+
+// whileSlow lets the user know their request is still being worked on once
+// it takes longer than PROGRESS_DELAY, updating the elapsed time until stop
+// is called. It edits msg, keeping markup, or sends a message of its own and
+// deletes it when msg is nil. stop returns once the last update is done and
+// can be called more than once.
+func (b *Bot) whileSlow(tc tele.Context, msg *tele.Message, markup *tele.ReplyMarkup) (stop func()) {
+	delay := b.cfg().ProgressDelay
+	if delay <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		start := time.Now()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		own := msg == nil
+		for tick := 0; ; tick++ {
+			select {
+			case <-done:
+				if own && msg != nil {
+					tc.Bot().Delete(msg)
+				}
+				return
+			case <-timer.C:
+			}
+
+			text := b.t(tc, "%s Still thinking… %s", []string{"⏳", "⌛"}[tick%2], time.Since(start).Round(time.Second))
+			if msg == nil {
+				msg, _ = send(tc, text)
+			} else if markup != nil {
+				tc.Bot().Edit(msg, text, markup)
+			} else {
+				tc.Bot().Edit(msg, text)
+			}
+			timer.Reset(progressInterval)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
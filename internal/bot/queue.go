@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"errors"
+	"sync"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var errBusy = errors.New("user already has a request in flight")
+
+// WorkerPool caps how many requests hit Groq at once and lets each user
+// have only one request in flight.
+type WorkerPool struct {
+	slots chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[int64]bool
+}
+
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		slots:    make(chan struct{}, size),
+		inFlight: map[int64]bool{},
+	}
+}
+
+// Do runs fn once wait returns and a worker slot is free. It returns errBusy
+// straight away if the user is still waiting on an earlier request.
+func (p *WorkerPool) Do(userID int64, wait func() error, fn func() error) error {
+	p.mu.Lock()
+	if p.inFlight[userID] {
+		p.mu.Unlock()
+		return errBusy
+	}
+	p.inFlight[userID] = true
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.inFlight, userID)
+		p.mu.Unlock()
+	}()
+
+	if err := wait(); err != nil {
+		return err
+	}
+	return p.Run(fn)
+}
+
+// Run runs fn once a worker slot is free.
+func (p *WorkerPool) Run(fn func() error) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+
+	return fn()
+}
+
+func (b *Bot) withQueue(handler tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !b.allowRequest(c) {
+			return nil
+		}
+		defer b.finishJob(b.saveJob(c))
+		err := b.pool.Do(c.Sender().ID, func() error {
+			return b.waitForGroq(c)
+		}, func() error {
+			return handler(c)
+		})
+		if errors.Is(err, errBusy) {
+			return c.Send(b.t(c, "Still thinking about your last message, hang on"))
+		}
+		if errors.Is(err, errQueueFull) {
+			return c.Send(b.t(c, "Groq is rate limited and the queue is full, try again in a minute"))
+		}
+		return err
+	}
+}
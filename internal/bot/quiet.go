@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const heldTick = time.Minute
+
+const quietUsage = `Usage: /quiet 23:00-07:00 [timezone], e.g. /quiet 10pm-8am Europe/Berlin
+Digests, reminders and announcements due in that window are held until it ends. /quiet off turns it off`
+
+// parseQuiet reads /quiet's start-end window and optional IANA timezone,
+// falling back to DIGEST_TIMEZONE.
+func (b *Bot) parseQuiet(args []string) (store.QuietHours, error) {
+	var q store.QuietHours
+	from, to, ok := strings.Cut(args[0], "-")
+	if !ok {
+		return q, fmt.Errorf("can't read %q, give the start and end like 23:00-07:00", args[0])
+	}
+	hour, minute, err := parseClock(from)
+	if err != nil {
+		return q, err
+	}
+	q.Start = hour*60 + minute
+	if hour, minute, err = parseClock(to); err != nil {
+		return q, err
+	}
+	q.End = hour*60 + minute
+	if q.Start == q.End {
+		return q, fmt.Errorf("quiet hours can't start and end at the same time")
+	}
+
+	name := b.cfg().DigestTimezone
+	if len(args) > 1 {
+		name = args[1]
+	}
+	zone := time.Local
+	if name != "" {
+		if zone, err = time.LoadLocation(name); err != nil {
+			return q, fmt.Errorf("unknown timezone %q, try one like Europe/Berlin or America/New_York", name)
+		}
+	}
+	q.Timezone = zone.String()
+	return q, nil
+}
+
+func (b *Bot) quietHandler(c tele.Context) error {
+	args := c.Args()
+	if len(args) == 0 {
+		q, err := b.db.GetQuietHours(c.Sender().ID)
+		if err == sql.ErrNoRows {
+			return c.Send(b.t(c, "You don't have quiet hours") + "\n\n" + b.t(c, quietUsage))
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load your quiet hours: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Your quiet hours are %s-%s %s", formatClock(q.Start), formatClock(q.End), q.Timezone) + "\n\n" + b.t(c, quietUsage))
+	}
+	if strings.ToLower(args[0]) == "off" {
+		if _, err := b.db.DeleteQuietHours(c.Sender().ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Quiet hours off, messages held for you are sent in the next minute"))
+	}
+	if len(args) > 2 {
+		return c.Send(b.t(c, quietUsage))
+	}
+
+	q, err := b.parseQuiet(args)
+	if err != nil {
+		return c.Send(err.Error() + "\n\n" + b.t(c, quietUsage))
+	}
+	q.UserID = c.Sender().ID
+	if err := b.db.SetQuietHours(q); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Quiet hours set to %s-%s %s, I'll hold digests, reminders and announcements until they end", formatClock(q.Start), formatClock(q.End), q.Timezone))
+}
+
+// formatClock formats minutes past midnight as 15:04.
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// quiet reports whether it's the user's quiet hours at now. Users without
+// quiet hours, or whose can't be read, never are.
+func (b *Bot) quiet(userID int64, now time.Time) bool {
+	q, err := b.db.GetQuietHours(userID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Could not load quiet hours", "user_id", userID, "err", err)
+		}
+		return false
+	}
+	zone, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		zone = time.Local
+	}
+	local := now.In(zone)
+	minute := local.Hour()*60 + local.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+// deliver sends a scheduled message of kind to the user's chat, or holds it
+// when it's their quiet hours. It reports whether the message was held.
+func (b *Bot) deliver(userID, chatID int64, kind, text string) (held bool, err error) {
+	if b.quiet(userID, time.Now()) {
+		return true, b.db.HoldMessage(store.HeldMessage{UserID: userID, ChatID: chatID, Kind: kind, Text: text})
+	}
+	_, err = b.tele.Send(&tele.Chat{ID: chatID}, text)
+	return false, err
+}
+
+// runHeld sends the held messages of users whose quiet hours are over
+// until the process exits.
+func (b *Bot) runHeld() {
+	for range time.Tick(heldTick) {
+		b.sendHeld()
+	}
+}
+
+func (b *Bot) sendHeld() {
+	messages, err := b.db.HeldMessages()
+	if err != nil {
+		slog.Error("Could not load held messages", "err", err)
+		return
+	}
+	now := time.Now()
+	stillQuiet := map[int64]bool{}
+	for _, m := range messages {
+		quiet, ok := stillQuiet[m.UserID]
+		if !ok {
+			quiet = b.quiet(m.UserID, now)
+			stillQuiet[m.UserID] = quiet
+		}
+		if quiet {
+			continue
+		}
+		if _, err := b.tele.Send(&tele.Chat{ID: m.ChatID}, m.Text); err != nil {
+			slog.Error("Could not deliver held message", "user_id", m.UserID, "kind", m.Kind, "err", err)
+		}
+		if err := b.db.DeleteHeldMessage(m.ID); err != nil {
+			slog.Error("Could not delete held message", "id", m.ID, "err", err)
+		}
+	}
+}
+
+// broadcastHandler sends an announcement to every user, holding it for
+// those in their quiet hours.
+func (b *Bot) broadcastHandler(c tele.Context) error {
+	text := strings.TrimSpace(commandText(c.Message()))
+	if text == "" {
+		return c.Send(b.t(c, "Usage: /broadcast <announcement>, or in reply to the message to send"))
+	}
+	users, err := b.db.GetUsers()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load users: ") + err.Error())
+	}
+	sent, held, failed := 0, 0, 0
+	for _, u := range users {
+		if u.UserID == 0 {
+			continue
+		}
+		wasHeld, err := b.deliver(u.UserID, u.UserID, "broadcast", text)
+		switch {
+		case err != nil:
+			failed++
+			slog.Error("Could not deliver broadcast", "user_id", u.UserID, "err", err)
+		case wasHeld:
+			held++
+		default:
+			sent++
+		}
+	}
+	return c.Send(b.t(c, "Sent to %d users, held for %d in their quiet hours, %d failed", sent, held, failed))
+}
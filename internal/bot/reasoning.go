@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	showReasoningPreference = "show_reasoning"
+	effortPreference        = "reasoning_effort"
+	// maxReasoningShown keeps the reasoning and the answer inside one
+	// Telegram message.
+	maxReasoningShown = 1500
+)
+
+var efforts = []string{llm.EffortLow, llm.EffortMedium, llm.EffortHigh}
+
+func (b *Bot) reasoningHandler(c tele.Context) error {
+	userID := c.Sender().ID
+	arg := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+
+	var err error
+	switch {
+	case arg == "":
+		shown := b.t(c, "hidden")
+		if b.showsReasoning(userID) {
+			shown = b.t(c, "shown")
+		}
+		return c.Send(b.t(c, "Reasoning models think before they answer. Their thinking is %s, effort %s.\n\n/reasoning show or /reasoning hide to see it behind a spoiler or not, /reasoning low, medium or high to set how hard they think.", shown, b.t(c, b.reasoningEffort(userID))))
+	case arg == "show" || arg == "hide":
+		err = b.db.SetPreference(userID, showReasoningPreference, arg)
+	case slices.Contains(efforts, arg):
+		err = b.db.SetPreference(userID, effortPreference, arg)
+	default:
+		return c.Send(b.t(c, "Usage: /reasoning [show|hide|low|medium|high]"))
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+
+	note := ""
+	if !llm.Reasons(b.userModel(userID)) {
+		note = "\n" + b.t(c, "Your model doesn't reason, pick one that does with /start")
+	}
+	switch arg {
+	case "show":
+		return c.Send(b.t(c, "I'll show the reasoning behind a spoiler") + note)
+	case "hide":
+		return c.Send(b.t(c, "I'll keep the reasoning to myself") + note)
+	}
+	return c.Send(b.t(c, "Reasoning effort set to %s", b.t(c, arg)) + note)
+}
+
+func (b *Bot) showsReasoning(userID int64) bool {
+	value, _ := b.db.GetPreference(userID, showReasoningPreference)
+	return value == "show"
+}
+
+func (b *Bot) reasoningEffort(userID int64) string {
+	value, _ := b.db.GetPreference(userID, effortPreference)
+	if !slices.Contains(efforts, value) {
+		return llm.EffortMedium
+	}
+	return value
+}
+
+// modelOptions pick the user's model, their sampling settings and, for
+// reasoning models, their effort.
+func (b *Bot) modelOptions(userID int64) []llm.Option {
+	opts := []llm.Option{llm.WithModel(b.userModel(userID)), llm.WithReasoning(b.reasoningEffort(userID))}
+	return append(opts, b.samplingOptions(userID)...)
+}
+
+// withReasoning puts the model's reasoning above answer behind a spoiler
+// when the user wants to see it, returning the text and its entities.
+func (b *Bot) withReasoning(c tele.Context, res llm.Completion, answer string) (string, tele.Entities) {
+	if res.Reasoning == "" || !b.showsReasoning(c.Sender().ID) {
+		return answer, nil
+	}
+	prefix := "🤔 "
+	reasoning := truncate(strings.TrimSpace(res.Reasoning), maxReasoningShown)
+	spoiler := tele.MessageEntity{
+		Type:   tele.EntitySpoiler,
+		Offset: utf16Len(prefix),
+		Length: utf16Len(reasoning),
+	}
+	return prefix + reasoning + "\n\n" + answer, tele.Entities{spoiler}
+}
+
+// utf16Len is the length of s in the UTF-16 code units Telegram measures
+// entities in.
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
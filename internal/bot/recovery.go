@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// withRecovery turns a panicking handler into an apology to the user and a
+// report with the stack, so one bad update doesn't take the bot down.
+func (b *Bot) withRecovery(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			errorsTotal.WithLabelValues("panic").Inc()
+			b.reportPanic(c, r, string(debug.Stack()))
+			if c.Callback() != nil {
+				c.Respond(&tele.CallbackResponse{Text: b.t(c, "Sorry, something went wrong")})
+			}
+			err = c.Send(b.t(c, "Sorry, something went wrong on my end. It's been reported, please try again"))
+		}()
+		return next(c)
+	}
+}
+
+func (b *Bot) reportPanic(c tele.Context, r any, stack string) {
+	endpoint := endpointName(c)
+	var username string
+	if sender := c.Sender(); sender != nil {
+		username = sender.Username
+	}
+	slog.ErrorContext(requestContext(c), "Handler panicked", "handler", endpoint, "username", username, "panic", fmt.Sprint(r), "stack", stack)
+
+	if b.cfg().AlertChatID != 0 {
+		text := fmt.Sprintf("💥 %s panicked for @%s: %v\n\n%s", endpoint, username, r, stack)
+		if _, err := b.tele.Send(&tele.Chat{ID: b.cfg().AlertChatID}, truncate(text, 4000)); err != nil {
+			slog.ErrorContext(requestContext(c), "Could not send panic alert", "err", err)
+		}
+	}
+	if b.sentry != nil {
+		tags := map[string]string{"handler": endpoint, "username": username}
+		if err := b.sentry.report(fmt.Sprint(r), stack, tags); err != nil {
+			slog.ErrorContext(requestContext(c), "Could not report panic to sentry", "err", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Redaction levels: standard scrubs secrets, API keys and card numbers,
+// strict also emails, phone numbers and profanity.
+const (
+	redactOff      = "off"
+	redactStandard = "standard"
+	redactStrict   = "strict"
+)
+
+var (
+	apiKeyRe = regexp.MustCompile(`\b(?:gsk_[A-Za-z0-9]{20,}|sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{30,}|xox[abpr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})\b|(?i:\bbearer\s+)[A-Za-z0-9._~+/-]{20,}=*`)
+	cardRe   = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	emailRe  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phoneRe  = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\b\d{2,4})[ .-]?\d{3,4}[ .-]?\d{3,4}\b`)
+	// profanityRe matches the commonest English swear words and the words
+	// built on them.
+	profanityRe = regexp.MustCompile(`(?i)\b(?:fuck|shit|bitch|cunt|asshole|bastard|dick|pussy|wank|twat|motherfuck)[a-z]*\b`)
+)
+
+// validRedaction reports whether level is one REDACTION takes.
+func validRedaction(level string) error {
+	switch level {
+	case redactOff, redactStandard, redactStrict:
+		return nil
+	}
+	return fmt.Errorf("unknown level %q, expected off, standard or strict", level)
+}
+
+// redact scrubs what level covers out of s.
+func redact(level, s string) string {
+	if level == redactOff || s == "" {
+		return s
+	}
+	s = apiKeyRe.ReplaceAllString(s, "[api key]")
+	s = cardRe.ReplaceAllStringFunc(s, func(m string) string {
+		if luhn(m) {
+			return "[card number]"
+		}
+		return m
+	})
+	if level != redactStrict {
+		return s
+	}
+	s = emailRe.ReplaceAllString(s, "[email]")
+	s = phoneRe.ReplaceAllString(s, "[phone]")
+	return profanityRe.ReplaceAllStringFunc(s, func(m string) string {
+		return m[:1] + strings.Repeat("*", len(m)-1)
+	})
+}
+
+// luhn reports whether the digits of s pass the Luhn check card numbers
+// carry, so order numbers and the like are left alone.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// redactExchange scrubs the exchange's prompt and response before it's
+// stored, the model having already seen them as written.
+func (b *Bot) redactExchange(ex *store.Exchange) {
+	level := b.cfg().Redaction
+	ex.Prompt = redact(level, ex.Prompt)
+	ex.Response = redact(level, ex.Response)
+}
+
+// redactMessage copies m with its text and caption, and the replied-to
+// message's, scrubbed.
+func redactMessage(level string, m *tele.Message) *tele.Message {
+	if m == nil || level == redactOff {
+		return m
+	}
+	scrubbed := *m
+	scrubbed.Text = redact(level, m.Text)
+	scrubbed.Caption = redact(level, m.Caption)
+	scrubbed.ReplyTo = redactMessage(level, m.ReplyTo)
+	return &scrubbed
+}
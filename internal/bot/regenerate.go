@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const regenerateTemperature = 0.9
+
+var (
+	btnRegenerate = tele.Btn{Unique: "regenerate"}
+	btnEditPrompt = tele.Btn{Unique: "edit_prompt"}
+)
+
+// answerMenu is the keyboard under a finished answer.
+func (b *Bot) answerMenu(c tele.Context) *tele.ReplyMarkup {
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(
+		menu.Data(b.t(c, "🔄 Regenerate"), btnRegenerate.Unique),
+		menu.Data(b.t(c, "✏️ Edit prompt"), btnEditPrompt.Unique),
+	), menu.Row(
+		menu.Data("👍", btnRate.Unique, "1"),
+		menu.Data("👎", btnRate.Unique, "-1"),
+	))
+	return menu
+}
+
+// regenerateHandler re-runs the prompt of the answer the button is under
+// with a higher temperature and replaces the stored answer with the new one.
+func (b *Bot) regenerateHandler(c tele.Context) error {
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Regenerating…")})
+
+	ex, err := b.answeredIn(c, c.Message().ID)
+	if err == sql.ErrNoRows {
+		return c.Send(b.t(c, "Nothing to regenerate yet"))
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your conversation: ") + err.Error())
+	}
+	var history []store.Exchange
+	if ex.ParentID != "" {
+		history, err = b.db.Thread(ex.ParentID, b.cfg().ContextWindow)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load your conversation: ") + err.Error())
+		}
+	}
+
+	res, err := b.answer(c, ex.Prompt, history, llm.WithTemperature(regenerateTemperature))
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+
+	b.replaceAnswer(ex, res)
+
+	msg, err := b.showAnswer(c, nil, res, res.Content, b.answerMenu(c))
+	if err != nil {
+		return err
+	}
+	if err := b.db.SetExchangeMessage(ex.ID, msg.ID); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save message id", "err", err)
+	}
+	return nil
+}
+
+// answeredIn finds the exchange whose answer was sent as messageID, in a
+// group whoever asked it.
+func (b *Bot) answeredIn(c tele.Context, messageID int) (store.Exchange, error) {
+	if inGroup(c) {
+		return b.db.ExchangeInChats(groupPrefix(c.Chat().ID), messageID)
+	}
+	return b.db.ExchangeByMessage(c.Sender().ID, messageID)
+}
+
+// replaceAnswer stores res as ex's new answer and re-embeds its memory.
+func (b *Bot) replaceAnswer(ex store.Exchange, res llm.Completion) {
+	ex.Response = res.Content
+	ex.Model = res.Model
+	ex.PromptTokens = res.PromptTokens
+	ex.CompletionTokens = res.CompletionTokens
+	// Feedback was on the old answer.
+	ex.Feedback = 0
+	b.redactExchange(&ex)
+	if err := b.db.UpdateExchange(ex); err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		slog.Error("Could not update exchange", "err", err)
+		return
+	}
+	if b.embedder == nil {
+		return
+	}
+	go func() {
+		if err := b.db.DeleteMemories(ex.ID); err != nil {
+			slog.Error("Could not delete memory", "err", err)
+		}
+		if err := b.remember(ex); err != nil {
+			slog.Error("Could not store memory", "err", err)
+		}
+	}()
+}
+
+// editedHandler answers an edited message again, replacing both the stored
+// exchange and the bot's reply to it.
+func (b *Bot) editedHandler(c tele.Context) error {
+	ex, err := b.db.ExchangeByPrompt(c.Sender().ID, c.Message().ID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your conversation: ") + err.Error())
+	}
+	if !b.allowPrompt(c, c.Text()) {
+		return nil
+	}
+
+	var history []store.Exchange
+	if ex.ParentID != "" {
+		history, err = b.db.Thread(ex.ParentID, b.cfg().ContextWindow)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not load your conversation: ") + err.Error())
+		}
+	}
+
+	res, err := b.answer(c, c.Text(), history)
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+
+	ex.Prompt = c.Text()
+	b.replaceAnswer(ex, res)
+
+	reply := &tele.Message{ID: ex.MessageID, Chat: c.Chat()}
+	if _, err := b.showAnswer(c, reply, res, res.Content, b.answerMenu(c)); err == nil {
+		return nil
+	}
+	// The old reply may be gone or too old to edit, answer afresh instead.
+	msg, err := b.showAnswer(c, nil, res, res.Content, b.answerMenu(c))
+	if err != nil {
+		return err
+	}
+	if err := b.db.SetExchangeMessage(ex.ID, msg.ID); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not save message id", "err", err)
+	}
+	return nil
+}
+
+func (b *Bot) editPromptButtonHandler(c tele.Context) error {
+	b.pendingEdits.Store(c.Sender().ID, true)
+	c.Respond()
+	return c.Send(b.t(c, "Send me the edited version of your last prompt"))
+}
+
+// editPromptHandler drops the user's last exchange and answers the edited
+// prompt in its place.
+func (b *Bot) editPromptHandler(c tele.Context, prompt string) error {
+	userID := c.Sender().ID
+
+	last, err := b.db.RecentExchanges(userID, b.activeChat(c), 1)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your conversation: ") + err.Error())
+	}
+	if len(last) == 1 {
+		if err := b.db.DeleteExchange(last[0].ID); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not replace your last prompt: ") + err.Error())
+		}
+		if err := b.db.DeleteMemories(last[0].ID); err != nil {
+			slog.ErrorContext(requestContext(c), "Could not delete memory", "err", err)
+		}
+	}
+
+	return b.chatHandler(c, prompt)
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/logging"
+	tele "gopkg.in/telebot.v3"
+)
+
+// restartSettings are read once at startup, so reloading keeps their old
+// values.
+var restartSettings = map[string]bool{
+	"BotToken": true, "DatabaseURL": true, "SQLiteDriver": true, "Provider": true, "MockTemplate": true, "LogFormat": true,
+	"FallbackModels": true, "OpenAIToken": true, "OpenAIURL": true,
+	"MaxConcurrency": true, "TelegramRate": true, "TelegramChatRate": true, "CacheTTL": true, "CacheSize": true, "SessionTTL": true,
+	"MetricsAddr": true, "HealthAddr": true, "GatewayAddr": true, "ShareAddr": true,
+	"ModelPrices": true, "SentryDSN": true, "AuditLog": true, "BackupInterval": true,
+	"TracingEndpoint": true, "TracingService": true, "TracingHeaders": true,
+	"BackupEndpoint": true, "BackupRegion": true, "BackupBucket": true, "BackupAccessKey": true, "BackupSecretKey": true,
+	"EmbeddingsToken": true, "EmbeddingsURL": true, "EmbeddingsModel": true,
+	"ImagesToken": true, "ImagesURL": true, "ImagesModel": true,
+	"SpeechToken": true, "SpeechURL": true, "SpeechModel": true, "SpeechVoice": true,
+	"SearchProvider": true, "SearchURL": true, "SearchToken": true,
+	"CodeSandbox": true, "SandboxPythonImage": true, "SandboxGoImage": true, "SandboxTimeout": true,
+}
+
+var reloading sync.Mutex
+
+func (b *Bot) cfg() *config.Config {
+	return b.config.Load()
+}
+
+// reload re-reads the config and swaps in the settings that can change
+// while running. It returns the names of the settings that changed and of
+// the ones that need a restart to.
+func (b *Bot) reload() (changed, pending []string, err error) {
+	reloading.Lock()
+	defer reloading.Unlock()
+
+	next, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := logging.SetLevel(next.LogLevel); err != nil {
+		return nil, nil, err
+	}
+	if err := validRedaction(next.Redaction); err != nil {
+		return nil, nil, fmt.Errorf("REDACTION: %v", err)
+	}
+	current := b.cfg()
+	old, updated := reflect.ValueOf(*current), reflect.ValueOf(&next).Elem()
+	for i := 0; i < old.NumField(); i++ {
+		name := old.Type().Field(i).Name
+		if reflect.DeepEqual(old.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+		if restartSettings[name] {
+			pending = append(pending, name)
+			updated.Field(i).Set(old.Field(i))
+			continue
+		}
+		changed = append(changed, name)
+	}
+
+	b.gate.resize(next.RateLimitQueue)
+	b.config.Store(&next)
+	return changed, pending, nil
+}
+
+func (b *Bot) reloadHandler(c tele.Context) error {
+	changed, pending, err := b.reload()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not reload the config: ") + err.Error())
+	}
+	slog.InfoContext(requestContext(c), "Config reloaded", "by", c.Sender().ID, "changed", changed, "needs_restart", pending)
+
+	text := b.t(c, "Reloaded, nothing changed")
+	if len(changed) > 0 {
+		text = b.t(c, "Reloaded. Changed: %s", strings.Join(changed, ", "))
+	}
+	if len(pending) > 0 {
+		text += "\n" + b.t(c, "These only change on restart: %s", strings.Join(pending, ", "))
+	}
+	return c.Send(text)
+}
+
+// reloadOnHangup reloads the config whenever the process gets SIGHUP.
+func (b *Bot) reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		changed, pending, err := b.reload()
+		if err != nil {
+			slog.Error("Could not reload the config", "err", err)
+			continue
+		}
+		slog.Info("Config reloaded", "changed", changed, "needs_restart", pending)
+	}
+}
+
+// instructions are the system instructions every conversation starts with,
+// the user's styles followed by SYSTEM_PROMPT.
+func (b *Bot) instructions(userID int64) string {
+	var fragments []string
+	for _, s := range b.userStyles(userID) {
+		fragments = append(fragments, s.Instruct)
+	}
+	if prompt := b.cfg().SystemPrompt; prompt != "" {
+		fragments = append(fragments, prompt)
+	}
+	return strings.Join(fragments, "\n")
+}
+
+// defaultModel answers users who haven't picked a model.
+func (b *Bot) defaultModel() string {
+	if model := b.cfg().DefaultModel; model != "" {
+		return model
+	}
+	return llm.DefaultModel
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const reminderTick = 30 * time.Second
+
+var (
+	reminderTimeRe    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	btnCancelReminder = tele.Btn{Unique: "cancel_reminder"}
+)
+
+// parseReminder parses `<time> [once|daily|weekdays] <prompt>`, e.g.
+// `9am daily "summarize Go releases"`.
+func parseReminder(payload string) (hour, minute int, repeat, prompt string, err error) {
+	fields := strings.Fields(payload)
+	if len(fields) < 2 {
+		return 0, 0, "", "", fmt.Errorf("missing time or prompt")
+	}
+
+	hour, minute, err = parseClock(fields[0])
+	if err != nil {
+		return 0, 0, "", "", err
+	}
+
+	rest := fields[1:]
+	repeat = "once"
+	switch strings.ToLower(rest[0]) {
+	case "once", "daily", "weekdays":
+		repeat = strings.ToLower(rest[0])
+		rest = rest[1:]
+	}
+
+	prompt = strings.Trim(strings.Join(rest, " "), `"“”'`)
+	if prompt == "" {
+		return 0, 0, "", "", fmt.Errorf("missing prompt")
+	}
+	return hour, minute, repeat, prompt, nil
+}
+
+// parseClock reads a time of day like 9am, 6:30pm or 21:00.
+func parseClock(s string) (hour, minute int, err error) {
+	m := reminderTimeRe.FindStringSubmatch(strings.ToLower(s))
+	if m == nil {
+		return 0, 0, fmt.Errorf("can't read time %q, try 9am, 6:30pm or 21:00", s)
+	}
+	hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("%q is not a valid time", s)
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("%q is not a valid time", s)
+	}
+	return hour, minute, nil
+}
+
+// nextRun returns the first time after `after` at hour:minute that matches
+// the repeat rule.
+func nextRun(hour, minute int, repeat string, after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), hour, minute, 0, 0, after.Location())
+	for !next.After(after) || (repeat == "weekdays" && (next.Weekday() == time.Saturday || next.Weekday() == time.Sunday)) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// userLocation is the user's timezone, the one they gave /quiet or /digest,
+// otherwise DIGEST_TIMEZONE or the server's.
+func (b *Bot) userLocation(userID int64) *time.Location {
+	var name string
+	if q, err := b.db.GetQuietHours(userID); err == nil {
+		name = q.Timezone
+	} else if dg, err := b.db.GetDigest(userID); err == nil {
+		name = dg.Timezone
+	}
+	if name == "" {
+		name = b.cfg().DigestTimezone
+	}
+	if zone, err := time.LoadLocation(name); name != "" && err == nil {
+		return zone
+	}
+	return time.Local
+}
+
+func (b *Bot) remindHandler(c tele.Context) error {
+	hour, minute, repeat, prompt, err := parseReminder(c.Message().Payload)
+	if err != nil {
+		return c.Send(b.t(c, "%v\nUsage: /remind 9am daily \"give me a summary of Go releases\"", err))
+	}
+
+	r := store.Reminder{
+		UserID:   c.Sender().ID,
+		Username: c.Sender().Username,
+		ChatID:   c.Chat().ID,
+		Prompt:   redact(b.cfg().Redaction, prompt),
+		Hour:     hour,
+		Minute:   minute,
+		Repeat:   repeat,
+		NextRun:  nextRun(hour, minute, repeat, time.Now().In(b.userLocation(c.Sender().ID))),
+	}
+	if err := b.db.SaveReminder(r); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your reminder: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Got it, next run %s.\nSee /reminders to list or cancel", r.NextRun.Format("Mon Jan 2 15:04 MST")))
+}
+
+func (b *Bot) remindersHandler(c tele.Context) error {
+	text, menu, err := b.remindersList(c)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your reminders: ") + err.Error())
+	}
+	return c.Send(text, menu)
+}
+
+func (b *Bot) cancelReminderHandler(c tele.Context) error {
+	if err := b.db.DeleteReminder(c.Sender().ID, c.Callback().Data); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not cancel that reminder")})
+	}
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Cancelled")})
+
+	text, menu, err := b.remindersList(c)
+	if err != nil {
+		return err
+	}
+	return c.Edit(text, menu)
+}
+
+func (b *Bot) remindersList(c tele.Context) (string, *tele.ReplyMarkup, error) {
+	reminders, err := b.db.GetReminders(c.Sender().ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	menu := &tele.ReplyMarkup{}
+	if len(reminders) == 0 {
+		return b.t(c, "You have no reminders"), menu, nil
+	}
+
+	var sb strings.Builder
+	var rows []tele.Row
+	for i, r := range reminders {
+		sb.WriteString(fmt.Sprintf("%d. %02d:%02d %s: %s\n", i+1, r.Hour, r.Minute, b.t(c, r.Repeat), truncate(r.Prompt, 40)))
+		rows = append(rows, menu.Row(menu.Data(b.t(c, "❌ Cancel %d", i+1), btnCancelReminder.Unique, r.ID)))
+	}
+	menu.Inline(rows...)
+	return sb.String(), menu, nil
+}
+
+// runReminders fires due reminders until the process exits.
+func (b *Bot) runReminders() {
+	for range time.Tick(reminderTick) {
+		due, err := b.db.DueReminders(time.Now())
+		if err != nil {
+			slog.Error("Could not load due reminders", "err", err)
+			continue
+		}
+		for _, r := range due {
+			b.fireReminder(r)
+		}
+	}
+}
+
+func (b *Bot) fireReminder(r store.Reminder) {
+	user := &tele.User{ID: r.UserID, Username: r.Username}
+
+	// The user may have lost access since they set the reminder.
+	dbUser, err := b.db.GetUser(r.UserID)
+	if err != nil && err != store.ErrUserNotFound {
+		slog.Error("Could not load reminder's user", "reminder", r.ID, "err", err)
+		return
+	}
+	if err != nil || !b.authorized(dbUser) || !b.permitted(user) {
+		slog.Info("Dropping reminder of a user without access", "reminder", r.ID, "user_id", r.UserID)
+		if err := b.db.DeleteReminder(r.UserID, r.ID); err != nil {
+			slog.Error("Could not delete reminder", "reminder", r.ID, "err", err)
+		}
+		return
+	}
+
+	text := "⏰ " + r.Prompt + "\n\n"
+	left, quota, err := b.userQuotaLeft(user)
+	if err != nil {
+		slog.Error("Could not check reminder's quota", "reminder", r.ID, "err", err)
+	}
+	if quota > 0 && left == 0 {
+		// Skip this run rather than go over, the next one may fit.
+		text += tr(b.userLanguage(user), "You've used your %d messages for today, try again tomorrow or get more with /plans", quota)
+	} else {
+		text += b.reminderAnswer(r, user)
+	}
+
+	if _, err := b.deliver(r.UserID, r.ChatID, "reminder", text); err != nil {
+		slog.Error("Could not deliver reminder", "reminder", r.ID, "err", err)
+	}
+
+	if r.Repeat == "once" {
+		err = b.db.DeleteReminder(r.UserID, r.ID)
+	} else {
+		err = b.db.SetReminderNextRun(r.ID, nextRun(r.Hour, r.Minute, r.Repeat, time.Now().In(b.userLocation(r.UserID))))
+	}
+	if err != nil {
+		slog.Error("Could not reschedule reminder", "reminder", r.ID, "err", err)
+	}
+}
+
+// reminderAnswer asks the model r's prompt for user, the error reply when
+// that fails.
+func (b *Bot) reminderAnswer(r store.Reminder, user *tele.User) string {
+	apiKey, err := b.groqKeyFor(user)
+	if err == nil {
+		var res llm.Completion
+		start := time.Now()
+		res, err = b.llm.Complete(context.Background(), apiKey, []llm.Message{
+			{Role: "system", Content: b.instructions(r.UserID)},
+			{Role: "user", Content: r.Prompt},
+		}, llm.WithModel(b.defaultModelFor(r.UserID)))
+		b.audit(user, r.Prompt, res, time.Since(start), err)
+		if err == nil {
+			b.recordRequest(user, res, time.Since(start))
+			return res.Content
+		}
+	}
+	errorsTotal.WithLabelValues("reminder").Inc()
+	slog.Error("Reminder failed", "reminder", r.ID, "err", err)
+	return errorReply(b.userLanguage(user), err)
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	retentionPreference = "retention"
+	retentionInterval   = time.Hour
+	day                 = 24 * time.Hour
+)
+
+const retentionUsage = `Usage: /retention 30d keeps your conversations, memories and usage for 30 days, 4w for four weeks
+/retention default goes back to the bot's policy
+/purge deletes them all now`
+
+var retentionRe = regexp.MustCompile(`^(\d+)([dw]?)$`)
+
+var btnPurge = tele.Btn{Unique: "purge"}
+
+// parseRetention reads /retention's 30d or 4w. Plain numbers are days.
+func parseRetention(arg string) (time.Duration, error) {
+	m := retentionRe.FindStringSubmatch(strings.ToLower(arg))
+	if m == nil {
+		return 0, fmt.Errorf("can't read %q, try 30d or 4w", arg)
+	}
+	n, _ := strconv.Atoi(m[1])
+	if n == 0 {
+		return 0, fmt.Errorf("keep your data at least a day, /purge deletes it now")
+	}
+	if m[2] == "w" {
+		n *= 7
+	}
+	return time.Duration(n) * day, nil
+}
+
+// retention is how long the user's data is kept, zero keeping it forever.
+// Their own pick can only be shorter than DATA_RETENTION_DAYS.
+func (b *Bot) retention(userID int64) time.Duration {
+	policy := b.cfg().DataRetention
+	value, _ := b.db.GetPreference(userID, retentionPreference)
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		return policy
+	}
+	if picked := time.Duration(days) * day; policy == 0 || picked < policy {
+		return picked
+	}
+	return policy
+}
+
+// describeRetention says how long data is kept, in the user's language.
+func (b *Bot) describeRetention(c tele.Context, d time.Duration) string {
+	if d == 0 {
+		return b.t(c, "until you delete it")
+	}
+	return b.t(c, "for %d days", int(d/day))
+}
+
+func (b *Bot) retentionHandler(c tele.Context) error {
+	userID := c.Sender().ID
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send(b.t(c, "Your conversations, memories and usage are kept %s", b.describeRetention(c, b.retention(userID))) + "\n\n" + b.t(c, retentionUsage))
+	}
+
+	value := ""
+	if strings.ToLower(args[0]) != "default" {
+		d, err := parseRetention(args[0])
+		if err != nil {
+			return c.Send(err.Error() + "\n\n" + b.t(c, retentionUsage))
+		}
+		if policy := b.cfg().DataRetention; policy > 0 && d > policy {
+			return c.Send(b.t(c, "This bot keeps data for at most %d days", int(policy/day)))
+		}
+		value = strconv.Itoa(int(d / day))
+	}
+	if err := b.db.SetPreference(userID, retentionPreference, value); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Your conversations, memories and usage are kept %s, older ones are deleted within the hour", b.describeRetention(c, b.retention(userID))))
+}
+
+// pruneUserData deletes the data older than each user's retention until
+// the process exits.
+func (b *Bot) pruneUserData() {
+	for {
+		users, err := b.db.GetUsers()
+		if err != nil {
+			slog.Error("Could not load users to prune", "err", err)
+		}
+		for _, u := range users {
+			retention := b.retention(u.UserID)
+			if u.UserID == 0 || retention == 0 {
+				continue
+			}
+			deleted, err := b.db.DeleteUserDataBefore(u.UserID, time.Now().Add(-retention))
+			if err != nil {
+				slog.Error("Could not prune user data", "user_id", u.UserID, "err", err)
+				continue
+			}
+			logDeletion(context.Background(), u.UserID, "retention", deleted)
+		}
+		time.Sleep(retentionInterval)
+	}
+}
+
+// logDeletion logs what was deleted from which table, when anything was.
+func logDeletion(ctx context.Context, userID int64, reason string, deleted map[string]int64) {
+	if len(deleted) == 0 {
+		return
+	}
+	attrs := []any{"user_id", userID, "reason", reason}
+	for table, n := range deleted {
+		attrs = append(attrs, table, n)
+	}
+	slog.InfoContext(ctx, "Deleted user data", attrs...)
+}
+
+func (b *Bot) purgeHandler(c tele.Context) error {
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(menu.Data(b.t(c, "Yes, delete my conversations"), btnPurge.Unique)))
+	return c.Send(b.t(c, "This deletes all your chats, conversations, memories, documents, pins and shared links now, keeping your account, settings and how much of your quotas you've used. It can't be undone."), menu)
+}
+
+func (b *Bot) confirmPurgeHandler(c tele.Context) error {
+	sender := c.Sender()
+	if cancel, ok := b.generations.LoadAndDelete(sender.ID); ok {
+		cancel.(context.CancelFunc)()
+	}
+	b.pendingEdits.Delete(sender.ID)
+
+	deleted, err := b.db.PurgeUserData(sender.ID)
+	if err != nil {
+		slog.ErrorContext(requestContext(c), "Could not purge user data", "user_id", sender.ID, "err", err)
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Could not delete your data")})
+	}
+	logDeletion(requestContext(c), sender.ID, "purge", deleted)
+	// The active chat is gone with the rest.
+	if err := b.db.SetPreference(sender.ID, chatPreference, ""); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not reset active chat", "err", err)
+	}
+	c.Respond(&tele.CallbackResponse{Text: b.t(c, "Deleted")})
+	return c.Edit(b.t(c, "Your conversations and everything that came of them are gone"))
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+)
+
+// maxRoutedErrorRate is the error rate at which auto routing stops picking
+// a model until it recovers.
+const maxRoutedErrorRate = 0.5
+
+// autoTiers are the model options that route each request, with the lowest
+// tier they pick from.
+var autoTiers = map[string]int{"auto": 1, "auto-smart": 2}
+
+// modelTiers rank the models auto routing picks from by quality. Reasoning
+// models are left out, as they take their time thinking.
+var modelTiers = map[string]int{
+	llm.DefaultModel:          1,
+	"gemma2-9b-it":            1,
+	"llama-3.3-70b-versatile": 2,
+	"mixtral-8x7b-32768":      2,
+}
+
+// route is the model auto routing picked and why.
+type route struct {
+	Model string
+	Stats llm.ModelStats
+}
+
+// pickedModel is the model option the user picked: an auto one, or the
+// model userModel answers them with.
+func (b *Bot) pickedModel(userID int64) string {
+	model, _ := b.db.GetPreference(userID, modelPreference)
+	if _, ok := autoTiers[model]; ok {
+		return model
+	}
+	return b.userModel(userID)
+}
+
+// routeModel picks the fastest healthy model of the auto option's tier or
+// above that the user may use. Models without recent requests are tried
+// first so there's something to go by, and when every model is failing the
+// one failing least is picked.
+func (b *Bot) routeModel(userID int64, auto string) route {
+	var best, leastFailing route
+	for _, model := range models {
+		if modelTiers[model] < autoTiers[auto] || !b.modelAllowed(userID, model) {
+			continue
+		}
+		r := route{Model: model, Stats: b.tracker.Stats(model)}
+		if leastFailing.Model == "" || r.Stats.ErrorRate < leastFailing.Stats.ErrorRate {
+			leastFailing = r
+		}
+		if r.Stats.Samples > 0 && r.Stats.ErrorRate >= maxRoutedErrorRate {
+			continue
+		}
+		if best.Model == "" || faster(r.Stats, best.Stats) {
+			best = r
+		}
+	}
+	if best.Model == "" {
+		best = leastFailing
+	}
+	if best.Model == "" {
+		best.Model = b.defaultModelFor(userID)
+	}
+
+	if last, _ := b.routes.Swap(auto, best.Model); last != best.Model {
+		slog.Info("Auto routing picked a model", "option", auto, "model", best.Model, "previous", last, "latency", best.Stats.Latency.Round(time.Millisecond), "error_rate", best.Stats.ErrorRate, "samples", best.Stats.Samples)
+	}
+	return best
+}
+
+// faster reports whether a model with stats a beats one with b, unmeasured
+// models winning so they get measured.
+func faster(a, b llm.ModelStats) bool {
+	if a.Latency == 0 || b.Latency == 0 {
+		return a.Latency == 0 && b.Latency != 0
+	}
+	return a.Latency < b.Latency
+}
+
+// validModel reports whether model is one users can pick.
+func validModel(model string) bool {
+	_, auto := autoTiers[model]
+	return auto || slices.Contains(models, model)
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	stopPreference             = "stop"
+	frequencyPenaltyPreference = "frequency_penalty"
+	presencePenaltyPreference  = "presence_penalty"
+	seedPreference             = "seed"
+	// maxStopSequences is as many as Groq takes.
+	maxStopSequences = 4
+	maxPenalty       = 2.0
+)
+
+const samplingUsage = `Usage:
+/sampling stop <sequence> | <sequence> ends answers at the first of up to four sequences, \n for a new line
+/sampling frequency <-2 to 2> and /sampling presence <-2 to 2> make the model repeat words and topics less, or more when negative
+/sampling seed <number> makes answers repeatable
+/sampling <setting> off clears one, /sampling reset all of them`
+
+// samplingSettings maps what /sampling calls a setting to its preference.
+var samplingSettings = map[string]string{
+	"stop":      stopPreference,
+	"frequency": frequencyPenaltyPreference,
+	"presence":  presencePenaltyPreference,
+	"seed":      seedPreference,
+}
+
+func (b *Bot) samplingHandler(c tele.Context) error {
+	userID := c.Sender().ID
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(b.samplingSummary(c) + "\n\n" + b.t(c, samplingUsage))
+	}
+
+	name := strings.ToLower(args[0])
+	if name == "reset" {
+		for _, key := range samplingSettings {
+			if err := b.db.SetPreference(userID, key, ""); err != nil {
+				return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+			}
+		}
+		return c.Send(b.t(c, "Sampling is back to the defaults"))
+	}
+	key, ok := samplingSettings[name]
+	if !ok || len(args) < 2 {
+		return c.Send(b.t(c, samplingUsage))
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c.Message().Payload), args[0]))
+	if strings.EqualFold(value, "off") {
+		value = ""
+	} else {
+		var err error
+		if value, err = parseSampling(key, value); err != nil {
+			return c.Send(err.Error() + "\n\n" + b.t(c, samplingUsage))
+		}
+	}
+	if err := b.db.SetPreference(userID, key, value); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	return c.Send(b.samplingSummary(c))
+}
+
+// parseSampling checks a /sampling value, returning it as it's stored.
+func parseSampling(key, value string) (string, error) {
+	switch key {
+	case stopPreference:
+		var sequences []string
+		for _, s := range strings.Split(value, "|") {
+			if s = strings.ReplaceAll(strings.TrimSpace(s), `\n`, "\n"); s != "" {
+				sequences = append(sequences, s)
+			}
+		}
+		if len(sequences) == 0 || len(sequences) > maxStopSequences {
+			return "", fmt.Errorf("give one to %d stop sequences", maxStopSequences)
+		}
+		data, err := json.Marshal(sequences)
+		return string(data), err
+	case seedPreference:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("%q isn't a whole number", value)
+		}
+		return value, nil
+	}
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil || p < -maxPenalty || p > maxPenalty {
+		return "", fmt.Errorf("%q isn't a number from -2 to 2", value)
+	}
+	return value, nil
+}
+
+// samplingSummary lists the sender's sampling settings.
+func (b *Bot) samplingSummary(c tele.Context) string {
+	userID := c.Sender().ID
+	stop, frequency, presence, seed := b.t(c, "none"), b.t(c, "default"), b.t(c, "default"), b.t(c, "random")
+	if sequences := b.stopSequences(userID); len(sequences) > 0 {
+		quoted := make([]string, len(sequences))
+		for i, s := range sequences {
+			quoted[i] = strconv.Quote(s)
+		}
+		stop = strings.Join(quoted, ", ")
+	}
+	if v, _ := b.db.GetPreference(userID, frequencyPenaltyPreference); v != "" {
+		frequency = v
+	}
+	if v, _ := b.db.GetPreference(userID, presencePenaltyPreference); v != "" {
+		presence = v
+	}
+	if v, _ := b.db.GetPreference(userID, seedPreference); v != "" {
+		seed = v
+	}
+	return b.t(c, "Stop sequences: %s\nFrequency penalty: %s\nPresence penalty: %s\nSeed: %s", stop, frequency, presence, seed)
+}
+
+func (b *Bot) stopSequences(userID int64) []string {
+	value, _ := b.db.GetPreference(userID, stopPreference)
+	var sequences []string
+	if value != "" {
+		json.Unmarshal([]byte(value), &sequences)
+	}
+	return sequences
+}
+
+// samplingOptions are the user's /sampling settings as request options.
+func (b *Bot) samplingOptions(userID int64) []llm.Option {
+	var opts []llm.Option
+	if sequences := b.stopSequences(userID); len(sequences) > 0 {
+		opts = append(opts, llm.WithStop(sequences...))
+	}
+	if v, _ := b.db.GetPreference(userID, frequencyPenaltyPreference); v != "" {
+		if p, err := strconv.ParseFloat(v, 64); err == nil {
+			opts = append(opts, llm.WithFrequencyPenalty(p))
+		}
+	}
+	if v, _ := b.db.GetPreference(userID, presencePenaltyPreference); v != "" {
+		if p, err := strconv.ParseFloat(v, 64); err == nil {
+			opts = append(opts, llm.WithPresencePenalty(p))
+		}
+	}
+	if v, _ := b.db.GetPreference(userID, seedPreference); v != "" {
+		if seed, err := strconv.Atoi(v); err == nil {
+			opts = append(opts, llm.WithSeed(seed))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+const runCodeParameters = `{
+	"type": "object",
+	"properties": {
+		"language": {"type": "string", "enum": ["python", "go"]},
+		"code": {"type": "string", "description": "A complete program that prints its results. Go code needs package main and a main function. There is no network access."}
+	},
+	"required": ["language", "code"]
+}`
+
+// codeTool lets the model run Python and Go snippets in the sandbox, for
+// admins and SANDBOX_USERS only.
+func (b *Bot) codeTool() tool {
+	return tool{
+		def:  llm.NewTool("run_code", "Run a Python or Go program and get its stdout, stderr and exit code. Use it to calculate, check code or process data instead of guessing.", []byte(runCodeParameters)),
+		flag: "code",
+		enabled: func(user *tele.User) bool {
+			return listed(b.cfg().Admins, user) || listed(b.cfg().SandboxUsers, user)
+		},
+		status: func(lang, arguments string) string {
+			var args struct{ Language string }
+			toolArguments(arguments, &args)
+			if args.Language == "go" {
+				return tr(lang, "⚙️ Running Go code…")
+			}
+			return tr(lang, "⚙️ Running Python code…")
+		},
+		run: func(ctx context.Context, arguments string) (toolResult, error) {
+			var args struct{ Language, Code string }
+			if err := toolArguments(arguments, &args); err != nil {
+				return toolResult{}, err
+			}
+			res, err := b.sandbox.Run(ctx, args.Language, args.Code)
+			if err != nil {
+				return toolResult{}, err
+			}
+
+			var sb strings.Builder
+			if res.TimedOut {
+				fmt.Fprintf(&sb, "Timed out after %s\n", b.cfg().SandboxTimeout)
+			} else {
+				fmt.Fprintf(&sb, "Exit code: %d\n", res.ExitCode)
+			}
+			if res.Stdout != "" {
+				sb.WriteString("stdout:\n" + res.Stdout + "\n")
+			}
+			if res.Stderr != "" {
+				sb.WriteString("stderr:\n" + res.Stderr + "\n")
+			}
+			return toolResult{Content: sb.String()}, nil
+		},
+	}
+}
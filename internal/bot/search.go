@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+)
+
+const searchParameters = `{
+	"type": "object",
+	"properties": {
+		"query": {"type": "string", "description": "What to search the web for"}
+	},
+	"required": ["query"]
+}`
+
+// searchTool lets the model look up current information on the web.
+func (b *Bot) searchTool() tool {
+	return tool{
+		flag: "web_search",
+		def:  llm.NewTool("search", "Search the web. Use it for recent events, current data or anything you're not sure about, then answer from the results.", []byte(searchParameters)),
+		status: func(lang, arguments string) string {
+			var args struct{ Query string }
+			toolArguments(arguments, &args)
+			return tr(lang, "🔎 Searching the web for %q…", args.Query)
+		},
+		run: func(ctx context.Context, arguments string) (toolResult, error) {
+			var args struct{ Query string }
+			if err := toolArguments(arguments, &args); err != nil {
+				return toolResult{}, err
+			}
+			results, err := b.search.Search(ctx, args.Query, b.cfg().SearchResults)
+			if err != nil {
+				return toolResult{}, err
+			}
+			if len(results) == 0 {
+				return toolResult{Content: "No results"}, nil
+			}
+
+			var sb strings.Builder
+			var sources []source
+			for i, r := range results {
+				fmt.Fprintf(&sb, "[%d] %s\n%s\n%s\n\n", i+1, r.Title, r.URL, r.Snippet)
+				sources = append(sources, source{Title: r.Title, URL: r.URL})
+			}
+			return toolResult{Content: strings.TrimSpace(sb.String()), Sources: sources}, nil
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const sentryTimeout = 10 * time.Second
+
+// sentry sends events to the store endpoint of a Sentry-compatible service.
+type sentry struct {
+	storeURL string
+	auth     string
+	client   *http.Client
+}
+
+// newSentry parses a DSN like https://<key>@sentry.example.com/<project>.
+func newSentry(dsn string) (*sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	key := u.User.Username()
+	project := path.Base(u.Path)
+	if key == "" || project == "" || project == "/" || project == "." {
+		return nil, fmt.Errorf("expected https://<key>@<host>/<project>, got %q", dsn)
+	}
+
+	prefix := strings.TrimSuffix(path.Dir(u.Path), "/")
+	return &sentry{
+		storeURL: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:     "Sentry sentry_version=7, sentry_client=groqy/1.0, sentry_key=" + key,
+		client:   &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+// report sends a fatal event with the panic's message and stack.
+func (s *sentry) report(message, stack string, tags map[string]string) error {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	body, err := json.Marshal(map[string]any{
+		"event_id":  hex.EncodeToString(id),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"level":     "fatal",
+		"platform":  "go",
+		"logger":    "groqy",
+		"message":   message,
+		"tags":      tags,
+		"extra":     map[string]string{"stack": stack},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sentry returned %s", resp.Status)
+	}
+	return nil
+}
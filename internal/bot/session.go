@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const sessionJanitorInterval = 10 * time.Minute
+
+// expireSession starts the sender over with an empty context when they have
+// been away for longer than the session TTL.
+func (b *Bot) expireSession(c tele.Context) {
+	if b.cfg().SessionTTL <= 0 {
+		return
+	}
+
+	last, err := b.db.LastActive(c.Sender().ID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.ErrorContext(requestContext(c), "Could not load last activity", "err", err)
+		}
+		return
+	}
+	if time.Since(last) < b.cfg().SessionTTL {
+		return
+	}
+
+	if err := b.db.DeleteSession(c.Sender().ID); err != nil {
+		slog.ErrorContext(requestContext(c), "Could not expire session", "err", err)
+		return
+	}
+	if b.cfg().SessionNotify {
+		c.Send(b.t(c, "It's been a while, starting a fresh conversation"))
+	}
+}
+
+// runSessionJanitor prunes the conversations of users who never came back.
+func (b *Bot) runSessionJanitor() {
+	for {
+		n, err := b.db.ExpireSessions(time.Now().Add(-b.cfg().SessionTTL))
+		if err != nil {
+			slog.Error("Could not expire sessions", "err", err)
+		} else if n > 0 {
+			slog.Info("Expired inactive sessions", "exchanges", n)
+		}
+		time.Sleep(sessionJanitorInterval)
+	}
+}
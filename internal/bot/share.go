@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const sharePath = "/share/"
+
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; color: #222; background: #fafafa; }
+header p { color: #777; font-size: .9rem; }
+.msg { white-space: pre-wrap; padding: .75rem 1rem; border-radius: .75rem; margin: .5rem 0; line-height: 1.45; }
+.user { background: #dcf3ff; margin-left: 15%; }
+.bot { background: #fff; border: 1px solid #e4e4e4; margin-right: 15%; }
+.meta { color: #999; font-size: .75rem; margin-top: .4rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{.Shared}}</p>
+</header>
+{{range .Exchanges}}
+<div class="msg user">{{.Prompt}}</div>
+<div class="msg bot">{{.Response}}<div class="meta">{{.Model}} · {{.CreatedAt.Format "2006-01-02 15:04"}}</div></div>
+{{end}}
+</body>
+</html>
+`))
+
+// newShareToken is a link's secret, long enough not to be guessed.
+func newShareToken() (string, error) {
+	token := make([]byte, 18)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// shareHandler makes a link to a read-only page of the active chat as it
+// is now, or revokes the sender's links with /share off.
+func (b *Bot) shareHandler(c tele.Context) error {
+	cfg := b.cfg()
+	if cfg.ShareAddr == "" || cfg.ShareURL == "" {
+		return c.Send(b.t(c, "Sharing isn't enabled on this bot"))
+	}
+	if args := c.Args(); len(args) > 0 && strings.ToLower(args[0]) == "off" {
+		n, err := b.db.DeleteShares(c.Sender().ID)
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not revoke your links: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Revoked %d links", n))
+	}
+
+	chatID := b.activeChat(c)
+	exchanges, err := b.db.ChatExchanges(c.Sender().ID, chatID, time.Now())
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your history: ") + err.Error())
+	}
+	if len(exchanges) == 0 {
+		return c.Send(b.t(c, "There's nothing in this chat to share yet"))
+	}
+	title := b.t(c, "Main chat")
+	if chatID != "" {
+		chat, err := b.db.GetChat(c.Sender().ID, chatID)
+		if err == nil && chat.Title != "" {
+			title = chat.Title
+		}
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not share the chat: ") + err.Error())
+	}
+	share := store.Share{Token: token, UserID: c.Sender().ID, ChatID: chatID, Title: title, CreatedAt: time.Now()}
+	if err := b.db.SaveShare(share); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not share the chat: ") + err.Error())
+	}
+	link := cfg.ShareURL + sharePath + token
+	return c.Send(b.t(c, "🔗 %s\n\nAnyone with the link can read this chat as it is now, later messages stay private. /share off revokes your links.", link), tele.NoPreview)
+}
+
+// startShareServer serves the shared chats on addr.
+func (b *Bot) startShareServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(sharePath, b.sharedChat)
+
+	serveHTTP("Share", addr, mux)
+}
+
+func (b *Bot) sharedChat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	token := strings.TrimPrefix(r.URL.Path, sharePath)
+	share, err := b.db.GetShare(token)
+	if err == sql.ErrNoRows || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Error("Could not load share", "err", err)
+		http.Error(w, "could not load the chat", http.StatusInternalServerError)
+		return
+	}
+	exchanges, err := b.db.ChatExchanges(share.UserID, share.ChatID, share.CreatedAt)
+	if err != nil {
+		slog.Error("Could not load shared chat", "user", share.UserID, "err", err)
+		http.Error(w, "could not load the chat", http.StatusInternalServerError)
+		return
+	}
+
+	lang := b.userLanguage(&tele.User{ID: share.UserID})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = sharePage.Execute(w, map[string]any{
+		"Lang":      lang,
+		"Title":     share.Title,
+		"Shared":    tr(lang, "Shared on %s, read-only", share.CreatedAt.Format("2006-01-02 15:04")),
+		"Exchanges": exchanges,
+	})
+	if err != nil {
+		slog.Error("Could not render shared chat", "err", err)
+	}
+}
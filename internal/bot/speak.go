@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	speakPreference = "speak"
+	speechTimeout   = time.Minute
+	// maxSpeechLength is the longest input OpenAI's speech API accepts.
+	maxSpeechLength = 4096
+)
+
+// speakHandler toggles sending every answer as a voice note too.
+func (b *Bot) speakHandler(c tele.Context) error {
+	if b.speech == nil {
+		return c.Send(b.t(c, "Text-to-speech is not enabled on this bot"))
+	}
+	if !b.featureEnabled("tts", c.Sender().ID) {
+		return c.Send(b.t(c, "/%s isn't available to you yet", "speak"))
+	}
+
+	on := !b.speaking(c.Sender().ID)
+	value := "off"
+	if on {
+		value = "on"
+	}
+	if err := b.db.SetPreference(c.Sender().ID, speakPreference, value); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	if on {
+		return c.Send(b.t(c, "I'll read my answers out too, /speak again to stop"))
+	}
+	return c.Send(b.t(c, "Back to text only"))
+}
+
+func (b *Bot) ttsHandler(c tele.Context) error {
+	if b.speech == nil {
+		return c.Send(b.t(c, "Text-to-speech is not enabled on this bot"))
+	}
+	text := strings.TrimSpace(c.Message().Payload)
+	if reply := replyTo(c.Message()); text == "" && reply != nil {
+		text = reply.Text
+	}
+	if text == "" {
+		return c.Send(b.t(c, "Usage: /tts <text>, or reply to a message with /tts"))
+	}
+	return b.sendVoice(c, text)
+}
+
+func (b *Bot) speaking(userID int64) bool {
+	if b.speech == nil || !b.featureEnabled("tts", userID) {
+		return false
+	}
+	value, _ := b.db.GetPreference(userID, speakPreference)
+	return value == "on"
+}
+
+func (b *Bot) sendVoice(c tele.Context, text string) error {
+	c.Notify(tele.RecordingAudio)
+	ctx, cancel := context.WithTimeout(requestContext(c), speechTimeout)
+	defer cancel()
+
+	audio, err := b.speech.Speak(ctx, truncate(text, maxSpeechLength-1))
+	if err != nil {
+		errorsTotal.WithLabelValues("speech").Inc()
+		slog.ErrorContext(requestContext(c), "Could not synthesize speech", "err", err)
+		return c.Send(b.t(c, "ERROR: Could not read that out"))
+	}
+	return c.Send(&tele.Voice{File: tele.FromReader(bytes.NewReader(audio))})
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const statsTopModels = 3
+
+func (b *Bot) statsHandler(c tele.Context) error {
+	stats, err := b.db.UsageStats(statsTopModels)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load stats: ") + err.Error())
+	}
+	day, err := b.db.ActiveUsers(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load stats: ") + err.Error())
+	}
+	week, err := b.db.ActiveUsers(time.Now().Add(-7 * 24 * time.Hour))
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load stats: ") + err.Error())
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.t(c, "Users: %d", stats.Users) + "\n")
+	sb.WriteString(b.t(c, "Active: %d in 24h, %d in 7d", day, week) + "\n")
+	sb.WriteString(b.t(c, "Messages handled: %d", stats.Exchanges) + "\n")
+	if stats.Requests > 0 {
+		sb.WriteString(b.t(c, "Avg Groq latency: %.0fms", stats.AvgLatencyMS) + "\n")
+		sb.WriteString(b.t(c, "Error rate: %.1f%% (%d of %d requests)",
+			100*float64(stats.Errors)/float64(stats.Requests), stats.Errors, stats.Requests) + "\n")
+	} else {
+		sb.WriteString(b.t(c, "Latency and errors: enable AUDIT_LOG to track them") + "\n")
+	}
+
+	if len(stats.TopModels) > 0 {
+		sb.WriteString("\n" + b.t(c, "Top models:") + "\n")
+		for i, m := range stats.TopModels {
+			sb.WriteString(b.t(c, "%d. %s  %d messages, %d tokens", i+1, m.Model, m.Exchanges, m.Tokens) + "\n")
+		}
+	}
+	return c.Send(sb.String())
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+// maxStickerSize is well above Telegram's own 512KB limit for stickers.
+const maxStickerSize = 1 << 20
+
+const stickerInstruct = "The user sent you a sticker. React to it the way a friend would in a chat: one or two playful sentences about what it shows and the mood it is going for. Plain text, no markdown. Reply in the language with the code %q."
+
+// stickerHandler answers stickers with a playful description, looking at
+// the sticker itself with the vision model when it can be downloaded and
+// going by its emoji otherwise.
+func (b *Bot) stickerHandler(c tele.Context) error {
+	sticker := c.Message().Sticker
+	prompt := "Sticker"
+	if sticker.Emoji != "" {
+		prompt += " " + sticker.Emoji
+	}
+	if sticker.SetName != "" {
+		prompt += " from the set " + sticker.SetName
+	}
+
+	message := llm.Message{Role: "user", Content: prompt}
+	model := b.userModel(c.Sender().ID)
+	var image string
+	var err error
+	if b.featureEnabled("vision", c.Sender().ID) {
+		if image, err = b.stickerImage(c, sticker); err != nil {
+			slog.WarnContext(requestContext(c), "Could not download the sticker", "err", err)
+		}
+	}
+	if image != "" {
+		message.Images = []string{image}
+		model = b.visionModel()
+	}
+	messages := []llm.Message{
+		{Role: "system", Content: b.instructions(c.Sender().ID)},
+		{Role: "system", Content: fmt.Sprintf(stickerInstruct, b.lang(c))},
+		message,
+	}
+
+	c.Notify(tele.Typing)
+	res, err := b.complete(c, prompt, func(apiKey string) (llm.Completion, error) {
+		return b.llm.Complete(requestContext(c), apiKey, messages, llm.WithModel(model))
+	})
+	if err != nil {
+		return c.Send(errorReply(b.lang(c), err))
+	}
+	msg, err := c.Bot().Reply(c.Message(), res.Content)
+	if err != nil {
+		return err
+	}
+
+	ex := store.Exchange{
+		UserID:           c.Sender().ID,
+		ChatID:           b.activeChat(c),
+		Prompt:           prompt,
+		Response:         res.Content,
+		Model:            res.Model,
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		MessageID:        msg.ID,
+		PromptMessageID:  c.Message().ID,
+	}
+	b.redactExchange(&ex)
+	if err := b.db.SaveExchange(&ex); err != nil {
+		errorsTotal.WithLabelValues("db").Inc()
+		slog.ErrorContext(requestContext(c), "Could not save exchange", "err", err)
+	}
+	return nil
+}
+
+// stickerImage downloads the sticker as a data URL. Animated and video
+// stickers aren't images, so their thumbnail is used, and "" is returned
+// when they have none.
+func (b *Bot) stickerImage(c tele.Context, sticker *tele.Sticker) (string, error) {
+	file := &sticker.File
+	if sticker.Animated || sticker.Video {
+		if sticker.Thumbnail == nil {
+			return "", nil
+		}
+		file = &sticker.Thumbnail.File
+	}
+
+	r, err := c.Bot().File(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxStickerSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxStickerSize {
+		return "", fmt.Errorf("sticker is over %dKB", maxStickerSize>>10)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("sticker is %s, not an image", mimeType)
+	}
+	return llm.ImageURL(mimeType, data), nil
+}
+
+// visionModel is the model that looks at images.
+func (b *Bot) visionModel() string {
+	if model := b.cfg().VisionModel; model != "" {
+		return model
+	}
+	return llm.DefaultVisionModel
+}
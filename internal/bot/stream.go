@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+// streamEditInterval keeps message edits well under Telegram's per-chat
+// rate limit while an answer streams in.
+const streamEditInterval = time.Second
+
+var btnStop = tele.Btn{Unique: "stop"}
+
+// streamAnswer streams the completion for messages into a new message that
+// carries a stop button until the answer is done. Stopping is not an error:
+// the message is finalized with whatever was produced. With a cacheKey the
+// answer comes from the cache when it can, and finished answers go in it.
+func (b *Bot) streamAnswer(tc tele.Context, userMessage string, messages []llm.Message, cacheKey string) (llm.Completion, *tele.Message, error) {
+	messages, trimmed, err := b.fitPrompt(tc, messages)
+	if err != nil {
+		msg, _ := send(tc, errorReply(b.lang(tc), err))
+		return llm.Completion{}, msg, err
+	}
+	if trimmed {
+		cacheKey = ""
+	}
+
+	if cacheKey != "" {
+		if cached, ok := b.cache.get(cacheKey); ok {
+			cacheLookups.WithLabelValues("hit").Inc()
+			msg, err := send(tc, cached.Response, b.answerMenu(tc))
+			return llm.Completion{Content: cached.Response, Model: cached.Model}, msg, err
+		}
+		cacheLookups.WithLabelValues("miss").Inc()
+	}
+
+	stopMenu := &tele.ReplyMarkup{}
+	stopMenu.Inline(stopMenu.Row(stopMenu.Data(b.t(tc, "⏹ Stop"), btnStop.Unique)))
+	placeholder := "…"
+	if llm.Reasons(b.userModel(tc.Sender().ID)) {
+		placeholder = b.t(tc, "🤔 Thinking…")
+	}
+	msg, err := send(tc, placeholder, stopMenu)
+	if err != nil {
+		return llm.Completion{}, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(requestContext(tc))
+	defer cancel()
+	b.generations.Store(tc.Sender().ID, cancel)
+	defer b.generations.Delete(tc.Sender().ID)
+
+	tc.Set(progressKey, true)
+	stopProgress := b.whileSlow(tc, msg, stopMenu)
+	defer stopProgress()
+
+	var text strings.Builder
+	var sources []source
+	lastEdit := time.Now()
+	res, err := b.complete(tc, userMessage, func(apiKey string) (llm.Completion, error) {
+		var res llm.Completion
+		var err error
+		res, sources, err = b.callWithTools(ctx, tc.Sender(), messages, func(messages []llm.Message, tools []llm.Tool) (llm.Completion, error) {
+			return b.llm.Stream(ctx, apiKey, messages, func(delta string) {
+				stopProgress()
+				text.WriteString(delta)
+				if time.Since(lastEdit) >= streamEditInterval {
+					lastEdit = time.Now()
+					tc.Bot().Edit(msg, text.String()+" ▌", stopMenu)
+				}
+			}, append(b.modelOptions(tc.Sender().ID), llm.WithTools(tools...))...)
+		}, func(t tool, arguments string) {
+			stopProgress()
+			text.Reset()
+			tc.Bot().Edit(msg, t.status(b.lang(tc), arguments), stopMenu)
+		})
+		return res, err
+	})
+
+	stopProgress()
+	stopped := errors.Is(err, context.Canceled)
+	if err != nil && !stopped {
+		tc.Bot().Edit(msg, errorReply(b.lang(tc), err))
+		return res, msg, err
+	}
+
+	final := res.Content
+	if stopped {
+		final = strings.TrimSpace(final + "\n\n" + b.t(tc, "(stopped)"))
+	} else if cacheKey != "" && len(sources) == 0 && !res.Fallback {
+		b.cache.put(cacheKey, res, b.cfg().Redaction)
+	}
+	final += sourcesList(b.lang(tc), sources)
+	// Regenerating and editing work on the conversation, which stateless
+	// answers aren't part of.
+	menu := b.answerMenu(tc)
+	if tc.Get(statelessKey) != nil {
+		menu = nil
+	}
+	if _, err := b.showAnswer(tc, msg, res, final, menu); err != nil {
+		return res, msg, err
+	}
+	return res, msg, nil
+}
+
+// showAnswer puts the finished answer into msg, or a new message when msg
+// is nil, the way the user asked for it: code blocks sent as files in /code
+// mode, the model's reasoning and Markdown. It returns the message the
+// answer is in.
+func (b *Bot) showAnswer(tc tele.Context, msg *tele.Message, res llm.Completion, answer string, menu *tele.ReplyMarkup) (*tele.Message, error) {
+	if res.Fallback {
+		answer += "\n\n" + b.t(tc, "↪️ Answered by %s, %s is busy right now", res.Model, b.userModel(tc.Sender().ID))
+	}
+	var files []codeFile
+	if b.codeMode(tc.Sender().ID) {
+		answer, files = b.codeAttachments(tc, answer)
+	}
+	answer, entities := b.withReasoning(tc, res, answer)
+	put := func(opts ...any) (*tele.Message, error) {
+		if msg == nil {
+			return send(tc, answer, opts...)
+		}
+		return tc.Bot().Edit(msg, answer, opts...)
+	}
+
+	if len(entities) == 0 && b.writesMarkdown(tc.Sender().ID) {
+		// Models don't always write Markdown Telegram can parse, in which
+		// case the answer is left as plain text.
+		if shown, err := put(menu, tele.ModeMarkdown); err == nil {
+			b.sendCodeFiles(tc, files)
+			return shown, nil
+		}
+	}
+	shown, err := put(menu, entities)
+	if err != nil {
+		return nil, err
+	}
+	b.sendCodeFiles(tc, files)
+	return shown, nil
+}
+
+func (b *Bot) stopHandler(c tele.Context) error {
+	cancel, ok := b.generations.Load(c.Sender().ID)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Nothing to stop")})
+	}
+	cancel.(context.CancelFunc)()
+	return c.Respond(&tele.CallbackResponse{Text: b.t(c, "Stopped")})
+}
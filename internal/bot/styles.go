@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const stylePreference = "style"
+
+// style is a fragment of the instructions. A user's styles are composed
+// from at most one per group.
+type style struct {
+	Name     string
+	Group    string
+	Instruct string
+}
+
+var styles = []style{
+	{Name: "plain", Group: "format", Instruct: "Do not use any markdown formatting in your response, keep it plain text"},
+	{Name: "markdown", Group: "format", Instruct: "Format your response with Telegram's Markdown only: *bold*, _italic_, `inline code` and ``` code blocks. No headings, tables or nested lists."},
+	{Name: "concise", Group: "length", Instruct: "Keep your answers short and to the point, a few sentences unless asked for more."},
+	{Name: "verbose", Group: "length", Instruct: "Give thorough answers, with the context, examples and caveats that help understand them."},
+	{Name: "eli5", Group: "audience", Instruct: "Explain things the way you would to a five year old, with simple words and everyday comparisons."},
+}
+
+const styleUsage = `Usage: /style <styles>, e.g. /style markdown concise
+
+plain or markdown: how answers are formatted
+concise or verbose: how long they are
+eli5: explained like you're five
+
+A style replaces the one of its kind you have, /style reset goes back to the default.`
+
+func findStyle(name string) (style, bool) {
+	i := slices.IndexFunc(styles, func(s style) bool { return s.Name == name })
+	if i < 0 {
+		return style{}, false
+	}
+	return styles[i], true
+}
+
+// pickStyles keeps the last of names in each group, dropping unknown ones.
+func pickStyles(names []string) []style {
+	var picked []style
+	for _, name := range names {
+		s, ok := findStyle(strings.ToLower(strings.TrimSpace(name)))
+		if !ok {
+			continue
+		}
+		picked = slices.DeleteFunc(picked, func(p style) bool { return p.Group == s.Group })
+		picked = append(picked, s)
+	}
+	return picked
+}
+
+// composeStyles picks from names, formatting as plain text when none of
+// them says how.
+func composeStyles(names []string) []style {
+	picked := pickStyles(names)
+	if !slices.ContainsFunc(picked, func(s style) bool { return s.Group == "format" }) {
+		plain, _ := findStyle("plain")
+		picked = append([]style{plain}, picked...)
+	}
+	return picked
+}
+
+// userStyles are the styles the user picked on top of DEFAULT_STYLE, with
+// /code mode's format in place of theirs.
+func (b *Bot) userStyles(userID int64) []style {
+	value, _ := b.db.GetPreference(userID, stylePreference)
+	names := append(slices.Clone(b.cfg().DefaultStyle), strings.Split(value, ",")...)
+	picked := composeStyles(names)
+	if b.codeMode(userID) {
+		picked = slices.DeleteFunc(picked, func(s style) bool { return s.Group == "format" })
+		picked = append([]style{codeStyle}, picked...)
+	}
+	return picked
+}
+
+func styleNames(picked []style) []string {
+	names := make([]string, 0, len(picked))
+	for _, s := range picked {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// writesMarkdown reports whether the user's answers are formatted with
+// Markdown.
+func (b *Bot) writesMarkdown(userID int64) bool {
+	return slices.ContainsFunc(b.userStyles(userID), func(s style) bool { return s.Name == "markdown" || s.Name == codeStyle.Name })
+}
+
+func (b *Bot) styleHandler(c tele.Context) error {
+	userID := c.Sender().ID
+	args := strings.Fields(strings.ToLower(c.Message().Payload))
+	if len(args) == 0 {
+		return c.Send(b.t(c, "Your style: %s", strings.Join(styleNames(b.userStyles(userID)), ", ")) + "\n\n" + b.t(c, styleUsage))
+	}
+
+	value := ""
+	if !(len(args) == 1 && args[0] == "reset") {
+		for _, name := range args {
+			if _, ok := findStyle(name); !ok {
+				return c.Send(b.t(c, "There's no %q style", name) + "\n\n" + b.t(c, styleUsage))
+			}
+		}
+		current, _ := b.db.GetPreference(userID, stylePreference)
+		value = strings.Join(styleNames(pickStyles(append(strings.Split(current, ","), args...))), ",")
+	}
+	if err := b.db.SetPreference(userID, stylePreference, value); err != nil {
+		return c.Send(b.t(c, "ERROR: Could not save your setting: ") + err.Error())
+	}
+	return c.Send(b.t(c, "Your style: %s", strings.Join(styleNames(b.userStyles(userID)), ", ")))
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// suggestCommand is the command name, e.g. "/auth", that word is a typo of,
+// nil when none is close enough. Admin commands are only suggested to
+// admins, private ones outside groups.
+func (b *Bot) suggestCommand(c tele.Context, word string) *Command {
+	word = strings.ToLower(strings.TrimPrefix(word, "/"))
+	if word == "" {
+		return nil
+	}
+	// One typo, two in longer names.
+	allowed := 1
+	if len([]rune(word)) > 5 {
+		allowed = 2
+	}
+	var best *Command
+	bestDistance := allowed + 1
+	for i, cmd := range b.commands {
+		if cmd.Admin && !b.isAdmin(c) || cmd.Private && inGroup(c) {
+			continue
+		}
+		if d := levenshtein(word, strings.TrimPrefix(cmd.Name, "/")); d < bestDistance {
+			best, bestDistance = &b.commands[i], d
+		}
+	}
+	return best
+}
+
+// withCommandHints answers messages that look like a mistyped command, like
+// /autj or "auth mytoken" from someone who isn't authenticated, with the
+// command they probably meant.
+func (b *Bot) withCommandHints(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		fields := strings.Fields(c.Text())
+		if len(fields) == 0 {
+			return next(c)
+		}
+		word, to, _ := strings.Cut(fields[0], "@")
+		if to != "" && !strings.EqualFold(to, c.Bot().Me.Username) {
+			return next(c)
+		}
+
+		slash := strings.HasPrefix(word, "/")
+		// Short words are too easily a typo of something.
+		if !slash && (len([]rune(word)) < 3 || inGroup(c) || b.checkAuth(c) == nil) {
+			return next(c)
+		}
+		cmd := b.suggestCommand(c, word)
+		if cmd == nil {
+			return next(c)
+		}
+		if !slash && strings.EqualFold(word, strings.TrimPrefix(cmd.Name, "/")) {
+			return c.Send(b.t(c, "Commands start with a slash, try %s", strings.Join(append([]string{cmd.Name}, fields[1:]...), " ")))
+		}
+		return c.Send(b.t(c, "There's no %s, did you mean %s? %s", word, cmd.Name, b.t(c, cmd.Description)))
+	}
+}
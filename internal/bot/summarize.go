@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	summarizeInstruct = "You compress chat history. Write a concise summary of the conversation below, keeping facts, names, decisions and open questions the assistant will need to continue it. Reply with the summary only."
+	summaryPrefix     = "Summary of the earlier conversation with this user:\n\n"
+)
+
+func exchangeTokens(exchanges []store.Exchange) int {
+	n := 0
+	for _, ex := range exchanges {
+		n += llm.EstimateTokens(ex.Prompt) + llm.EstimateTokens(ex.Response)
+	}
+	return n
+}
+
+// maybeSummarize folds the chat's oldest unsummarized exchanges into its
+// running summary once those exchanges outgrow the token threshold or the
+// context window, keeping the most recent half of the window verbatim.
+func (b *Bot) maybeSummarize(ctx context.Context, user *tele.User, chatID string) error {
+	if _, running := b.summarizing.LoadOrStore(user.ID, true); running {
+		return nil
+	}
+	defer b.summarizing.Delete(user.ID)
+
+	exchanges, err := b.db.UnsummarizedExchanges(user.ID, chatID)
+	if err != nil {
+		return err
+	}
+
+	window := b.cfg().ContextWindow
+	if exchangeTokens(exchanges) <= b.cfg().SummarizeThreshold && len(exchanges) <= window {
+		return nil
+	}
+	keep := max(window/2, 2)
+	if len(exchanges) <= keep {
+		return nil
+	}
+	old := exchanges[:len(exchanges)-keep]
+
+	previous, err := b.db.GetSummary(user.ID, chatID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var sb strings.Builder
+	if previous.Content != "" {
+		sb.WriteString("Summary so far:\n" + previous.Content + "\n\nConversation since:\n")
+	}
+	for _, ex := range old {
+		sb.WriteString(fmt.Sprintf("User: %s\nAssistant: %s\n\n", ex.Prompt, ex.Response))
+	}
+
+	apiKey, err := b.groqKeyFor(user)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	res, err := b.llm.Complete(ctx, apiKey, []llm.Message{
+		{Role: "system", Content: summarizeInstruct},
+		{Role: "user", Content: sb.String()},
+	})
+	if err != nil {
+		return err
+	}
+	b.recordUsage(user, res, time.Since(start))
+
+	ids := make([]string, 0, len(old))
+	for _, ex := range old {
+		ids = append(ids, ex.ID)
+	}
+	if err := b.db.SaveSummary(user.ID, chatID, res.Content, ids); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Summarized conversation", "user", user.ID, "exchanges", len(old))
+	return nil
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/store"
+	tele "gopkg.in/telebot.v3"
+)
+
+const templateUsage = `Usage:
+/template save <name> "<text with {{input}}>"
+/template share <name> "<text>" (admin, everyone can use it)
+/template use <name> <input>
+/template list
+/template delete <name>
+
+Templates can use {{input}}, {{name}}, {{username}} and {{date}}.`
+
+var (
+	templateNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+	templateVarRe  = regexp.MustCompile(`{{\s*(\w+)\s*}}`)
+)
+
+// cutWord splits the first word off s, keeping the rest as written.
+func cutWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t\n"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i:])
+	}
+	return s, ""
+}
+
+// renderTemplate fills in the template's variables, leaving unknown ones
+// as they are.
+func renderTemplate(content string, vars map[string]string) string {
+	return templateVarRe.ReplaceAllStringFunc(content, func(v string) string {
+		if value, ok := vars[templateVarRe.FindStringSubmatch(v)[1]]; ok {
+			return value
+		}
+		return v
+	})
+}
+
+func (b *Bot) templateHandler(c tele.Context) error {
+	sub, rest := cutWord(c.Message().Payload)
+	name, text := cutWord(rest)
+
+	switch sub {
+	case "list":
+		return b.listTemplates(c)
+	case "save", "share", "use", "delete":
+	default:
+		return c.Send(b.t(c, templateUsage))
+	}
+	if !templateNameRe.MatchString(name) {
+		return c.Send(b.t(c, "Template names are up to 32 letters, digits, - or _") + "\n\n" + b.t(c, templateUsage))
+	}
+
+	switch sub {
+	case "save", "share":
+		t := store.Template{UserID: c.Sender().ID, Name: name, Content: strings.Trim(text, `"“”'`)}
+		if sub == "share" {
+			if !b.isAdmin(c) {
+				return c.Send(b.t(c, "Only admins can share templates"))
+			}
+			t.UserID = store.GlobalTemplates
+		}
+		if t.Content == "" {
+			return c.Send(b.t(c, templateUsage))
+		}
+		if err := b.db.SaveTemplate(t); err != nil {
+			return c.Send(b.t(c, "ERROR: Could not save the template: ") + err.Error())
+		}
+		return c.Send(b.t(c, "Saved %q, use it with /template use %s <input>", name, name))
+
+	case "delete":
+		deleted, err := b.db.DeleteTemplate(c.Sender().ID, name)
+		if err == nil && !deleted && b.isAdmin(c) {
+			deleted, err = b.db.DeleteTemplate(store.GlobalTemplates, name)
+		}
+		if err != nil {
+			return c.Send(b.t(c, "ERROR: Could not delete the template: ") + err.Error())
+		}
+		if !deleted {
+			return c.Send(b.t(c, "You have no template called %q", name))
+		}
+		return c.Send(b.t(c, "Deleted %q", name))
+	}
+
+	t, err := b.db.GetTemplate(c.Sender().ID, name)
+	if err == sql.ErrNoRows {
+		return c.Send(b.t(c, "No template called %q, see /template list", name))
+	}
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load the template: ") + err.Error())
+	}
+	prompt := renderTemplate(t.Content, map[string]string{
+		"input":    text,
+		"name":     c.Sender().FirstName,
+		"username": c.Sender().Username,
+		"date":     time.Now().Format("Monday, January 2 2006"),
+	})
+	return b.chatHandler(c, prompt)
+}
+
+func (b *Bot) listTemplates(c tele.Context) error {
+	templates, err := b.db.GetTemplates(c.Sender().ID)
+	if err != nil {
+		return c.Send(b.t(c, "ERROR: Could not load your templates: ") + err.Error())
+	}
+	if len(templates) == 0 {
+		return c.Send(b.t(c, "You have no templates yet") + "\n\n" + b.t(c, templateUsage))
+	}
+
+	var sb strings.Builder
+	for _, t := range templates {
+		shared := ""
+		if t.UserID == store.GlobalTemplates {
+			shared = " " + b.t(c, "(shared)")
+		}
+		sb.WriteString(fmt.Sprintf("%s%s: %s\n", t.Name, shared, truncate(t.Content, 60)))
+	}
+	return c.Send(sb.String())
+}
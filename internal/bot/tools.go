@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	tele "gopkg.in/telebot.v3"
+)
+
+// maxToolRounds is how many times the model may call tools for one answer
+// before it has to answer with what it has.
+const maxToolRounds = 3
+
+// tool is something the model can call while answering.
+type tool struct {
+	def llm.Tool
+	// enabled decides who the tool is offered to, everyone when nil, and
+	// flag is the feature flag that can turn it off.
+	enabled func(user *tele.User) bool
+	flag    string
+	// status is shown in the answer while the tool runs.
+	status func(lang, arguments string) string
+	run    func(ctx context.Context, arguments string) (toolResult, error)
+}
+
+type toolResult struct {
+	Content string
+	// Sources are listed under the answer.
+	Sources []source
+}
+
+type source struct {
+	Title string
+	URL   string
+}
+
+// userTools are the tools offered to user.
+func (b *Bot) userTools(user *tele.User) []tool {
+	if !b.featureEnabled("tools", user.ID) {
+		return nil
+	}
+	var tools []tool
+	for _, t := range b.tools {
+		if (t.enabled == nil || t.enabled(user)) && (t.flag == "" || b.featureEnabled(t.flag, user.ID)) {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// callWithTools runs call until the model answers instead of calling
+// tools, running the tools user may use in between. onTool is told about
+// each call before it runs.
+func (b *Bot) callWithTools(ctx context.Context, user *tele.User, messages []llm.Message, call func([]llm.Message, []llm.Tool) (llm.Completion, error), onTool func(t tool, arguments string)) (llm.Completion, []source, error) {
+	tools := b.userTools(user)
+	var sources []source
+	var promptTokens, completionTokens int
+	for round := 0; ; round++ {
+		var defs []llm.Tool
+		if round < maxToolRounds {
+			for _, t := range tools {
+				defs = append(defs, t.def)
+			}
+		}
+		res, err := call(messages, defs)
+		promptTokens += res.PromptTokens
+		completionTokens += res.CompletionTokens
+		if err != nil || len(res.ToolCalls) == 0 {
+			res.PromptTokens, res.CompletionTokens = promptTokens, completionTokens
+			return res, sources, err
+		}
+
+		messages = append(messages, llm.Message{Role: "assistant", Content: res.Content, ToolCalls: res.ToolCalls})
+		for _, tc := range res.ToolCalls {
+			result := runTool(ctx, tools, tc, onTool)
+			sources = append(sources, result.Sources...)
+			messages = append(messages, llm.Message{Role: "tool", ToolCallID: tc.ID, Content: result.Content})
+		}
+	}
+}
+
+func runTool(ctx context.Context, tools []tool, call llm.ToolCall, onTool func(tool, string)) toolResult {
+	for _, t := range tools {
+		if t.def.Function.Name != call.Function.Name {
+			continue
+		}
+		onTool(t, call.Function.Arguments)
+		result, err := t.run(ctx, call.Function.Arguments)
+		if err != nil {
+			errorsTotal.WithLabelValues("tool").Inc()
+			slog.ErrorContext(ctx, "Tool failed", "tool", call.Function.Name, "err", err)
+			return toolResult{Content: "The tool failed: " + err.Error()}
+		}
+		return result
+	}
+	return toolResult{Content: fmt.Sprintf("There is no tool called %q", call.Function.Name)}
+}
+
+// toolArguments decodes the JSON arguments the model called a tool with.
+func toolArguments(arguments string, dest any) error {
+	if err := json.Unmarshal([]byte(arguments), dest); err != nil {
+		return fmt.Errorf("invalid arguments %s: %v", arguments, err)
+	}
+	return nil
+}
+
+// sourcesList lists the sources of an answer, each once.
+func sourcesList(lang string, sources []source) string {
+	seen := map[string]bool{}
+	var sb strings.Builder
+	for _, s := range sources {
+		if seen[s.URL] {
+			continue
+		}
+		seen[s.URL] = true
+		fmt.Fprintf(&sb, "\n%d. %s\n%s", len(seen), s.Title, s.URL)
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "\n\n" + tr(lang, "Sources:") + sb.String()
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"fmt"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// topicContext sends everything to the forum topic the update came from,
+// where Telegram would otherwise put it in the group's General topic.
+type topicContext struct {
+	tele.Context
+	thread int
+}
+
+func (c *topicContext) Send(what any, opts ...any) error {
+	_, err := send(c, what, opts...)
+	return err
+}
+
+func (c *topicContext) SendAlbum(a tele.Album, opts ...any) error {
+	_, err := c.Bot().SendAlbum(c.Recipient(), a, append([]any{&tele.SendOptions{ThreadID: c.thread}}, opts...)...)
+	return err
+}
+
+func (c *topicContext) Notify(action tele.ChatAction) error {
+	return c.Bot().Notify(c.Recipient(), action, c.thread)
+}
+
+// withTopic answers updates from a forum topic in that topic.
+func withTopic(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if thread := topicID(c); thread != 0 {
+			c = &topicContext{Context: c, thread: thread}
+		}
+		return next(c)
+	}
+}
+
+// topicID is the forum topic the update came from, 0 outside of one and
+// in a group's General topic.
+func topicID(c tele.Context) int {
+	msg := c.Message()
+	if msg == nil || !msg.TopicMessage {
+		return 0
+	}
+	return msg.ThreadID
+}
+
+// send sends what like c.Send does, returning the message sent.
+func send(c tele.Context, what any, opts ...any) (*tele.Message, error) {
+	if thread := topicID(c); thread != 0 {
+		// Options given after this one override it, replies landing in
+		// the replied-to message's topic anyway.
+		opts = append([]any{&tele.SendOptions{ThreadID: thread}}, opts...)
+	}
+	return c.Bot().Send(c.Recipient(), what, opts...)
+}
+
+// replyTo is the message msg replies to. Messages in a forum topic that
+// reply to nothing come as replies to the message that opened the topic,
+// which this leaves out.
+func replyTo(msg *tele.Message) *tele.Message {
+	if msg == nil || msg.ReplyTo == nil || msg.TopicMessage && msg.ReplyTo.ID == msg.ThreadID {
+		return nil
+	}
+	return msg.ReplyTo
+}
+
+// topicKey is the conversation key of a forum topic, under the group's.
+func topicKey(chatID int64, thread int) string {
+	return groupPrefix(chatID) + fmt.Sprintf("topic:%d", thread)
+}
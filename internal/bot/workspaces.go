@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/store"
+)
+
+var errBudgetSpent = errors.New("the workspace's daily budget is spent")
+
+// tokenWorkspace returns the name of the workspace token joins, "" for
+// AUTH_TOKEN, and whether it is valid at all.
+func (b *Bot) tokenWorkspace(token string) (string, bool) {
+	if token == b.cfg().AuthToken {
+		return "", true
+	}
+	for _, ws := range b.cfg().Workspaces {
+		if slices.Contains(ws.AuthTokens, token) {
+			return ws.Name, true
+		}
+	}
+	return "", false
+}
+
+// authorized reports whether the user's token still joins the workspace
+// they are in.
+func (b *Bot) authorized(user store.User) bool {
+	ws, ok := b.tokenWorkspace(user.Token)
+	return ok && ws == user.Workspace
+}
+
+// workspace returns the settings of the workspace the user is in, the
+// bot-wide ones when they aren't in any.
+func (b *Bot) workspace(userID int64) config.Workspace {
+	cfg := b.cfg()
+	name := ""
+	if len(cfg.Workspaces) > 0 {
+		user, err := b.db.GetUser(userID)
+		if err != nil && err != store.ErrUserNotFound {
+			slog.Error("Could not load user", "user", userID, "err", err)
+		}
+		name = user.Workspace
+	}
+	for _, ws := range cfg.Workspaces {
+		if ws.Name == name {
+			return ws
+		}
+	}
+	return config.Workspace{GroqToken: cfg.GroqToken, DefaultModel: cfg.DefaultModel, ImageQuota: cfg.ImageQuota}
+}
+
+// defaultModelFor is the model for users who haven't picked one, their
+// workspace's default if it has one.
+func (b *Bot) defaultModelFor(userID int64) string {
+	model := b.workspace(userID).DefaultModel
+	if model == "" {
+		model = llm.DefaultModel
+	}
+	if !b.modelAllowed(userID, model) {
+		return b.planModels(userID)[0]
+	}
+	return model
+}
+
+// checkWorkspaceBudget fails once the workspace has spent its daily budget.
+func (b *Bot) checkWorkspaceBudget(ws config.Workspace) error {
+	if ws.Name == "" || ws.DailyBudget <= 0 {
+		return nil
+	}
+	totals, err := b.db.UsageTotals(startOfDay(time.Now()))
+	if err != nil {
+		return err
+	}
+	if b.workspaceSpend(totals, ws.Name) >= ws.DailyBudget {
+		return errBudgetSpent
+	}
+	return nil
+}
+
+func (b *Bot) workspaceSpend(totals []store.UsageTotal, name string) float64 {
+	sum := 0.0
+	for _, t := range totals {
+		if t.Workspace == name {
+			c, _ := b.cost(t)
+			sum += c
+		}
+	}
+	return sum
+}
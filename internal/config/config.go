@@ -0,0 +1,460 @@
+// Package config reads the bot's settings from the environment and .env.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	BotToken    string
+	GroqToken   string
+	AuthToken   string
+	DatabaseURL string
+	// SQLiteDriver is cgo or purego, store's default for the binary when
+	// empty.
+	SQLiteDriver string
+
+	// Provider is "groq", or "mock" to answer with MockTemplate offline.
+	Provider     string
+	MockTemplate string
+	// FallbackModels are tried in order when the requested model keeps
+	// being rate limited or unavailable, openai:<model> ones on the
+	// OpenAI-compatible API at OpenAIURL with OpenAIToken.
+	FallbackModels []string
+	OpenAIToken    string
+	OpenAIURL      string
+	// DefaultModel answers users who haven't picked a model, llm's default
+	// when empty.
+	DefaultModel string
+	// CompareModels are the models /compare runs a prompt against, the
+	// first three of them.
+	CompareModels []string
+	// SystemPrompt is added to the instructions of every conversation.
+	SystemPrompt string
+	// DefaultStyle are the output styles of users who haven't picked their
+	// own, plain text when empty.
+	DefaultStyle []string
+	// VisionModel looks at the stickers users send, llm's default when
+	// empty.
+	VisionModel string
+	// FollowUps suggests questions to ask next under each answer, written
+	// by FollowUpModel, llm's default when empty.
+	FollowUps     bool
+	FollowUpModel string
+
+	// ContextWindow is the number of past exchanges sent with each prompt.
+	ContextWindow int
+	// SummarizeThreshold is the estimated token count of unsummarized
+	// history that triggers summarization.
+	SummarizeThreshold int
+	MaxConcurrency     int
+	// DigestTime and DigestTimezone are when /digest sends the daily
+	// digest unless the user picks their own, the server's timezone when
+	// DigestTimezone is empty.
+	DigestTime     string
+	DigestTimezone string
+	// ProgressDelay is how long a completion runs before the user is told
+	// it's still going, zero never tells them.
+	ProgressDelay time.Duration
+	// SessionTTL starts a fresh conversation after this long without a
+	// message, zero keeping conversations forever.
+	SessionTTL    time.Duration
+	SessionNotify bool
+	// RateLimitQueue is how many requests may wait while the shared Groq
+	// key is rate limited.
+	RateLimitQueue int
+	// JobRetryWindow is how old a request left unanswered by a restart may
+	// be and still get answered after it, older ones being dropped.
+	// ReadReceipts reacts 👀 to the messages taken in to be answered.
+	JobRetryWindow time.Duration
+	ReadReceipts   bool
+	// TelegramRate caps the calls a second to the Bot API, TelegramChatRate
+	// the ones for each private chat. Groups get Telegram's 20 a minute.
+	TelegramRate     float64
+	TelegramChatRate float64
+	// CacheTTL is how long answers to context-free prompts are reused,
+	// zero disabling the cache. CacheSize of them are kept in memory.
+	CacheTTL  time.Duration
+	CacheSize int
+
+	// Admins and ServerKeyUsers hold Telegram user IDs or usernames.
+	Admins []string
+	// ServerKeyUsers may fall back to GroqToken, everyone may when empty.
+	ServerKeyUsers []string
+	// AllowedUsers are the Telegram user IDs that may use the bot, everyone
+	// with the token when empty. DeniedUsers never may.
+	AllowedUsers []int64
+	DeniedUsers  []int64
+	// EncryptionKey encrypts users' own Groq keys; /apikey is disabled
+	// without it.
+	EncryptionKey string
+
+	// LogFormat is text or json, LogLevel debug, info, warn or error.
+	LogFormat string
+	LogLevel  string
+
+	MetricsAddr string
+	HealthAddr  string
+	// GatewayAddr serves an OpenAI-compatible API backed by the bot.
+	GatewayAddr string
+	// ShareAddr serves the chats shared with /share, at ShareURL from the
+	// outside.
+	ShareAddr string
+	ShareURL  string
+
+	// ModelPrices adds to or overrides llm.DefaultPrices.
+	ModelPrices string
+	// DailyBudget in USD, AlertChatID is told when a day's spend passes it
+	// and gets the stack of handler panics.
+	DailyBudget float64
+	AlertChatID int64
+	// SentryDSN reports handler panics to Sentry or a compatible service.
+	SentryDSN string
+	// TracingEndpoint is the OpenTelemetry collector spans are exported to
+	// over OTLP/HTTP, as TracingService with TracingHeaders.
+	TracingEndpoint string
+	TracingService  string
+	TracingHeaders  string
+
+	AuditLog       bool
+	AuditRetention time.Duration
+	// DataRetention is how long users' conversations, memories, audit
+	// entries and usage are kept unless they pick a shorter /retention,
+	// zero keeping them until they pick one.
+	DataRetention time.Duration
+	// Redaction is how much personal data is scrubbed from prompts and
+	// answers before they're logged or stored: off, standard (API keys and
+	// card numbers) or strict (emails, phone numbers and profanity too).
+	Redaction string
+
+	// BackupInterval uploads a backup of the SQLite database to the
+	// S3-compatible BackupBucket this often, zero disabling it.
+	BackupInterval  time.Duration
+	BackupEndpoint  string
+	BackupRegion    string
+	BackupBucket    string
+	BackupAccessKey string
+	BackupSecretKey string
+
+	// EmbeddingsToken enables long-term memory.
+	EmbeddingsToken string
+	EmbeddingsURL   string
+	EmbeddingsModel string
+
+	// ImagesToken enables /imagine through an OpenAI-compatible images API.
+	ImagesToken string
+	ImagesURL   string
+	ImagesModel string
+	// ImageQuota is how many images a user may generate a day, zero for
+	// no limit.
+	ImageQuota int
+
+	// SpeechToken enables /speak and /tts through an OpenAI-compatible
+	// speech API.
+	SpeechToken string
+	SpeechURL   string
+	SpeechModel string
+	SpeechVoice string
+
+	// SearchProvider is searxng, brave or serper, enabling the web search
+	// tool. SearchURL is the SearxNG instance, SearchToken the API key of
+	// the others.
+	SearchProvider string
+	SearchURL      string
+	SearchToken    string
+	SearchResults  int
+
+	// CodeSandbox enables the tool that runs Python and Go in Docker, for
+	// admins and SandboxUsers, IDs or usernames.
+	CodeSandbox        bool
+	SandboxUsers       []string
+	SandboxPythonImage string
+	SandboxGoImage     string
+	SandboxTimeout     time.Duration
+
+	Moderation      bool
+	ModerationModel string
+	// ModerationBlock lists the refused hazard categories, all when empty.
+	ModerationBlock []string
+
+	// AbuseCooldown is the first cooldown of a user who trips one of the
+	// abuse signals, doubling each time within a day. Zero turns it off.
+	AbuseCooldown time.Duration
+	// AbuseBurst is how many requests a minute trip it, AbuseRepeats how
+	// many near identical prompts in ten minutes and AbuseViolations how
+	// many moderation violations in a day. Zero disables the signal.
+	AbuseBurst      int
+	AbuseRepeats    int
+	AbuseViolations int
+
+	// Workspaces split the bot between teams, each with its own tokens,
+	// key, defaults and budget. Users who authenticate with AuthToken are
+	// in none of them.
+	Workspaces []Workspace
+
+	// Plans are sold for Telegram Stars. Without any, nobody has a message
+	// quota or is kept off models. Workspace members aren't on a plan.
+	Plans []Plan
+	// FreeMessageQuota is how many messages a day users without a paid
+	// plan get, zero for no limit.
+	FreeMessageQuota int
+	// FreeModels are the models users without a paid plan may use, every
+	// model when empty.
+	FreeModels []string
+}
+
+// Workspace is configured with WORKSPACE_<NAME>_* variables. Its settings
+// default to the bot-wide ones, except for DailyBudget.
+type Workspace struct {
+	Name string
+	// AuthTokens are the tokens that join the workspace.
+	AuthTokens   []string
+	GroqToken    string
+	DefaultModel string
+	ImageQuota   int
+	// DailyBudget in USD stops answers on the workspace's key for the rest
+	// of the day once spent, zero for no limit.
+	DailyBudget float64
+}
+
+// Plan is configured with PLAN_<NAME>_* variables.
+type Plan struct {
+	Name string
+	// Stars is the price of Days on the plan.
+	Stars int
+	Days  int
+	// MessageQuota and ImageQuota are per day, zero for no limit.
+	MessageQuota int
+	ImageQuota   int
+	// Models are the models the plan may use, every model when empty.
+	Models []string
+}
+
+// fromFile holds the variables Load took from .env. Variables already in
+// the environment win over .env, the ones from .env are updated by every
+// Load.
+var fromFile = map[string]bool{}
+
+// Load reads .env into the environment and builds the config from it. It
+// can be called again to pick up changes to .env.
+func Load() (Config, error) {
+	vars, err := godotenv.Read()
+	if err != nil {
+		return Config{}, fmt.Errorf("Error loading .env file")
+	}
+	for name := range fromFile {
+		if _, ok := vars[name]; !ok {
+			os.Unsetenv(name)
+			delete(fromFile, name)
+		}
+	}
+	for name, value := range vars {
+		if _, set := os.LookupEnv(name); set && !fromFile[name] {
+			continue
+		}
+		os.Setenv(name, value)
+		fromFile[name] = true
+	}
+	return FromEnv(), nil
+}
+
+func FromEnv() Config {
+	cfg := Config{
+		BotToken:    os.Getenv("BOT_TOKEN"),
+		GroqToken:   os.Getenv("GROQ_TOKEN"),
+		AuthToken:   os.Getenv("AUTH_TOKEN"),
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+
+		SQLiteDriver: os.Getenv("SQLITE_DRIVER"),
+
+		Provider:     envString("PROVIDER", "groq"),
+		MockTemplate: os.Getenv("MOCK_TEMPLATE"),
+		DefaultModel: os.Getenv("DEFAULT_MODEL"),
+
+		CompareModels: envList("COMPARE_MODELS"),
+
+		FallbackModels: envList("FALLBACK_MODELS"),
+		OpenAIToken:    os.Getenv("OPENAI_TOKEN"),
+		OpenAIURL:      envString("OPENAI_URL", "https://api.openai.com/v1"),
+
+		SystemPrompt: os.Getenv("SYSTEM_PROMPT"),
+		DefaultStyle: envList("DEFAULT_STYLE"),
+		VisionModel:  os.Getenv("VISION_MODEL"),
+
+		FollowUps:     os.Getenv("FOLLOW_UPS") == "true",
+		FollowUpModel: os.Getenv("FOLLOW_UP_MODEL"),
+
+		ContextWindow:      envInt("CONTEXT_WINDOW", 10),
+		SummarizeThreshold: envInt("SUMMARIZE_THRESHOLD", 3000),
+		MaxConcurrency:     envInt("MAX_CONCURRENCY", 4),
+		DigestTime:         envString("DIGEST_TIME", "21:00"),
+		DigestTimezone:     os.Getenv("DIGEST_TIMEZONE"),
+		ProgressDelay:      envDuration("PROGRESS_DELAY", 5*time.Second),
+		RateLimitQueue:     envInt("RATE_LIMIT_QUEUE", 20),
+		JobRetryWindow:     envDuration("JOB_RETRY_WINDOW", time.Hour),
+		ReadReceipts:       os.Getenv("READ_RECEIPTS") == "true",
+		TelegramRate:       envFloat("TELEGRAM_RATE", 30),
+		TelegramChatRate:   envFloat("TELEGRAM_CHAT_RATE", 1),
+		CacheTTL:           envDuration("CACHE_TTL", 0),
+		CacheSize:          envInt("CACHE_SIZE", 1000),
+		SessionTTL:         envDuration("SESSION_TTL", 0),
+		SessionNotify:      os.Getenv("SESSION_NOTIFY") == "true",
+
+		Admins:         envList("ADMINS"),
+		ServerKeyUsers: envList("SERVER_KEY_USERS"),
+		AllowedUsers:   envIDs("ALLOWED_USERS"),
+		DeniedUsers:    envIDs("DENIED_USERS"),
+		EncryptionKey:  os.Getenv("ENCRYPTION_KEY"),
+
+		LogFormat: envString("LOG_FORMAT", "text"),
+		LogLevel:  envString("LOG_LEVEL", "info"),
+
+		MetricsAddr: os.Getenv("METRICS_ADDR"),
+		HealthAddr:  os.Getenv("HEALTH_ADDR"),
+		GatewayAddr: os.Getenv("GATEWAY_ADDR"),
+		ShareAddr:   os.Getenv("SHARE_ADDR"),
+		ShareURL:    strings.TrimSuffix(os.Getenv("SHARE_URL"), "/"),
+
+		ModelPrices: os.Getenv("MODEL_PRICES"),
+		DailyBudget: envFloat("DAILY_BUDGET", 0),
+		AlertChatID: int64(envInt("ALERT_CHAT_ID", 0)),
+		SentryDSN:   os.Getenv("SENTRY_DSN"),
+
+		TracingEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		TracingService:  envString("OTEL_SERVICE_NAME", "groqy"),
+		TracingHeaders:  os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"),
+
+		AuditLog:       os.Getenv("AUDIT_LOG") == "true",
+		AuditRetention: time.Duration(envInt("AUDIT_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		DataRetention:  time.Duration(envInt("DATA_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		Redaction:      envString("REDACTION", "standard"),
+
+		BackupInterval:  envDuration("BACKUP_INTERVAL", 0),
+		BackupEndpoint:  envString("BACKUP_S3_ENDPOINT", "https://s3.amazonaws.com"),
+		BackupRegion:    envString("BACKUP_S3_REGION", "us-east-1"),
+		BackupBucket:    os.Getenv("BACKUP_S3_BUCKET"),
+		BackupAccessKey: os.Getenv("BACKUP_S3_ACCESS_KEY"),
+		BackupSecretKey: os.Getenv("BACKUP_S3_SECRET_KEY"),
+
+		EmbeddingsToken: os.Getenv("EMBEDDINGS_TOKEN"),
+		EmbeddingsURL:   envString("EMBEDDINGS_URL", "https://api.openai.com/v1/embeddings"),
+		EmbeddingsModel: envString("EMBEDDINGS_MODEL", "text-embedding-3-small"),
+
+		ImagesToken: os.Getenv("IMAGES_TOKEN"),
+		ImagesURL:   envString("IMAGES_URL", "https://api.openai.com/v1/images/generations"),
+		ImagesModel: envString("IMAGES_MODEL", "dall-e-3"),
+		ImageQuota:  envInt("IMAGE_QUOTA", 5),
+
+		SpeechToken: os.Getenv("TTS_TOKEN"),
+		SpeechURL:   envString("TTS_URL", "https://api.openai.com/v1/audio/speech"),
+		SpeechModel: envString("TTS_MODEL", "tts-1"),
+		SpeechVoice: envString("TTS_VOICE", "alloy"),
+
+		SearchProvider: os.Getenv("SEARCH_PROVIDER"),
+		SearchURL:      os.Getenv("SEARCH_URL"),
+		SearchToken:    os.Getenv("SEARCH_TOKEN"),
+		SearchResults:  envInt("SEARCH_RESULTS", 5),
+
+		CodeSandbox:        os.Getenv("CODE_SANDBOX") == "true",
+		SandboxUsers:       envList("SANDBOX_USERS"),
+		SandboxPythonImage: envString("SANDBOX_PYTHON_IMAGE", "python:3.12-alpine"),
+		SandboxGoImage:     envString("SANDBOX_GO_IMAGE", "golang:1.22-alpine"),
+		SandboxTimeout:     envDuration("SANDBOX_TIMEOUT", 20*time.Second),
+
+		Moderation:      os.Getenv("MODERATION") == "true",
+		ModerationModel: envString("MODERATION_MODEL", "llama-guard-3-8b"),
+		ModerationBlock: envList("MODERATION_BLOCK"),
+
+		AbuseCooldown:   envDuration("ABUSE_COOLDOWN", 5*time.Minute),
+		AbuseBurst:      envInt("ABUSE_BURST", 20),
+		AbuseRepeats:    envInt("ABUSE_REPEATS", 5),
+		AbuseViolations: envInt("ABUSE_VIOLATIONS", 3),
+
+		FreeMessageQuota: envInt("FREE_MESSAGE_QUOTA", 20),
+		FreeModels:       envList("FREE_MODELS"),
+	}
+	for _, name := range envList("WORKSPACES") {
+		prefix := "WORKSPACE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg.Workspaces = append(cfg.Workspaces, Workspace{
+			Name:         name,
+			AuthTokens:   envList(prefix + "AUTH_TOKENS"),
+			GroqToken:    envString(prefix+"GROQ_TOKEN", cfg.GroqToken),
+			DefaultModel: envString(prefix+"DEFAULT_MODEL", cfg.DefaultModel),
+			ImageQuota:   envInt(prefix+"IMAGE_QUOTA", cfg.ImageQuota),
+			DailyBudget:  envFloat(prefix+"DAILY_BUDGET", 0),
+		})
+	}
+	for _, name := range envList("PLANS") {
+		prefix := "PLAN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg.Plans = append(cfg.Plans, Plan{
+			Name:         name,
+			Stars:        envInt(prefix+"STARS", 0),
+			Days:         envInt(prefix+"DAYS", 30),
+			MessageQuota: envInt(prefix+"MESSAGE_QUOTA", 0),
+			ImageQuota:   envInt(prefix+"IMAGE_QUOTA", cfg.ImageQuota),
+			Models:       envList(prefix + "MODELS"),
+		})
+	}
+	return cfg
+}
+
+func envString(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(name string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func envFloat(name string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// envList splits a comma separated variable, dropping blanks and any
+// leading @ so usernames can be written either way.
+func envList(name string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimPrefix(strings.TrimSpace(v), "@"); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// envIDs parses a comma separated list of Telegram user IDs, skipping
+// anything that isn't one.
+func envIDs(name string) []int64 {
+	var ids []int64
+	for _, v := range envList(name) {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,85 @@
+// Package i18n translates the bot's replies. Messages are looked up by
+// their English text in the catalogs under locales, one JSON object per
+// language, and fall back to English when there's no translation.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Default is the language the messages are written in.
+const Default = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		data, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", f.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(f.Name(), ".json")] = catalog
+	}
+}
+
+// Languages returns the supported language codes, English first.
+func Languages() []string {
+	langs := []string{Default}
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Supported reports whether lang has a catalog, or is English.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == Default
+}
+
+// Normalize turns a Telegram language_code like "pt-br" into a supported
+// language, English when there's none.
+func Normalize(code string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(code), "-")
+	if !Supported(lang) {
+		return Default
+	}
+	return lang
+}
+
+// T returns msg in lang, msg itself when it isn't translated.
+func T(lang, msg string) string {
+	if t, ok := catalogs[lang][msg]; ok && t != "" {
+		return t
+	}
+	return msg
+}
+
+// Translations returns msg in every language it's translated to, for
+// Telegram's command menu.
+func Translations(msg string) map[string]string {
+	translations := map[string]string{}
+	for lang, catalog := range catalogs {
+		if t, ok := catalog[msg]; ok && t != "" {
+			translations[lang] = t
+		}
+	}
+	return translations
+}
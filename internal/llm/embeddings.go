@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Embedder turns text into a vector for similarity search.
+type Embedder interface {
+	Embed(input string) ([]float32, error)
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible embeddings endpoint.
+type OpenAIEmbedder struct {
+	URL        string
+	Model      string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func NewOpenAIEmbedder(url, model, token string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{URL: url, Model: model, Token: token, HTTPClient: http.DefaultClient}
+}
+
+func (e *OpenAIEmbedder) Embed(input string) ([]float32, error) {
+	jsonBody, err := json.Marshal(map[string]string{"input": input, "model": e.Model})
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling JSON:\n%v", err)
+	}
+
+	req, err := http.NewRequest("POST", e.URL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request:\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.Token)
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Embeddings request failed with %s: %s", resp.Status, body)
+	}
+
+	var responseBody struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &responseBody); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling response: %v", err)
+	}
+	if len(responseBody.Data) == 0 {
+		return nil, fmt.Errorf("No embedding found in the response")
+	}
+	return responseBody.Data[0].Embedding, nil
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryAfter is used when a rate limited response doesn't say how
+// long to wait.
+const defaultRetryAfter = 10 * time.Second
+
+var (
+	ErrInvalidKey    = errors.New("api key rejected")
+	ErrBadRequest    = errors.New("request rejected")
+	ErrModelNotFound = errors.New("model not found")
+	ErrUnavailable   = errors.New("service unavailable")
+)
+
+// APIError is a non-200 response, with the details from its error object.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string
+	Code       string
+	// RequestID identifies the request to Groq support.
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Groq returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.RequestID != "" {
+		msg += ", request id " + e.RequestID
+	}
+	return msg
+}
+
+// Unwrap maps the status to one of the Err values, so callers can use
+// errors.Is.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrInvalidKey
+	case e.StatusCode == http.StatusNotFound || e.Code == "model_not_found":
+		return ErrModelNotFound
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	case e.StatusCode >= 400:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// RateLimitError is returned when the API answers 429 Too Many Requests.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	RequestID  string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("rate limited, retry in %s", e.RetryAfter)
+	if e.RequestID != "" {
+		msg += ", request id " + e.RequestID
+	}
+	return msg
+}
+
+// responseError turns a non-200 response and its body into an APIError or
+// RateLimitError.
+func responseError(resp *http.Response, body []byte) error {
+	requestID := resp.Header.Get("X-Request-Id")
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{RetryAfter: retryAfter(resp.Header), RequestID: requestID}
+	}
+
+	e := &APIError{StatusCode: resp.StatusCode, RequestID: requestID}
+	var errorBody struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorBody); err == nil && errorBody.Error.Message != "" {
+		e.Message = errorBody.Error.Message
+		e.Type = errorBody.Error.Type
+		if errorBody.Error.Code != nil {
+			e.Code = fmt.Sprint(errorBody.Error.Code)
+		}
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+func retryAfter(h http.Header) time.Duration {
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	// Groq also says when the request quota resets, e.g. "2m59.56s".
+	if d, err := time.ParseDuration(h.Get("X-Ratelimit-Reset-Requests")); err == nil {
+		return d
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// failoverAttempts is how many 429 or 503 responses in a row move on
+	// to the next model.
+	failoverAttempts = 2
+	failoverPause    = time.Second
+	// unavailableCooldown skips a model that answered 503 for this long.
+	unavailableCooldown = 30 * time.Second
+)
+
+// Route is a model to fall back to. Requests on it use APIKey instead of
+// the caller's key when it is set, for other providers.
+type Route struct {
+	Provider string
+	Model    string
+	Client   Client
+	APIKey   string
+}
+
+func (r Route) name() string {
+	return r.Provider + "/" + r.Model
+}
+
+// Failover is a Client that moves on to the next of its fallbacks when a
+// model keeps answering 429 or 503. A model that failed is skipped until
+// it should be back, so later requests don't wait on it again.
+type Failover struct {
+	primary   Client
+	provider  string
+	fallbacks []Route
+	// OnFailover is called when a fallback answered instead of the model
+	// asked for, err being the last error of the one before it.
+	OnFailover func(from, to string, err error)
+
+	mu      sync.Mutex
+	cooling map[string]time.Time
+}
+
+// NewFailover wraps primary, named provider, with the fallbacks tried in
+// order after the requested model.
+func NewFailover(primary Client, provider string, fallbacks []Route) *Failover {
+	return &Failover{primary: primary, provider: provider, fallbacks: fallbacks, cooling: map[string]time.Time{}}
+}
+
+func (f *Failover) Complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error) {
+	return f.try(ctx, apiKey, opts, func(r Route, key string, opts []Option) (Completion, bool, error) {
+		res, err := r.Client.Complete(ctx, key, messages, opts...)
+		return res, false, err
+	})
+}
+
+func (f *Failover) Stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error) {
+	return f.try(ctx, apiKey, opts, func(r Route, key string, opts []Option) (Completion, bool, error) {
+		started := false
+		res, err := r.Client.Stream(ctx, key, messages, func(delta string) {
+			started = true
+			onDelta(delta)
+		}, opts...)
+		return res, started, err
+	})
+}
+
+func (f *Failover) CheckKey(ctx context.Context, apiKey string) error {
+	return f.primary.CheckKey(ctx, apiKey)
+}
+
+// try runs call on each route in turn. It stops at the first answer, at
+// errors a fallback won't fix and once content was streamed.
+func (f *Failover) try(ctx context.Context, apiKey string, opts []Option, call func(r Route, key string, opts []Option) (Completion, bool, error)) (Completion, error) {
+	requested := NewRequestBody(nil, opts...).Model
+	routes := []Route{{Provider: f.provider, Model: requested, Client: f.primary}}
+	for _, r := range f.fallbacks {
+		if r.name() != routes[0].name() {
+			routes = append(routes, r)
+		}
+	}
+
+	var lastErr error
+	for i, r := range routes {
+		last := i == len(routes)-1
+		if !last && f.coolingDown(r) {
+			continue
+		}
+		key := apiKey
+		if r.APIKey != "" {
+			key = r.APIKey
+		}
+		routeOpts := append(append([]Option{}, opts...), WithModel(r.Model))
+
+		for attempt := 1; ; attempt++ {
+			res, started, err := call(r, key, routeOpts)
+			if err == nil || started || !failsOver(err) {
+				if err == nil && i > 0 {
+					res.Fallback = true
+					if f.OnFailover != nil {
+						f.OnFailover(routes[0].name(), r.name(), lastErr)
+					}
+				}
+				return res, err
+			}
+			lastErr = err
+			if attempt >= failoverAttempts || last {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return Completion{}, ctx.Err()
+			case <-time.After(failoverPause):
+			}
+		}
+		f.coolDown(r, lastErr)
+		if last {
+			break
+		}
+	}
+	return Completion{}, lastErr
+}
+
+// failsOver reports whether err is worth trying another model for.
+func failsOver(err error) bool {
+	var limited *RateLimitError
+	return errors.As(err, &limited) || errors.Is(err, ErrUnavailable)
+}
+
+func (f *Failover) coolingDown(r Route) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Now().Before(f.cooling[r.name()])
+}
+
+func (f *Failover) coolDown(r Route, err error) {
+	d := unavailableCooldown
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		d = limited.RetryAfter
+	}
+	f.mu.Lock()
+	f.cooling[r.name()] = time.Now().Add(d)
+	f.mu.Unlock()
+}
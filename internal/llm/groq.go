@@ -0,0 +1,274 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/logging"
+	"github.com/musaubrian/groqy/internal/tracing"
+)
+
+// Groq is a Client for Groq's OpenAI-compatible API, or any other API at
+// BaseURL that speaks the same protocol.
+type Groq struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewGroq() *Groq {
+	return &Groq{BaseURL: DefaultBaseURL, HTTPClient: http.DefaultClient}
+}
+
+func (g *Groq) newRequest(ctx context.Context, apiKey string, requestBody RequestBody) (*http.Request, error) {
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling JSON:\n%v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.BaseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request:\n%v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set("X-Request-Id", id)
+	}
+	tracing.Inject(ctx, req.Header)
+	slog.DebugContext(ctx, "Sending completion request", "model", requestBody.Model, "messages", len(requestBody.Messages), "stream", requestBody.Stream)
+	return req, nil
+}
+
+// traced runs call in a groq_request span.
+func (g *Groq) traced(ctx context.Context, stream bool, opts []Option, call func(ctx context.Context) (Completion, error)) (Completion, error) {
+	ctx, span := tracing.Start(ctx, "groq_request", tracing.Client)
+	defer span.End()
+	span.Set("server.address", strings.TrimPrefix(strings.TrimPrefix(g.BaseURL, "https://"), "http://"))
+	span.Set("gen_ai.request.model", NewRequestBody(nil, opts...).Model)
+	span.Set("stream", stream)
+	res, err := call(ctx)
+	span.Set("gen_ai.response.model", res.Model)
+	span.Set("gen_ai.usage.input_tokens", res.PromptTokens)
+	span.Set("gen_ai.usage.output_tokens", res.CompletionTokens)
+	span.Fail(err)
+	return res, err
+}
+
+func (g *Groq) Complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error) {
+	return g.traced(ctx, false, opts, func(ctx context.Context) (Completion, error) {
+		return g.complete(ctx, apiKey, messages, opts...)
+	})
+}
+
+func (g *Groq) complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error) {
+	req, err := g.newRequest(ctx, apiKey, NewRequestBody(messages, opts...))
+	if err != nil {
+		return Completion{}, err
+	}
+
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return Completion{}, fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+	slog.DebugContext(ctx, "Completion response", "status", resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Completion{}, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Completion{}, responseError(resp, body)
+	}
+
+	var responseBody struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content   string     `json:"content"`
+				Reasoning string     `json:"reasoning"`
+				ToolCalls []ToolCall `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+
+	err = json.Unmarshal(body, &responseBody)
+	if err != nil {
+		return Completion{}, fmt.Errorf("Error unmarshaling response: %v", err)
+	}
+
+	if len(responseBody.Choices) == 0 {
+		return Completion{}, fmt.Errorf("No message found in the response")
+	}
+
+	message := responseBody.Choices[0].Message
+	res := Completion{
+		Content:          message.Content,
+		Reasoning:        message.Reasoning,
+		Model:            responseBody.Model,
+		PromptTokens:     responseBody.Usage.PromptTokens,
+		CompletionTokens: responseBody.Usage.CompletionTokens,
+		ToolCalls:        message.ToolCalls,
+	}
+	if res.Reasoning == "" {
+		res.Reasoning, res.Content = SplitThinking(res.Content)
+	}
+	return res, nil
+}
+
+type streamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string          `json:"content"`
+			Reasoning string          `json:"reasoning"`
+			ToolCalls []toolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *streamUsage `json:"usage"`
+	XGroq *struct {
+		Usage *streamUsage `json:"usage"`
+	} `json:"x_groq"`
+}
+
+// toolCallDelta is a piece of a streamed tool call, the pieces with the
+// same Index make up one call.
+type toolCallDelta struct {
+	Index int `json:"index"`
+	ToolCall
+}
+
+type streamUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (g *Groq) Stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error) {
+	return g.traced(ctx, true, opts, func(ctx context.Context) (Completion, error) {
+		return g.stream(ctx, apiKey, messages, onDelta, opts...)
+	})
+}
+
+func (g *Groq) stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error) {
+	requestBody := NewRequestBody(messages, opts...)
+	requestBody.Stream = true
+
+	req, err := g.newRequest(ctx, apiKey, requestBody)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Completion{}, ctx.Err()
+		}
+		return Completion{}, fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+	slog.DebugContext(ctx, "Completion response", "status", resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return Completion{}, responseError(resp, body)
+	}
+
+	var res Completion
+	var content, reasoning strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return res, fmt.Errorf("Error unmarshaling response: %v", err)
+		}
+		if chunk.Model != "" {
+			res.Model = chunk.Model
+		}
+		usage := chunk.Usage
+		if usage == nil && chunk.XGroq != nil {
+			usage = chunk.XGroq.Usage
+		}
+		if usage != nil {
+			res.PromptTokens = usage.PromptTokens
+			res.CompletionTokens = usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			reasoning.WriteString(choice.Delta.Reasoning)
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+			for _, d := range choice.Delta.ToolCalls {
+				if d.Index < 0 {
+					continue
+				}
+				for len(res.ToolCalls) <= d.Index {
+					res.ToolCalls = append(res.ToolCalls, ToolCall{})
+				}
+				call := &res.ToolCalls[d.Index]
+				if d.ID != "" {
+					call.ID = d.ID
+				}
+				if d.Type != "" {
+					call.Type = d.Type
+				}
+				call.Function.Name += d.Function.Name
+				call.Function.Arguments += d.Function.Arguments
+			}
+		}
+	}
+	res.Content, res.Reasoning = content.String(), reasoning.String()
+	if res.Reasoning == "" {
+		res.Reasoning, res.Content = SplitThinking(res.Content)
+	}
+
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return res, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	return res, nil
+}
+
+func (g *Groq) CheckKey(ctx context.Context, apiKey string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.BaseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return responseError(resp, body)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/llm/llmtest"
+	"github.com/musaubrian/groqy/internal/tracing"
+)
+
+// serve starts a fake API answering chat completions with handle, and
+// returns a client for it.
+func serve(t *testing.T, handle http.HandlerFunc) *llm.Groq {
+	t.Helper()
+	srv := httptest.NewServer(handle)
+	t.Cleanup(srv.Close)
+	return &llm.Groq{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusBadRequest, `{"error":{"message":"context too long","type":"invalid_request_error"}}`, llm.ErrBadRequest},
+		{http.StatusUnprocessableEntity, `not json`, llm.ErrBadRequest},
+		{http.StatusUnauthorized, `{"error":{"message":"Invalid API Key"}}`, llm.ErrInvalidKey},
+		{http.StatusNotFound, `{"error":{"message":"no such model","code":"model_not_found"}}`, llm.ErrModelNotFound},
+		{http.StatusServiceUnavailable, `{"error":{"message":"over capacity"}}`, llm.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			g := serve(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req_1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			_, err := g.Complete(context.Background(), llmtest.Key, []llm.Message{{Role: "user", Content: "hi"}})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			var apiErr *llm.APIError
+			if !errors.As(err, &apiErr) || apiErr.RequestID != "req_1" {
+				t.Errorf("got %#v, want an APIError with the request id", err)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	g := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	_, err := g.Complete(context.Background(), llmtest.Key, nil)
+	var limited *llm.RateLimitError
+	if !errors.As(err, &limited) || limited.RetryAfter.Seconds() != 7 {
+		t.Fatalf("got %v, want a rate limit of 7s", err)
+	}
+}
+
+// flaky answers with statuses in turn, then with ok.
+func flaky(calls *atomic.Int32, statuses ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		var body llm.RequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]any{
+			"model":   body.Model,
+			"choices": []any{map[string]any{"message": map[string]string{"content": "ok"}}},
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		// calls is how many requests the primary gets.
+		calls    int
+		fallback bool
+		want     error
+	}{
+		{name: "unavailable once", statuses: []int{503}, calls: 2},
+		{name: "rate limited twice", statuses: []int{429, 429}, calls: 2, fallback: true},
+		{name: "bad request", statuses: []int{400}, calls: 1, want: llm.ErrBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryCalls, fallbackCalls atomic.Int32
+			primary := serve(t, flaky(&primaryCalls, tt.statuses...))
+			fallback := serve(t, flaky(&fallbackCalls))
+			f := llm.NewFailover(primary, "groq", []llm.Route{{Provider: "groq", Model: "backup", Client: fallback}})
+
+			res, err := f.Complete(context.Background(), llmtest.Key, nil, llm.WithModel("main"))
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+			} else if err != nil || res.Content != "ok" {
+				t.Fatalf("got %q, %v, want ok", res.Content, err)
+			}
+			if got := int(primaryCalls.Load()); got != tt.calls {
+				t.Errorf("primary got %d requests, want %d", got, tt.calls)
+			}
+			if res.Fallback != tt.fallback || (fallbackCalls.Load() > 0) != tt.fallback {
+				t.Errorf("fallback answered: %v after %d requests, want %v", res.Fallback, fallbackCalls.Load(), tt.fallback)
+			}
+			if tt.fallback && res.Model != "backup" {
+				t.Errorf("answered by %s, want backup", res.Model)
+			}
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	srv := llmtest.NewServer(func(llm.RequestBody) string { return "one two three" })
+	defer srv.Close()
+
+	var deltas []string
+	res, err := llmtest.NewClient(srv).Stream(context.Background(), llmtest.Key, []llm.Message{{Role: "user", Content: "count"}}, func(d string) {
+		deltas = append(deltas, d)
+	}, llm.WithModel("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "one two three" || strings.Join(deltas, "") != res.Content || len(deltas) != 3 {
+		t.Errorf("got %q in %q, want one two three word by word", res.Content, deltas)
+	}
+	if res.Model != "m" || res.PromptTokens != 1 || res.CompletionTokens != 3 {
+		t.Errorf("got model %q with %d+%d tokens, want m with 1+3", res.Model, res.PromptTokens, res.CompletionTokens)
+	}
+}
+
+func TestStreamThinking(t *testing.T) {
+	srv := llmtest.NewServer(func(llm.RequestBody) string { return "<think>hmm</think>answer" })
+	defer srv.Close()
+
+	res, err := llmtest.NewClient(srv).Stream(context.Background(), llmtest.Key, nil, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reasoning != "hmm" || res.Content != "answer" {
+		t.Errorf("got reasoning %q and content %q", res.Reasoning, res.Content)
+	}
+}
+
+var weather = llm.NewTool("weather", "Get the weather", json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`))
+
+func TestToolCalls(t *testing.T) {
+	g := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		var body llm.RequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Tools) != 1 || body.Tools[0].Function.Name != "weather" {
+			http.Error(w, "tools missing", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"model":"m","choices":[{"message":{"content":"","tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Nairobi\"}"}}]}}]}`))
+	})
+	res, err := g.Complete(context.Background(), llmtest.Key, nil, llm.WithTools(weather))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(res.ToolCalls))
+	}
+	call := res.ToolCalls[0]
+	if call.ID != "call_1" || call.Function.Name != "weather" || call.Function.Arguments != `{"city":"Nairobi"}` {
+		t.Errorf("got %+v", call)
+	}
+}
+
+func TestStreamToolCalls(t *testing.T) {
+	g := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Nairobi\"}"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"weather","arguments":"{}"}}]}}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	res, err := g.Stream(context.Background(), llmtest.Key, nil, func(string) {}, llm.WithTools(weather))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(res.ToolCalls))
+	}
+	if call := res.ToolCalls[0]; call.ID != "call_1" || call.Function.Arguments != `{"city":"Nairobi"}` {
+		t.Errorf("pieces of the first call put together as %+v", call)
+	}
+	if res.ToolCalls[1].ID != "call_2" {
+		t.Errorf("second call is %+v", res.ToolCalls[1])
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	tracing.Enable(tracing.NewExporter("http://127.0.0.1:0", "groqy", nil))
+	t.Cleanup(func() { tracing.Enable(nil) })
+
+	var got string
+	g := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	})
+	const caller = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := tracing.Extract(context.Background(), http.Header{"Traceparent": {caller}})
+	if _, err := g.Complete(ctx, llmtest.Key, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Same trace, under the groq_request span rather than the caller's.
+	if !strings.HasPrefix(got, "00-0af7651916cd43dd8448eb211c80319c-") || got == caller || !strings.HasSuffix(got, "-01") {
+		t.Errorf("sent traceparent %q, want the caller's trace with a span of our own", got)
+	}
+}
@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ImageGenerator turns a prompt into an image, returned as encoded bytes.
+type ImageGenerator interface {
+	GenerateImage(ctx context.Context, prompt string) ([]byte, error)
+}
+
+// OpenAIImages calls an OpenAI-compatible images endpoint.
+type OpenAIImages struct {
+	URL        string
+	Model      string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func NewOpenAIImages(url, model, token string) *OpenAIImages {
+	return &OpenAIImages{URL: url, Model: model, Token: token, HTTPClient: http.DefaultClient}
+}
+
+func (o *OpenAIImages) GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
+	jsonBody, err := json.Marshal(map[string]any{
+		"model":           o.Model,
+		"prompt":          prompt,
+		"n":               1,
+		"response_format": "b64_json",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling JSON:\n%v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.URL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request:\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.Token)
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Image request failed with %s: %s", resp.Status, body)
+	}
+
+	var responseBody struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &responseBody); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling response: %v", err)
+	}
+	if len(responseBody.Data) == 0 {
+		return nil, fmt.Errorf("No image found in the response")
+	}
+	return base64.StdEncoding.DecodeString(responseBody.Data[0].B64JSON)
+}
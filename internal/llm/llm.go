@@ -0,0 +1,139 @@
+// Package llm talks to OpenAI-compatible chat completion APIs, Groq by
+// default.
+package llm
+
+import "context"
+
+const (
+	DefaultModel   = "llama-3.1-8b-instant"
+	DefaultBaseURL = "https://api.groq.com/openai/v1"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are set on assistant messages that called tools, ToolCallID
+	// on the "tool" messages answering them.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Images are data URLs sent along with Content to vision models.
+	Images []string `json:"-"`
+}
+
+type RequestBody struct {
+	Messages    []Message `json:"messages"`
+	Model       string    `json:"model"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens"`
+	TopP        float64   `json:"top_p"`
+	Stream      bool      `json:"stream"`
+	// Stop ends the answer at the first of up to four sequences.
+	Stop []string `json:"stop,omitempty"`
+	// FrequencyPenalty and PresencePenalty, from -2 to 2, make the model
+	// repeat itself less or more.
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	// Seed makes sampling repeatable, as far as the model allows.
+	Seed  *int   `json:"seed,omitempty"`
+	Tools []Tool `json:"tools,omitempty"`
+	// ResponseFormat set to json_object makes the model answer with JSON.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// ReasoningFormat and ReasoningEffort are only sent to reasoning
+	// models.
+	ReasoningFormat string `json:"reasoning_format,omitempty"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type Completion struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	// ToolCalls are the tools the model wants run before it answers.
+	ToolCalls []ToolCall
+	// Fallback is set when a fallback model answered instead of the one
+	// asked for.
+	Fallback bool
+	// Reasoning is what a reasoning model thought before answering.
+	Reasoning string
+}
+
+// Client sends chat completions, billed to apiKey.
+type Client interface {
+	Complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error)
+	// Stream calls onDelta with each piece of content as it arrives. When
+	// ctx is cancelled it returns what was produced so far along with ctx's
+	// error.
+	Stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error)
+	// CheckKey reports whether apiKey is accepted by the API.
+	CheckKey(ctx context.Context, apiKey string) error
+}
+
+// Option tweaks the request body before it is sent.
+type Option func(*RequestBody)
+
+func WithTemperature(t float64) Option {
+	return func(r *RequestBody) {
+		r.Temperature = t
+	}
+}
+
+func WithModel(model string) Option {
+	return func(r *RequestBody) {
+		r.Model = model
+	}
+}
+
+// WithStop ends answers at the first of the sequences.
+func WithStop(sequences ...string) Option {
+	return func(r *RequestBody) {
+		r.Stop = sequences
+	}
+}
+
+func WithFrequencyPenalty(p float64) Option {
+	return func(r *RequestBody) {
+		r.FrequencyPenalty = &p
+	}
+}
+
+func WithPresencePenalty(p float64) Option {
+	return func(r *RequestBody) {
+		r.PresencePenalty = &p
+	}
+}
+
+func WithSeed(seed int) Option {
+	return func(r *RequestBody) {
+		r.Seed = &seed
+	}
+}
+
+// WithJSON asks for a JSON object answer. The prompt has to mention JSON
+// too, and Groq doesn't stream these.
+func WithJSON() Option {
+	return func(r *RequestBody) {
+		r.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+}
+
+func NewRequestBody(messages []Message, opts ...Option) RequestBody {
+	requestBody := RequestBody{
+		Messages:    messages,
+		Model:       DefaultModel,
+		Temperature: 0.5,
+		MaxTokens:   DefaultMaxTokens,
+		TopP:        1,
+		Stream:      false,
+		Stop:        nil,
+	}
+	for _, opt := range opts {
+		opt(&requestBody)
+	}
+	requestBody.fitReasoning()
+	return requestBody
+}
@@ -0,0 +1,94 @@
+// Package llmtest provides a fake Groq API for tests.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/musaubrian/groqy/internal/llm"
+)
+
+// Key is the only API key the fake server accepts.
+const Key = "test-key"
+
+// NewServer starts a fake OpenAI-compatible API that answers every chat
+// completion with reply(request), streaming it word by word when asked to.
+// Point llm.Groq's BaseURL at the returned server's URL.
+func NewServer(reply func(llm.RequestBody) string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/models", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			http.Error(w, `{"error":{"message":"Invalid API Key"}}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	})
+	mux.HandleFunc("/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			http.Error(w, `{"error":{"message":"Invalid API Key"}}`, http.StatusUnauthorized)
+			return
+		}
+
+		var body llm.RequestBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content := reply(body)
+		promptTokens := 0
+		for _, m := range body.Messages {
+			promptTokens += len(strings.Fields(m.Content))
+		}
+		completionTokens := len(strings.Fields(content))
+
+		if !body.Stream {
+			json.NewEncoder(w).Encode(map[string]any{
+				"model": body.Model,
+				"choices": []any{
+					map[string]any{"message": map[string]string{"role": "assistant", "content": content}},
+				},
+				"usage": map[string]int{"prompt_tokens": promptTokens, "completion_tokens": completionTokens},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range strings.SplitAfter(content, " ") {
+			chunk := map[string]any{
+				"model":   body.Model,
+				"choices": []any{map[string]any{"delta": map[string]string{"content": word}}},
+			}
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		usage, _ := json.Marshal(map[string]any{
+			"model":   body.Model,
+			"choices": []any{},
+			"x_groq":  map[string]any{"usage": map[string]int{"prompt_tokens": promptTokens, "completion_tokens": completionTokens}},
+		})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", usage)
+	})
+	return httptest.NewServer(mux)
+}
+
+// Echo replies with the last user message.
+func Echo(body llm.RequestBody) string {
+	for i := len(body.Messages) - 1; i >= 0; i-- {
+		if body.Messages[i].Role == "user" {
+			return body.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// NewClient returns a Groq client for srv.
+func NewClient(srv *httptest.Server) *llm.Groq {
+	return &llm.Groq{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "Bearer "+Key
+}
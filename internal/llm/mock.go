@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"text/template"
+)
+
+// DefaultMockTemplate echoes the prompt back.
+const DefaultMockTemplate = "[mock {{.Model}}] {{.Prompt}}"
+
+// Mock is a Client that never leaves the process. Every completion is its
+// Template executed with the request, so handlers can be developed and
+// exercised offline.
+type Mock struct {
+	Template *template.Template
+}
+
+// MockData is what a Mock's template is executed with.
+type MockData struct {
+	// Prompt is the last user message.
+	Prompt   string
+	Model    string
+	Messages []Message
+}
+
+// NewMock parses tmpl, DefaultMockTemplate when it is empty.
+func NewMock(tmpl string) (*Mock, error) {
+	if tmpl == "" {
+		tmpl = DefaultMockTemplate
+	}
+	t, err := template.New("mock").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &Mock{Template: t}, nil
+}
+
+func (m *Mock) Complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error) {
+	body := NewRequestBody(messages, opts...)
+	data := MockData{Model: body.Model, Messages: messages}
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			data.Prompt = msg.Content
+		}
+	}
+
+	var sb strings.Builder
+	if err := m.Template.Execute(&sb, data); err != nil {
+		return Completion{}, err
+	}
+
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += len(strings.Fields(msg.Content))
+	}
+	return Completion{
+		Content:          sb.String(),
+		Model:            body.Model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: len(strings.Fields(sb.String())),
+	}, ctx.Err()
+}
+
+// Stream delivers the completion a word at a time.
+func (m *Mock) Stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error) {
+	res, err := m.Complete(ctx, apiKey, messages, opts...)
+	if err != nil {
+		return Completion{}, err
+	}
+	full := res.Content
+	res.Content = ""
+	for _, word := range strings.SplitAfter(full, " ") {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		res.Content += word
+		onDelta(word)
+	}
+	return res, nil
+}
+
+func (m *Mock) CheckKey(ctx context.Context, apiKey string) error {
+	return nil
+}
+
+// GenerateImage draws a square in a colour picked from the prompt.
+func (m *Mock) GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	sum := h.Sum32()
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = fill.R, fill.G, fill.B, fill.A
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), ctx.Err()
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model charges in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// DefaultPrices are Groq's list prices.
+var DefaultPrices = map[string]Price{
+	"llama-3.1-8b-instant":          {Input: 0.05, Output: 0.08},
+	"llama-3.1-70b-versatile":       {Input: 0.59, Output: 0.79},
+	"llama-3.3-70b-versatile":       {Input: 0.59, Output: 0.79},
+	"llama-guard-3-8b":              {Input: 0.20, Output: 0.20},
+	"gemma2-9b-it":                  {Input: 0.20, Output: 0.20},
+	"mixtral-8x7b-32768":            {Input: 0.24, Output: 0.24},
+	"deepseek-r1-distill-llama-70b": {Input: 0.75, Output: 0.99},
+	"qwen-qwq-32b":                  {Input: 0.29, Output: 0.39},
+	"llama-3.2-11b-vision-preview":  {Input: 0.18, Output: 0.18},
+}
+
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// ParsePrices reads `model=input/output` pairs separated by commas, e.g.
+// `llama-3.1-8b-instant=0.05/0.08`, on top of DefaultPrices.
+func ParsePrices(s string) (map[string]Price, error) {
+	prices := map[string]Price{}
+	for model, p := range DefaultPrices {
+		prices[model] = p
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(pair, "=")
+		input, output, ok2 := strings.Cut(rates, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("can't read price %q, use model=input/output", pair)
+		}
+		in, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't read price %q: %v", pair, err)
+		}
+		out, err := strconv.ParseFloat(output, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't read price %q: %v", pair, err)
+		}
+		prices[strings.TrimSpace(model)] = Price{Input: in, Output: out}
+	}
+	return prices, nil
+}
@@ -0,0 +1,86 @@
+package llm
+
+import "strings"
+
+// ReasoningMaxTokens leaves reasoning models room to think before they
+// answer, DefaultMaxTokens being mostly spent on thinking.
+const ReasoningMaxTokens = 4096
+
+// Reasoning efforts users can pick, mapped onto what each model takes.
+const (
+	EffortLow    = "low"
+	EffortMedium = "medium"
+	EffortHigh   = "high"
+)
+
+// reasoningModels are the prefixes of models that think before answering,
+// with how they map the efforts. A nil map means the model has no
+// reasoning_effort setting.
+var reasoningModels = map[string]map[string]string{
+	"deepseek-r1":    nil,
+	"qwen-qwq":       nil,
+	"qwen/qwen3":     {EffortLow: "none", EffortMedium: "default", EffortHigh: "default"},
+	"openai/gpt-oss": {EffortLow: "low", EffortMedium: "medium", EffortHigh: "high"},
+}
+
+// MaxTokens is the default answer budget of model.
+func MaxTokens(model string) int {
+	if Reasons(model) {
+		return ReasoningMaxTokens
+	}
+	return DefaultMaxTokens
+}
+
+// Reasons reports whether model is a reasoning model.
+func Reasons(model string) bool {
+	_, ok := reasoningEfforts(model)
+	return ok
+}
+
+func reasoningEfforts(model string) (map[string]string, bool) {
+	for prefix, efforts := range reasoningModels {
+		if strings.HasPrefix(model, prefix) {
+			return efforts, true
+		}
+	}
+	return nil, false
+}
+
+// WithReasoning asks reasoning models for their reasoning in
+// Completion.Reasoning instead of the content, thinking with effort when
+// the model can be told how hard to think. Other models ignore it.
+func WithReasoning(effort string) Option {
+	return func(r *RequestBody) {
+		r.ReasoningFormat = "parsed"
+		r.ReasoningEffort = effort
+	}
+}
+
+// fitReasoning drops the reasoning settings the model doesn't take and
+// translates the effort to its values.
+func (r *RequestBody) fitReasoning() {
+	efforts, ok := reasoningEfforts(r.Model)
+	if !ok {
+		r.ReasoningFormat, r.ReasoningEffort = "", ""
+		return
+	}
+	r.ReasoningEffort = efforts[r.ReasoningEffort]
+	if r.MaxTokens == DefaultMaxTokens {
+		r.MaxTokens = ReasoningMaxTokens
+	}
+}
+
+// SplitThinking separates a leading <think> section, which reasoning
+// models write when their reasoning isn't parsed out, from the answer.
+func SplitThinking(content string) (reasoning, answer string) {
+	rest, ok := strings.CutPrefix(strings.TrimLeft(content, " \n"), "<think>")
+	if !ok {
+		return "", content
+	}
+	reasoning, answer, ok = strings.Cut(rest, "</think>")
+	if !ok {
+		// Still thinking.
+		return strings.TrimSpace(rest), ""
+	}
+	return strings.TrimSpace(reasoning), strings.TrimLeft(answer, " \n")
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SpeechSynthesizer reads text out, returning Ogg Opus audio.
+type SpeechSynthesizer interface {
+	Speak(ctx context.Context, text string) ([]byte, error)
+}
+
+// OpenAISpeech calls an OpenAI-compatible text-to-speech endpoint.
+type OpenAISpeech struct {
+	URL        string
+	Model      string
+	Voice      string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func NewOpenAISpeech(url, model, voice, token string) *OpenAISpeech {
+	return &OpenAISpeech{URL: url, Model: model, Voice: voice, Token: token, HTTPClient: http.DefaultClient}
+}
+
+func (o *OpenAISpeech) Speak(ctx context.Context, text string) ([]byte, error) {
+	jsonBody, err := json.Marshal(map[string]string{
+		"model":           o.Model,
+		"voice":           o.Voice,
+		"input":           text,
+		"response_format": "opus",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error marshaling JSON:\n%v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.URL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request:\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.Token)
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Speech request failed with %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxTokens is the completion length asked for unless an option
+// changes it.
+const DefaultMaxTokens = 1024
+
+// messageOverhead is what the chat format adds around each message.
+const messageOverhead = 4
+
+// ContextWindows are the context lengths of the models users can pick.
+// Others are assumed to have defaultContextWindow.
+var ContextWindows = map[string]int{
+	"llama-3.1-8b-instant":          131072,
+	"llama-3.1-70b-versatile":       131072,
+	"llama-3.3-70b-versatile":       131072,
+	"llama-guard-3-8b":              8192,
+	"gemma2-9b-it":                  8192,
+	"mixtral-8x7b-32768":            32768,
+	"deepseek-r1-distill-llama-70b": 131072,
+	"qwen-qwq-32b":                  131072,
+	"llama-3.2-11b-vision-preview":  8192,
+}
+
+const defaultContextWindow = 8192
+
+func ContextWindow(model string) int {
+	if n, ok := ContextWindows[model]; ok {
+		return n
+	}
+	return defaultContextWindow
+}
+
+// EstimateTokens approximates how a BPE tokenizer like tiktoken splits s:
+// a token per four letters of a word, rounded up, one per punctuation
+// mark and one per character of scripts without spaces. It errs on the
+// high side for English.
+func EstimateTokens(s string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimateMessages approximates the prompt tokens of a request.
+func EstimateMessages(messages []Message) int {
+	n := 3
+	for _, m := range messages {
+		n += messageOverhead + EstimateTokens(m.Content)
+		for _, call := range m.ToolCalls {
+			n += EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+		}
+	}
+	return n
+}
@@ -0,0 +1,39 @@
+package llm
+
+import "encoding/json"
+
+// Tool is a function the model may call instead of answering.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is the JSON schema of the function's arguments.
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is the model asking for a tool to be run with Arguments, a JSON
+// object. The result goes back in a message with the "tool" role and the
+// call's ID.
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+func NewTool(name, description string, parameters json.RawMessage) Tool {
+	return Tool{Type: "function", Function: ToolFunction{Name: name, Description: description, Parameters: parameters}}
+}
+
+// WithTools lets the model call tools, leaving it to decide when.
+func WithTools(tools ...Tool) Option {
+	return func(r *RequestBody) {
+		r.Tools = tools
+	}
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// trackedSamples is how many of a model's latest requests its stats
+	// are made of, and trackedAge how old they may be.
+	trackedSamples = 20
+	trackedAge     = 15 * time.Minute
+)
+
+// ModelStats are how a model's recent requests went.
+type ModelStats struct {
+	// Latency is the mean time the successful ones took.
+	Latency   time.Duration
+	ErrorRate float64
+	Samples   int
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// Tracker is a Client that keeps a rolling window of each model's latency
+// and errors.
+type Tracker struct {
+	next Client
+
+	mu      sync.Mutex
+	samples map[string][]sample
+}
+
+func NewTracker(next Client) *Tracker {
+	return &Tracker{next: next, samples: map[string][]sample{}}
+}
+
+func (t *Tracker) Complete(ctx context.Context, apiKey string, messages []Message, opts ...Option) (Completion, error) {
+	start := time.Now()
+	res, err := t.next.Complete(ctx, apiKey, messages, opts...)
+	t.record(res, err, opts, time.Since(start))
+	return res, err
+}
+
+func (t *Tracker) Stream(ctx context.Context, apiKey string, messages []Message, onDelta func(string), opts ...Option) (Completion, error) {
+	start := time.Now()
+	res, err := t.next.Stream(ctx, apiKey, messages, onDelta, opts...)
+	t.record(res, err, opts, time.Since(start))
+	return res, err
+}
+
+func (t *Tracker) CheckKey(ctx context.Context, apiKey string) error {
+	return t.next.CheckKey(ctx, apiKey)
+}
+
+// record counts the request against the model that answered it. Cancelled
+// requests and rejected keys say nothing about the model and aren't
+// counted.
+func (t *Tracker) record(res Completion, err error, opts []Option, elapsed time.Duration) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrInvalidKey) {
+		return
+	}
+	model := res.Model
+	if model == "" || err != nil {
+		model = NewRequestBody(nil, opts...).Model
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples := append(t.samples[model], sample{at: now, latency: elapsed, failed: err != nil})
+	if len(samples) > trackedSamples {
+		samples = samples[len(samples)-trackedSamples:]
+	}
+	t.samples[model] = samples
+}
+
+// Stats returns how the model's requests in the window went, no samples
+// when it has none.
+func (t *Tracker) Stats(model string) ModelStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var stats ModelStats
+	var total time.Duration
+	failed, succeeded := 0, 0
+	for _, s := range t.samples[model] {
+		if time.Since(s.at) > trackedAge {
+			continue
+		}
+		if s.failed {
+			failed++
+		} else {
+			succeeded++
+			total += s.latency
+		}
+	}
+	stats.Samples = failed + succeeded
+	if stats.Samples > 0 {
+		stats.ErrorRate = float64(failed) / float64(stats.Samples)
+	}
+	if succeeded > 0 {
+		stats.Latency = total / time.Duration(succeeded)
+	}
+	return stats
+}
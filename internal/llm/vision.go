@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// DefaultVisionModel is used for messages with images unless configured
+// otherwise.
+const DefaultVisionModel = "llama-3.2-11b-vision-preview"
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// ImageURL inlines an image as a data URL for Message.Images.
+func ImageURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// MarshalJSON sends messages with images as a list of content parts, the
+// way vision models take them.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+
+	parts := []contentPart{{Type: "text", Text: m.Content}}
+	for _, url := range m.Images {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), parts})
+}
@@ -0,0 +1,133 @@
+// Package sandbox runs untrusted Python and Go snippets in throwaway Docker
+// containers with no network, a read-only filesystem and tight limits.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// maxOutput is how much of stdout and stderr is kept each.
+const maxOutput = 8 << 10
+
+var ErrUnsupported = errors.New("unsupported language")
+
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+}
+
+type Runner struct {
+	// Images maps a language to the image its code runs in.
+	Images  map[string]string
+	Timeout time.Duration
+	// Memory is the container's memory limit in Docker's notation, e.g. 128m.
+	Memory string
+}
+
+func NewRunner(pythonImage, goImage string, timeout time.Duration) *Runner {
+	return &Runner{
+		Images:  map[string]string{"python": pythonImage, "go": goImage},
+		Timeout: timeout,
+		Memory:  "256m",
+	}
+}
+
+// commands read the code from stdin and run it.
+var commands = map[string]string{
+	"python": "python3 -",
+	"go":     "cat > /tmp/main.go && cd /tmp && go run main.go",
+}
+
+// Run runs code written in language and returns what it printed. Code that
+// fails or runs out of time is not an error, only failing to run it is.
+func (r *Runner) Run(ctx context.Context, language, code string) (Result, error) {
+	image, ok := r.Images[language]
+	if !ok {
+		return Result{}, fmt.Errorf("%w %q", ErrUnsupported, language)
+	}
+
+	name := "groqy-sandbox-" + strings.ToLower(ulid.Make().String())
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	cmd := exec.Command("docker", "run", "--rm", "-i",
+		"--name", name,
+		"--network", "none",
+		"--read-only",
+		"--tmpfs", "/tmp:rw,exec,size=256m",
+		"--memory", r.Memory,
+		"--memory-swap", r.Memory,
+		"--cpus", "1",
+		"--pids-limit", "64",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--user", "65534:65534",
+		"--env", "HOME=/tmp",
+		"--env", "GOCACHE=/tmp/.cache",
+		image, "sh", "-c", commands[language])
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr limitedBuffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("could not start docker: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var res Result
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Killing the docker client would leave the container running.
+		exec.Command("docker", "rm", "-f", name).Run()
+		err = <-done
+		res.TimedOut = true
+	}
+
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitCode()
+		// 125 is docker itself failing, not the code.
+		if res.ExitCode == 125 && !res.TimedOut {
+			return res, fmt.Errorf("docker: %s", strings.TrimSpace(res.Stderr))
+		}
+	} else if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutput - b.Len(); room < len(p) {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.Buffer.String() + "\n[output truncated]"
+	}
+	return b.Buffer.String()
+}
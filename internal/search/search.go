@@ -0,0 +1,165 @@
+// Package search looks things up on the web through SearxNG, Brave or
+// Serper.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// Searcher returns up to n results for query.
+type Searcher interface {
+	Search(ctx context.Context, query string, n int) ([]Result, error)
+}
+
+// New returns the searcher for provider, one of searxng, brave or serper.
+// baseURL is the SearxNG instance, token the Brave or Serper API key.
+func New(provider, baseURL, token string) (Searcher, error) {
+	switch provider {
+	case "searxng":
+		if baseURL == "" {
+			return nil, fmt.Errorf("searxng needs SEARCH_URL")
+		}
+		return &SearxNG{URL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}, nil
+	case "brave":
+		return &Brave{Token: token, HTTPClient: http.DefaultClient}, nil
+	case "serper":
+		return &Serper{Token: token, HTTPClient: http.DefaultClient}, nil
+	}
+	return nil, fmt.Errorf("unknown search provider %q", provider)
+}
+
+// SearxNG searches a SearxNG instance with the JSON format enabled.
+type SearxNG struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func (s *SearxNG) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL+"/search?"+url.Values{"q": {query}, "format": {"json"}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := do(s.HTTPClient, req, &body); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range body.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return first(results, n), nil
+}
+
+// Brave searches with the Brave Search API.
+type Brave struct {
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (b *Brave) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	q := url.Values{"q": {query}, "count": {strconv.Itoa(n)}}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.search.brave.com/res/v1/web/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.Token)
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := do(b.HTTPClient, req, &body); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range body.Web.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return first(results, n), nil
+}
+
+// Serper searches Google through serper.dev.
+type Serper struct {
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (s *Serper) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	jsonBody, err := json.Marshal(map[string]any{"q": query, "num": n})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://google.serper.dev/search", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", s.Token)
+	var body struct {
+		Organic []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic"`
+	}
+	if err := do(s.HTTPClient, req, &body); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range body.Organic {
+		results = append(results, Result{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return first(results, n), nil
+}
+
+func do(client *http.Client, req *http.Request, dest any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request:\n%v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Search request failed with %s: %s", resp.Status, body)
+	}
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("Error unmarshaling response: %v", err)
+	}
+	return nil
+}
+
+func first(results []Result, n int) []Result {
+	if n > 0 && len(results) > n {
+		return results[:n]
+	}
+	return results
+}
@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+func (d *DB) SaveAPIKey(userID int64, ciphertext []byte) error {
+	_, err := d.exec(`INSERT INTO api_keys(user_id, ciphertext, created_at) VALUES(?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET ciphertext=excluded.ciphertext, created_at=excluded.created_at`, userID, ciphertext, time.Now())
+	return err
+}
+
+func (d *DB) GetAPIKey(userID int64) ([]byte, error) {
+	var ciphertext []byte
+	err := d.get(&ciphertext, "SELECT ciphertext FROM api_keys WHERE user_id=?", userID)
+	return ciphertext, err
+}
+
+func (d *DB) DeleteAPIKey(userID int64) error {
+	_, err := d.exec("DELETE FROM api_keys WHERE user_id=?", userID)
+	return err
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type AuditEntry struct {
+	ID        string    `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Username  string    `db:"username"`
+	Prompt    string    `db:"prompt"`
+	Response  string    `db:"response"`
+	Model     string    `db:"model"`
+	LatencyMS int64     `db:"latency_ms"`
+	Status    string    `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveAuditEntry(e AuditEntry) error {
+	e.ID = ulid.Make().String()
+	e.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO audit_log(id, user_id, username, prompt, response, model, latency_ms, status, created_at)
+VALUES(:id, :user_id, :username, :prompt, :response, :model, :latency_ms, :status, :created_at)`, e)
+	return err
+}
+
+func (d *DB) GetAuditEntries(username string, limit int) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := d.selectAll(&entries, "SELECT * FROM audit_log WHERE username=? ORDER BY created_at DESC LIMIT ?", username, limit)
+	return entries, err
+}
+
+func (d *DB) DeleteAuditEntriesBefore(t time.Time) (int64, error) {
+	res, err := d.exec("DELETE FROM audit_log WHERE created_at < ?", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
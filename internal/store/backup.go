@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBackupUnsupported is returned for Postgres, which has pg_dump for
+// backups.
+var ErrBackupUnsupported = errors.New("backups are only supported on SQLite")
+
+// sqliteHeader starts every SQLite database file.
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// Backup writes a consistent snapshot of the database to path, a file that
+// doesn't exist yet, while the bot keeps running.
+func (d *DB) Backup(ctx context.Context, path string) error {
+	if d.dialect != sqlite {
+		return ErrBackupUnsupported
+	}
+	if d.driver == sqliteDrivers[PureGoSQLite] {
+		_, err := d.db.ExecContext(ctx, "VACUUM INTO ?", path)
+		return err
+	}
+	return d.onlineBackup(ctx, path)
+}
+
+// Restore replaces the SQLite database of databaseURL with the backup at
+// path, keeping the old one next to it with a .before-restore suffix. It
+// has to run before the database is opened.
+func Restore(databaseURL, path string) error {
+	target, ok := sqlitePath(databaseURL)
+	if !ok {
+		return ErrBackupUnsupported
+	}
+	target, _, _ = strings.Cut(target, "?")
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(src, header); err != nil || !bytes.Equal(header, sqliteHeader) {
+		return fmt.Errorf("%s is not a SQLite database", path)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// The old database's write-ahead log goes with it, so it isn't replayed
+	// onto the restored one.
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(target+suffix, target+".before-restore"+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), target)
+}
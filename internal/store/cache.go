@@ -0,0 +1,33 @@
+package store
+
+import "time"
+
+// CachedResponse is an answer kept for prompts that hash to PromptHash.
+type CachedResponse struct {
+	PromptHash string    `db:"prompt_hash"`
+	Model      string    `db:"model"`
+	Response   string    `db:"response"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveCachedResponse(r CachedResponse) error {
+	_, err := d.namedExec(`INSERT INTO response_cache(prompt_hash, model, response, created_at) VALUES(:prompt_hash, :model, :response, :created_at)
+ON CONFLICT(prompt_hash) DO UPDATE SET model=excluded.model, response=excluded.response, created_at=excluded.created_at`, r)
+	return err
+}
+
+// GetCachedResponse returns the answer cached for promptHash since the
+// given time, sql.ErrNoRows when there's none.
+func (d *DB) GetCachedResponse(promptHash string, since time.Time) (CachedResponse, error) {
+	var r CachedResponse
+	err := d.get(&r, "SELECT * FROM response_cache WHERE prompt_hash=? AND created_at>=?", promptHash, since)
+	return r, err
+}
+
+func (d *DB) DeleteCachedResponsesBefore(t time.Time) (int64, error) {
+	res, err := d.exec("DELETE FROM response_cache WHERE created_at<?", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
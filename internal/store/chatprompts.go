@@ -0,0 +1,56 @@
+package store
+
+import "time"
+
+// GetChatPrompt returns the system prompt set for a group chat,
+// sql.ErrNoRows when there is none.
+func (d *DB) GetChatPrompt(chatID int64) (string, error) {
+	var content string
+	err := d.get(&content, "SELECT content FROM chat_prompts WHERE chat_id=?", chatID)
+	return content, err
+}
+
+// SetChatPrompt sets the group chat's system prompt, userID being the
+// admin who set it.
+func (d *DB) SetChatPrompt(chatID int64, content string, userID int64) error {
+	_, err := d.exec(`INSERT INTO chat_prompts(chat_id, content, updated_by, updated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(chat_id) DO UPDATE SET content=excluded.content, updated_by=excluded.updated_by, updated_at=excluded.updated_at`, chatID, content, userID, time.Now())
+	return err
+}
+
+// DeleteChatPrompt reports whether the chat had a system prompt to delete.
+func (d *DB) DeleteChatPrompt(chatID int64) (bool, error) {
+	res, err := d.exec("DELETE FROM chat_prompts WHERE chat_id=?", chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetTopicPrompt returns the system prompt set for a forum topic of a
+// group, sql.ErrNoRows when there is none.
+func (d *DB) GetTopicPrompt(chatID int64, threadID int) (string, error) {
+	var content string
+	err := d.get(&content, "SELECT content FROM topic_prompts WHERE chat_id=? AND thread_id=?", chatID, threadID)
+	return content, err
+}
+
+// SetTopicPrompt sets the forum topic's system prompt, userID being the
+// admin who set it.
+func (d *DB) SetTopicPrompt(chatID int64, threadID int, content string, userID int64) error {
+	_, err := d.exec(`INSERT INTO topic_prompts(chat_id, thread_id, content, updated_by, updated_at) VALUES(?, ?, ?, ?, ?)
+ON CONFLICT(chat_id, thread_id) DO UPDATE SET content=excluded.content, updated_by=excluded.updated_by, updated_at=excluded.updated_at`, chatID, threadID, content, userID, time.Now())
+	return err
+}
+
+// DeleteTopicPrompt reports whether the topic had a system prompt to
+// delete.
+func (d *DB) DeleteTopicPrompt(chatID int64, threadID int) (bool, error) {
+	res, err := d.exec("DELETE FROM topic_prompts WHERE chat_id=? AND thread_id=?", chatID, threadID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
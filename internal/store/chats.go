@@ -0,0 +1,40 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Chat is one of a user's named conversations. Exchanges from before chats
+// existed, and those in the user's main chat, have an empty chat ID.
+type Chat struct {
+	ID        string    `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Title     string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (d *DB) CreateChat(userID int64, title string) (Chat, error) {
+	chat := Chat{ID: ulid.Make().String(), UserID: userID, Title: title, CreatedAt: time.Now()}
+	_, err := d.namedExec(`INSERT INTO chats(id, user_id, title, created_at) VALUES(:id, :user_id, :title, :created_at)`, chat)
+	return chat, err
+}
+
+func (d *DB) GetChat(userID int64, id string) (Chat, error) {
+	var chat Chat
+	err := d.get(&chat, "SELECT * FROM chats WHERE user_id=? AND id=?", userID, id)
+	return chat, err
+}
+
+// GetChats returns the user's chats, newest first.
+func (d *DB) GetChats(userID int64) ([]Chat, error) {
+	var chats []Chat
+	err := d.selectAll(&chats, "SELECT * FROM chats WHERE user_id=? ORDER BY created_at DESC", userID)
+	return chats, err
+}
+
+func (d *DB) SetChatTitle(id, title string) error {
+	_, err := d.exec("UPDATE chats SET title=? WHERE id=?", title, id)
+	return err
+}
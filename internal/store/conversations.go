@@ -0,0 +1,157 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type Exchange struct {
+	ID               string    `db:"id"`
+	UserID           int64     `db:"user_id"`
+	ChatID           string    `db:"chat_id"`
+	Prompt           string    `db:"prompt"`
+	Response         string    `db:"response"`
+	Model            string    `db:"model"`
+	PromptTokens     int       `db:"prompt_tokens"`
+	CompletionTokens int       `db:"completion_tokens"`
+	CreatedAt        time.Time `db:"created_at"`
+	// ParentID is the exchange this one followed, so replies to older bot
+	// messages can branch off from that point of the conversation.
+	ParentID string `db:"parent_id"`
+	// MessageID is the Telegram message the answer was sent as.
+	MessageID int `db:"message_id"`
+	// PromptMessageID is the user's message the prompt came from.
+	PromptMessageID int `db:"prompt_message_id"`
+	// Summarized exchanges have been folded into the user's summary and are
+	// no longer sent as context.
+	Summarized bool `db:"summarized"`
+	// Feedback is how the user rated the answer: 1 for 👍, -1 for 👎 and 0
+	// when they haven't.
+	Feedback int `db:"feedback"`
+}
+
+func (d *DB) SaveExchange(ex *Exchange) error {
+	ex.ID = ulid.Make().String()
+	ex.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO conversations(id, user_id, chat_id, prompt, response, model, prompt_tokens, completion_tokens, created_at, parent_id, message_id, prompt_message_id)
+VALUES(:id, :user_id, :chat_id, :prompt, :response, :model, :prompt_tokens, :completion_tokens, :created_at, :parent_id, :message_id, :prompt_message_id)`, ex)
+	return err
+}
+
+// RecentExchanges returns the last n unsummarized exchanges in one of the
+// user's chats, oldest first.
+func (d *DB) RecentExchanges(userID int64, chatID string, n int) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, `SELECT * FROM (
+	SELECT * FROM conversations WHERE user_id=? AND chat_id=? AND summarized=0 ORDER BY created_at DESC LIMIT ?
+) AS recent ORDER BY created_at`, userID, chatID, n)
+	return exchanges, err
+}
+
+// Thread returns up to n exchanges ending at id, following parent links
+// back, oldest first.
+func (d *DB) Thread(id string, n int) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, `WITH RECURSIVE thread(id, parent_id, depth) AS (
+	SELECT id, parent_id, 1 FROM conversations WHERE id=?
+	UNION ALL
+	SELECT c.id, c.parent_id, t.depth+1 FROM conversations c JOIN thread t ON c.id=t.parent_id WHERE t.depth < ?
+)
+SELECT conversations.* FROM conversations JOIN thread ON conversations.id=thread.id ORDER BY conversations.created_at`, id, n)
+	return exchanges, err
+}
+
+// ExchangeByMessage finds the exchange whose answer was sent as messageID.
+func (d *DB) ExchangeByMessage(userID int64, messageID int) (Exchange, error) {
+	var ex Exchange
+	err := d.get(&ex, "SELECT * FROM conversations WHERE user_id=? AND message_id=?", userID, messageID)
+	return ex, err
+}
+
+// SetFeedback rates the exchange whose answer was sent as messageID,
+// reporting whether there was one.
+func (d *DB) SetFeedback(userID int64, messageID int, feedback int) (bool, error) {
+	res, err := d.exec("UPDATE conversations SET feedback=? WHERE user_id=? AND message_id=?", feedback, userID, messageID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ExchangeInChats finds the exchange whose answer was sent as messageID in
+// any chat whose ID starts with chatIDPrefix, whoever asked.
+func (d *DB) ExchangeInChats(chatIDPrefix string, messageID int) (Exchange, error) {
+	var ex Exchange
+	err := d.get(&ex, "SELECT * FROM conversations WHERE chat_id LIKE ? AND message_id=?", chatIDPrefix+"%", messageID)
+	return ex, err
+}
+
+// LatestExchange returns the last exchange in the chat, whoever had it,
+// sql.ErrNoRows when there is none.
+func (d *DB) LatestExchange(chatID string) (Exchange, error) {
+	var ex Exchange
+	err := d.get(&ex, "SELECT * FROM conversations WHERE chat_id=? ORDER BY created_at DESC LIMIT 1", chatID)
+	return ex, err
+}
+
+// ExchangeByPrompt finds the exchange whose prompt was the user's message
+// messageID.
+func (d *DB) ExchangeByPrompt(userID int64, messageID int) (Exchange, error) {
+	var ex Exchange
+	err := d.get(&ex, "SELECT * FROM conversations WHERE user_id=? AND prompt_message_id=?", userID, messageID)
+	return ex, err
+}
+
+func (d *DB) SetExchangeMessage(id string, messageID int) error {
+	_, err := d.exec("UPDATE conversations SET message_id=? WHERE id=?", messageID, id)
+	return err
+}
+
+func (d *DB) AllExchanges(userID int64) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, "SELECT * FROM conversations WHERE user_id=? ORDER BY created_at", userID)
+	return exchanges, err
+}
+
+// ChatExchanges returns the user's exchanges in one of their chats made
+// up to until, summarized ones too, oldest first.
+func (d *DB) ChatExchanges(userID int64, chatID string, until time.Time) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, "SELECT * FROM conversations WHERE user_id=? AND chat_id=? AND created_at <= ? ORDER BY created_at", userID, chatID, until)
+	return exchanges, err
+}
+
+// ExchangesSince returns the user's exchanges from every chat made after
+// since, oldest first.
+func (d *DB) ExchangesSince(userID int64, since time.Time) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, "SELECT * FROM conversations WHERE user_id=? AND created_at > ? ORDER BY created_at", userID, since)
+	return exchanges, err
+}
+
+// RatedExchanges returns everyone's exchanges with feedback, oldest first.
+func (d *DB) RatedExchanges() ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, "SELECT * FROM conversations WHERE feedback<>0 ORDER BY created_at")
+	return exchanges, err
+}
+
+// ActiveUsers counts distinct users with an exchange since the given time.
+func (d *DB) ActiveUsers(since time.Time) (int, error) {
+	var n int
+	err := d.get(&n, "SELECT COUNT(DISTINCT user_id) FROM conversations WHERE created_at >= ?", since)
+	return n, err
+}
+
+func (d *DB) UpdateExchange(ex Exchange) error {
+	_, err := d.namedExec(`UPDATE conversations SET prompt=:prompt, response=:response, model=:model,
+prompt_tokens=:prompt_tokens, completion_tokens=:completion_tokens, feedback=:feedback WHERE id=:id`, ex)
+	return err
+}
+
+func (d *DB) DeleteExchange(id string) error {
+	_, err := d.exec("DELETE FROM conversations WHERE id=?", id)
+	return err
+}
@@ -0,0 +1,57 @@
+package store
+
+import "time"
+
+// Digest is a user's subscription to a daily summary of their
+// conversations, sent at Hour:Minute in Timezone.
+type Digest struct {
+	UserID   int64  `db:"user_id"`
+	Username string `db:"username"`
+	Hour     int    `db:"hour"`
+	Minute   int    `db:"minute"`
+	// Timezone is an IANA name like Europe/Berlin.
+	Timezone string `db:"timezone"`
+	// NextRun is kept in UTC, as the users' timezones differ.
+	NextRun   time.Time `db:"next_run"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SaveDigest subscribes the user, replacing the time of an earlier
+// subscription.
+func (d *DB) SaveDigest(dg Digest) error {
+	dg.NextRun = dg.NextRun.UTC()
+	dg.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO digests(user_id, username, hour, minute, timezone, next_run, created_at)
+VALUES(:user_id, :username, :hour, :minute, :timezone, :next_run, :created_at)
+ON CONFLICT(user_id) DO UPDATE SET username=excluded.username, hour=excluded.hour, minute=excluded.minute, timezone=excluded.timezone, next_run=excluded.next_run`, dg)
+	return err
+}
+
+// GetDigest returns the user's subscription, sql.ErrNoRows when they have
+// none.
+func (d *DB) GetDigest(userID int64) (Digest, error) {
+	var dg Digest
+	err := d.get(&dg, "SELECT * FROM digests WHERE user_id=?", userID)
+	return dg, err
+}
+
+// DeleteDigest unsubscribes the user, reporting whether they were.
+func (d *DB) DeleteDigest(userID int64) (bool, error) {
+	res, err := d.exec("DELETE FROM digests WHERE user_id=?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (d *DB) DueDigests(now time.Time) ([]Digest, error) {
+	var digests []Digest
+	err := d.selectAll(&digests, "SELECT * FROM digests WHERE next_run <= ?", now.UTC())
+	return digests, err
+}
+
+func (d *DB) SetDigestNextRun(userID int64, next time.Time) error {
+	_, err := d.exec("UPDATE digests SET next_run=? WHERE user_id=?", next.UTC(), userID)
+	return err
+}
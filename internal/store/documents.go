@@ -0,0 +1,48 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type DocumentChunk struct {
+	ID         string    `db:"id"`
+	UserID     int64     `db:"user_id"`
+	FileName   string    `db:"file_name"`
+	ChunkIndex int       `db:"chunk_index"`
+	Content    string    `db:"content"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveDocumentChunks(userID int64, fileName string, chunks []string) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM document_chunks WHERE user_id=? AND file_name=?"), userID, fileName); err != nil {
+		return err
+	}
+	now := time.Now()
+	for i, chunk := range chunks {
+		_, err := tx.Exec(tx.Rebind("INSERT INTO document_chunks(id, user_id, file_name, chunk_index, content, created_at) VALUES(?, ?, ?, ?, ?, ?)"),
+			ulid.Make().String(), userID, fileName, i, chunk, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (d *DB) GetDocumentChunks(userID int64) ([]DocumentChunk, error) {
+	var chunks []DocumentChunk
+	err := d.selectAll(&chunks, "SELECT * FROM document_chunks WHERE user_id=? ORDER BY created_at DESC, chunk_index", userID)
+	return chunks, err
+}
+
+func (d *DB) DeleteDocumentChunks(userID int64) error {
+	_, err := d.exec("DELETE FROM document_chunks WHERE user_id=?", userID)
+	return err
+}
@@ -0,0 +1,84 @@
+package store
+
+import "time"
+
+// Flag rolls a feature out to Percent of the users.
+type Flag struct {
+	Name      string    `db:"name"`
+	Percent   int       `db:"percent"`
+	UpdatedBy int64     `db:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// FlagOverride turns a feature on or off for one user whatever its
+// rollout.
+type FlagOverride struct {
+	Name      string    `db:"name"`
+	UserID    int64     `db:"user_id"`
+	Enabled   bool      `db:"enabled"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Flags returns the features that have a rollout set, by name.
+func (d *DB) Flags() ([]Flag, error) {
+	var flags []Flag
+	err := d.selectAll(&flags, "SELECT * FROM feature_flags ORDER BY name")
+	return flags, err
+}
+
+// GetFlag returns the feature's rollout, sql.ErrNoRows when it has none.
+func (d *DB) GetFlag(name string) (Flag, error) {
+	var f Flag
+	err := d.get(&f, "SELECT * FROM feature_flags WHERE name=?", name)
+	return f, err
+}
+
+func (d *DB) SetFlag(name string, percent int, updatedBy int64) error {
+	_, err := d.exec(`INSERT INTO feature_flags(name, percent, updated_by, updated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET percent=excluded.percent, updated_by=excluded.updated_by, updated_at=excluded.updated_at`, name, percent, updatedBy, time.Now())
+	return err
+}
+
+// DeleteFlag removes the feature's rollout and its overrides.
+func (d *DB) DeleteFlag(name string) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM feature_flags WHERE name=?"), name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM feature_flag_users WHERE name=?"), name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FlagOverrides returns the feature's overrides, all features' when name
+// is "".
+func (d *DB) FlagOverrides(name string) ([]FlagOverride, error) {
+	var overrides []FlagOverride
+	err := d.selectAll(&overrides, "SELECT * FROM feature_flag_users WHERE name=? OR ?='' ORDER BY name, user_id", name, name)
+	return overrides, err
+}
+
+func (d *DB) SetFlagOverride(name string, userID int64, enabled bool) error {
+	value := 0
+	if enabled {
+		value = 1
+	}
+	_, err := d.exec(`INSERT INTO feature_flag_users(name, user_id, enabled, updated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(name, user_id) DO UPDATE SET enabled=excluded.enabled, updated_at=excluded.updated_at`, name, userID, value, time.Now())
+	return err
+}
+
+// DeleteFlagOverride reports whether the user had an override to delete.
+func (d *DB) DeleteFlagOverride(name string, userID int64) (bool, error) {
+	res, err := d.exec("DELETE FROM feature_flag_users WHERE name=? AND user_id=?", name, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,30 @@
+package store
+
+import "time"
+
+// SaveGatewayKey replaces the user's gateway key with the one hashing to
+// hash.
+func (d *DB) SaveGatewayKey(userID int64, hash string) error {
+	_, err := d.exec(`INSERT INTO gateway_keys(user_id, key_hash, created_at) VALUES(?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET key_hash=excluded.key_hash, created_at=excluded.created_at`, userID, hash, time.Now())
+	return err
+}
+
+// GatewayKeyUser is the user whose gateway key hashes to hash.
+func (d *DB) GatewayKeyUser(hash string) (int64, error) {
+	var userID int64
+	err := d.get(&userID, "SELECT user_id FROM gateway_keys WHERE key_hash=?", hash)
+	return userID, err
+}
+
+// GatewayKeyCreated is when the user's gateway key was made.
+func (d *DB) GatewayKeyCreated(userID int64) (time.Time, error) {
+	var created time.Time
+	err := d.get(&created, "SELECT created_at FROM gateway_keys WHERE user_id=?", userID)
+	return created, err
+}
+
+func (d *DB) DeleteGatewayKey(userID int64) error {
+	_, err := d.exec("DELETE FROM gateway_keys WHERE user_id=?", userID)
+	return err
+}
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func (d *DB) SaveImageGeneration(userID int64, prompt string) error {
+	_, err := d.exec("INSERT INTO image_generations(id, user_id, prompt, created_at) VALUES(?, ?, ?, ?)",
+		ulid.Make().String(), userID, prompt, time.Now())
+	return err
+}
+
+// CountImageGenerations counts the images the user generated since the
+// given time.
+func (d *DB) CountImageGenerations(userID int64, since time.Time) (int, error) {
+	var n int
+	err := d.get(&n, "SELECT COUNT(*) FROM image_generations WHERE user_id=? AND created_at >= ?", userID, since)
+	return n, err
+}
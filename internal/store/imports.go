@@ -0,0 +1,42 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// ImportChat saves exchanges brought over from another assistant as a new
+// chat of the user's, keeping their times and chaining each to the one
+// before it.
+func (d *DB) ImportChat(userID int64, title string, exchanges []Exchange) (Chat, error) {
+	tx, err := d.begin()
+	if err != nil {
+		return Chat{}, err
+	}
+	defer tx.Rollback()
+
+	chat := Chat{ID: ulid.Make().String(), UserID: userID, Title: title, CreatedAt: time.Now()}
+	if _, err := tx.NamedExec(`INSERT INTO chats(id, user_id, title, created_at) VALUES(:id, :user_id, :title, :created_at)`, chat); err != nil {
+		return Chat{}, err
+	}
+	parent := ""
+	for _, ex := range exchanges {
+		ex.ID = ulid.Make().String()
+		ex.UserID = userID
+		ex.ChatID = chat.ID
+		ex.ParentID = parent
+		if ex.CreatedAt.IsZero() {
+			ex.CreatedAt = chat.CreatedAt
+		}
+		// In local time like the exchanges made here, so they compare.
+		ex.CreatedAt = ex.CreatedAt.Local()
+		_, err := tx.NamedExec(`INSERT INTO conversations(id, user_id, chat_id, prompt, response, model, prompt_tokens, completion_tokens, created_at, parent_id, message_id, prompt_message_id)
+VALUES(:id, :user_id, :chat_id, :prompt, :response, :model, :prompt_tokens, :completion_tokens, :created_at, :parent_id, :message_id, :prompt_message_id)`, ex)
+		if err != nil {
+			return Chat{}, err
+		}
+		parent = ex.ID
+	}
+	return chat, tx.Commit()
+}
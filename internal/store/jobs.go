@@ -0,0 +1,46 @@
+package store
+
+import "time"
+
+// Job is a request that was queued or being answered, kept until it's done
+// so a restart can pick it up again.
+type Job struct {
+	// ID is the Telegram update's ID.
+	ID        string `db:"id"`
+	UserID    int64  `db:"user_id"`
+	ChatID    int64  `db:"chat_id"`
+	MessageID int    `db:"message_id"`
+	// Prompt is what the user asked, for telling them about it.
+	Prompt string `db:"prompt"`
+	// Payload is the update as JSON.
+	Payload string `db:"payload"`
+	// Attempts counts the restarts the job was picked up after.
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SaveJob records the job, leaving one already recorded as it is.
+func (d *DB) SaveJob(j Job) error {
+	j.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO jobs(id, user_id, chat_id, message_id, prompt, payload, attempts, created_at)
+VALUES(:id, :user_id, :chat_id, :message_id, :prompt, :payload, :attempts, :created_at)
+ON CONFLICT(id) DO NOTHING`, j)
+	return err
+}
+
+func (d *DB) DeleteJob(id string) error {
+	_, err := d.exec("DELETE FROM jobs WHERE id=?", id)
+	return err
+}
+
+// PendingJobs returns the jobs left unfinished, oldest first.
+func (d *DB) PendingJobs() ([]Job, error) {
+	var jobs []Job
+	err := d.selectAll(&jobs, "SELECT * FROM jobs ORDER BY created_at")
+	return jobs, err
+}
+
+func (d *DB) SetJobAttempts(id string, attempts int) error {
+	_, err := d.exec("UPDATE jobs SET attempts=? WHERE id=?", attempts, id)
+	return err
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type Memory struct {
+	ID             string    `db:"id"`
+	UserID         int64     `db:"user_id"`
+	ConversationID string    `db:"conversation_id"`
+	Content        string    `db:"content"`
+	Embedding      []byte    `db:"embedding"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveMemory(m Memory) error {
+	_, err := d.exec("INSERT INTO memories(id, user_id, conversation_id, content, embedding, created_at) VALUES(?, ?, ?, ?, ?, ?)",
+		ulid.Make().String(), m.UserID, m.ConversationID, m.Content, m.Embedding, time.Now())
+	return err
+}
+
+func (d *DB) GetMemories(userID int64) ([]Memory, error) {
+	var memories []Memory
+	err := d.selectAll(&memories, "SELECT * FROM memories WHERE user_id=?", userID)
+	return memories, err
+}
+
+func (d *DB) DeleteMemories(conversationID string) error {
+	_, err := d.exec("DELETE FROM memories WHERE conversation_id=?", conversationID)
+	return err
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Pin is an exchange the user kept, copied so it outlives the
+// conversation it came from.
+type Pin struct {
+	ID         string    `db:"id"`
+	UserID     int64     `db:"user_id"`
+	ExchangeID string    `db:"exchange_id"`
+	Prompt     string    `db:"prompt"`
+	Response   string    `db:"response"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// SavePin pins the exchange, reporting false when it already was.
+func (d *DB) SavePin(ex Exchange) (bool, error) {
+	p := Pin{
+		ID:         ulid.Make().String(),
+		UserID:     ex.UserID,
+		ExchangeID: ex.ID,
+		Prompt:     ex.Prompt,
+		Response:   ex.Response,
+		CreatedAt:  time.Now(),
+	}
+	res, err := d.namedExec(`INSERT INTO pins(id, user_id, exchange_id, prompt, response, created_at) VALUES(:id, :user_id, :exchange_id, :prompt, :response, :created_at)
+ON CONFLICT(user_id, exchange_id) DO NOTHING`, p)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetPins returns the user's pins, newest first.
+func (d *DB) GetPins(userID int64) ([]Pin, error) {
+	var pins []Pin
+	err := d.selectAll(&pins, "SELECT * FROM pins WHERE user_id=? ORDER BY created_at DESC", userID)
+	return pins, err
+}
+
+func (d *DB) DeletePin(userID int64, id string) (bool, error) {
+	res, err := d.exec("DELETE FROM pins WHERE user_id=? AND id=?", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,15 @@
+package store
+
+// GetPreference returns one of the user's settings, sql.ErrNoRows when it
+// was never set.
+func (d *DB) GetPreference(userID int64, key string) (string, error) {
+	var value string
+	err := d.get(&value, "SELECT value FROM preferences WHERE user_id=? AND key=?", userID, key)
+	return value, err
+}
+
+func (d *DB) SetPreference(userID int64, key, value string) error {
+	_, err := d.exec(`INSERT INTO preferences(user_id, key, value) VALUES(?, ?, ?)
+ON CONFLICT(user_id, key) DO UPDATE SET value=excluded.value`, userID, key, value)
+	return err
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// QuietHours is the daily window a user doesn't want to be messaged in,
+// from Start to End minutes past midnight in Timezone. Windows past
+// midnight have Start after End.
+type QuietHours struct {
+	UserID int64 `db:"user_id"`
+	Start  int   `db:"start_minute"`
+	End    int   `db:"end_minute"`
+	// Timezone is an IANA name like Europe/Berlin.
+	Timezone  string    `db:"timezone"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// HeldMessage is a scheduled message that came due in the user's quiet
+// hours, kept to be sent when they end.
+type HeldMessage struct {
+	ID     string `db:"id"`
+	UserID int64  `db:"user_id"`
+	ChatID int64  `db:"chat_id"`
+	// Kind is what sent it: digest, reminder or broadcast.
+	Kind      string    `db:"kind"`
+	Text      string    `db:"text"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SetQuietHours saves the user's quiet hours, replacing earlier ones.
+func (d *DB) SetQuietHours(q QuietHours) error {
+	q.UpdatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO quiet_hours(user_id, start_minute, end_minute, timezone, updated_at)
+VALUES(:user_id, :start_minute, :end_minute, :timezone, :updated_at)
+ON CONFLICT(user_id) DO UPDATE SET start_minute=excluded.start_minute, end_minute=excluded.end_minute, timezone=excluded.timezone, updated_at=excluded.updated_at`, q)
+	return err
+}
+
+// GetQuietHours returns the user's quiet hours, sql.ErrNoRows when they
+// have none.
+func (d *DB) GetQuietHours(userID int64) (QuietHours, error) {
+	var q QuietHours
+	err := d.get(&q, "SELECT * FROM quiet_hours WHERE user_id=?", userID)
+	return q, err
+}
+
+// DeleteQuietHours turns the user's quiet hours off, reporting whether
+// they had any.
+func (d *DB) DeleteQuietHours(userID int64) (bool, error) {
+	res, err := d.exec("DELETE FROM quiet_hours WHERE user_id=?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (d *DB) HoldMessage(m HeldMessage) error {
+	m.ID = ulid.Make().String()
+	m.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO held_messages(id, user_id, chat_id, kind, text, created_at)
+VALUES(:id, :user_id, :chat_id, :kind, :text, :created_at)`, m)
+	return err
+}
+
+// HeldMessages returns the messages waiting for quiet hours to end, oldest
+// first.
+func (d *DB) HeldMessages() ([]HeldMessage, error) {
+	var messages []HeldMessage
+	err := d.selectAll(&messages, "SELECT * FROM held_messages ORDER BY created_at, id")
+	return messages, err
+}
+
+func (d *DB) DeleteHeldMessage(id string) error {
+	_, err := d.exec("DELETE FROM held_messages WHERE id=?", id)
+	return err
+}
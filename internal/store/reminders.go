@@ -0,0 +1,50 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type Reminder struct {
+	ID        string    `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Username  string    `db:"username"`
+	ChatID    int64     `db:"chat_id"`
+	Prompt    string    `db:"prompt"`
+	Hour      int       `db:"hour"`
+	Minute    int       `db:"minute"`
+	Repeat    string    `db:"repeat"`
+	NextRun   time.Time `db:"next_run"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveReminder(r Reminder) error {
+	r.ID = ulid.Make().String()
+	r.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO reminders(id, user_id, username, chat_id, prompt, hour, minute, repeat, next_run, created_at)
+VALUES(:id, :user_id, :username, :chat_id, :prompt, :hour, :minute, :repeat, :next_run, :created_at)`, r)
+	return err
+}
+
+func (d *DB) GetReminders(userID int64) ([]Reminder, error) {
+	var reminders []Reminder
+	err := d.selectAll(&reminders, "SELECT * FROM reminders WHERE user_id=? ORDER BY created_at", userID)
+	return reminders, err
+}
+
+func (d *DB) DueReminders(now time.Time) ([]Reminder, error) {
+	var reminders []Reminder
+	err := d.selectAll(&reminders, "SELECT * FROM reminders WHERE next_run <= ?", now)
+	return reminders, err
+}
+
+func (d *DB) SetReminderNextRun(id string, next time.Time) error {
+	_, err := d.exec("UPDATE reminders SET next_run=? WHERE id=?", next, id)
+	return err
+}
+
+func (d *DB) DeleteReminder(userID int64, id string) error {
+	_, err := d.exec("DELETE FROM reminders WHERE id=? AND user_id=?", id, userID)
+	return err
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// retentionTables are the tables of a user's data pruned to their retention
+// policy.
+var retentionTables = []string{"conversations", "summaries", "memories", "audit_log", "usage"}
+
+// purgeTables are the tables /purge empties, everything the user said and
+// was answered, leaving their account and settings. The usage ledger stays,
+// as the message quota and budgets are counted from it.
+var purgeTables = []string{
+	"conversations", "chats", "summaries", "memories", "document_chunks",
+	"audit_log", "pins", "shares", "held_messages",
+}
+
+// column is a table's column.
+type column struct{ table, name string }
+
+// purgeScrubbed are the columns /purge blanks in ledgers it keeps the rows
+// of, like image generations the image quota is counted from.
+var purgeScrubbed = []column{{"image_generations", "prompt"}}
+
+// agedBy is the column saying how old a table's rows are. Summaries are
+// rewritten in place, so it's when they were last updated.
+func agedBy(table string) string {
+	if table == "summaries" {
+		return "updated_at"
+	}
+	return "created_at"
+}
+
+// Deletion records user data deleted by retention or on request, kept
+// after the data is gone for compliance.
+type Deletion struct {
+	ID     string `db:"id"`
+	UserID int64  `db:"user_id"`
+	// Reason is retention or purge.
+	Reason string `db:"reason"`
+	// Counts are what was deleted by table, e.g. "conversations=3 usage=5".
+	Counts    string    `db:"counts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// DeleteUserDataBefore deletes the user's conversations, summaries,
+// memories, audit entries and usage from before t, returning what it
+// deleted from each table.
+func (d *DB) DeleteUserDataBefore(userID int64, t time.Time) (map[string]int64, error) {
+	return d.deleteUserData(userID, "retention", retentionTables, nil, t)
+}
+
+// PurgeUserData deletes all the user's conversations and what came of them,
+// returning what it deleted from each table, and blanks what they wrote in
+// the ledgers kept for their quotas, counted by table.column.
+func (d *DB) PurgeUserData(userID int64) (map[string]int64, error) {
+	return d.deleteUserData(userID, "purge", purgeTables, purgeScrubbed, time.Time{})
+}
+
+// deleteUserData deletes the user's rows from before t from tables, all of
+// them when t is zero, blanks their scrubbed columns and, when anything
+// changed, records the deletion in the same transaction.
+func (d *DB) deleteUserData(userID int64, reason string, tables []string, scrubbed []column, t time.Time) (map[string]int64, error) {
+	tx, err := d.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := map[string]int64{}
+	var counts []string
+	count := func(name string, res sql.Result) error {
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			deleted[name] = n
+			counts = append(counts, fmt.Sprintf("%s=%d", name, n))
+		}
+		return nil
+	}
+	for _, table := range tables {
+		query, args := "DELETE FROM "+table+" WHERE user_id=?", []any{userID}
+		if !t.IsZero() {
+			query, args = query+" AND "+agedBy(table)+" < ?", append(args, t)
+		}
+		res, err := tx.Exec(tx.Rebind(query), args...)
+		if err != nil {
+			return nil, fmt.Errorf("could not delete from %s: %v", table, err)
+		}
+		if err := count(table, res); err != nil {
+			return nil, err
+		}
+	}
+	for _, col := range scrubbed {
+		res, err := tx.Exec(tx.Rebind("UPDATE "+col.table+" SET "+col.name+"='' WHERE user_id=? AND "+col.name+" <> ''"), userID)
+		if err != nil {
+			return nil, fmt.Errorf("could not blank %s.%s: %v", col.table, col.name, err)
+		}
+		if err := count(col.table+"."+col.name, res); err != nil {
+			return nil, err
+		}
+	}
+	if len(deleted) == 0 {
+		return deleted, nil
+	}
+	_, err = tx.Exec(tx.Rebind("INSERT INTO data_deletions(id, user_id, reason, counts, created_at) VALUES(?, ?, ?, ?, ?)"),
+		ulid.Make().String(), userID, reason, strings.Join(counts, " "), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// Deletions returns the user's recorded deletions, latest first.
+func (d *DB) Deletions(userID int64) ([]Deletion, error) {
+	var deletions []Deletion
+	err := d.selectAll(&deletions, "SELECT * FROM data_deletions WHERE user_id=? ORDER BY created_at DESC", userID)
+	return deletions, err
+}
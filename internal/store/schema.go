@@ -0,0 +1,359 @@
+package store
+
+import "fmt"
+
+func (d *DB) CreateTables() error {
+	schema := `
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT NOT NULL PRIMARY KEY,
+	username TEXT NOT NULL,
+	token TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS document_chunks (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	chunk_index INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_document_chunks_user ON document_chunks(user_id);
+CREATE TABLE IF NOT EXISTS conversations (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	prompt TEXT NOT NULL,
+	response TEXT NOT NULL,
+	model TEXT NOT NULL,
+	prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_conversations_user ON conversations(user_id, created_at);
+CREATE TABLE IF NOT EXISTS memories (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	conversation_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	embedding BLOB NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_memories_user ON memories(user_id);
+CREATE TABLE IF NOT EXISTS audit_log (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	username TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	response TEXT NOT NULL,
+	model TEXT NOT NULL,
+	latency_ms INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_username ON audit_log(username, created_at);
+CREATE TABLE IF NOT EXISTS summaries (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	content TEXT NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS reminders (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	username TEXT NOT NULL,
+	chat_id INTEGER NOT NULL,
+	prompt TEXT NOT NULL,
+	hour INTEGER NOT NULL,
+	minute INTEGER NOT NULL,
+	repeat TEXT NOT NULL,
+	next_run DATETIME NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_next_run ON reminders(next_run);
+CREATE TABLE IF NOT EXISTS moderation_violations (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	username TEXT NOT NULL,
+	categories TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS api_keys (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	ciphertext BLOB NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS image_generations (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	prompt TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_image_generations_user ON image_generations(user_id, created_at);
+CREATE TABLE IF NOT EXISTS preferences (
+	user_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (user_id, key)
+);
+CREATE TABLE IF NOT EXISTS usage (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	username TEXT NOT NULL,
+	model TEXT NOT NULL,
+	prompt_tokens INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_usage_created ON usage(created_at);
+CREATE TABLE IF NOT EXISTS chats (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_chats_user ON chats(user_id, created_at);
+CREATE TABLE IF NOT EXISTS templates (
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, name)
+);
+CREATE TABLE IF NOT EXISTS response_cache (
+	prompt_hash TEXT NOT NULL PRIMARY KEY,
+	model TEXT NOT NULL,
+	response TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_response_cache_created ON response_cache(created_at);
+CREATE TABLE IF NOT EXISTS pins (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	exchange_id TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	response TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (user_id, exchange_id)
+);
+CREATE TABLE IF NOT EXISTS chat_prompts (
+	chat_id INTEGER NOT NULL PRIMARY KEY,
+	content TEXT NOT NULL,
+	updated_by INTEGER NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS digests (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	username TEXT NOT NULL,
+	hour INTEGER NOT NULL,
+	minute INTEGER NOT NULL,
+	timezone TEXT NOT NULL,
+	next_run DATETIME NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_digests_next_run ON digests(next_run);
+CREATE TABLE IF NOT EXISTS subscriptions (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	plan TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS payments (
+	charge_id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	plan TEXT NOT NULL,
+	stars INTEGER NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS shares (
+	token TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	chat_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_user ON shares(user_id);
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	chat_id INTEGER NOT NULL,
+	message_id INTEGER NOT NULL,
+	prompt TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS feature_flags (
+	name TEXT NOT NULL PRIMARY KEY,
+	percent INTEGER NOT NULL,
+	updated_by INTEGER NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS feature_flag_users (
+	name TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	enabled INTEGER NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (name, user_id)
+);
+CREATE TABLE IF NOT EXISTS quiet_hours (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	start_minute INTEGER NOT NULL,
+	end_minute INTEGER NOT NULL,
+	timezone TEXT NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS held_messages (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	chat_id INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	text TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS data_deletions (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	counts TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_data_deletions_user ON data_deletions(user_id, created_at);
+CREATE TABLE IF NOT EXISTS topic_prompts (
+	chat_id INTEGER NOT NULL,
+	thread_id INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	updated_by INTEGER NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (chat_id, thread_id)
+);
+CREATE TABLE IF NOT EXISTS gateway_keys (
+	user_id INTEGER NOT NULL PRIMARY KEY,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL
+);
+    `
+	if _, err := d.db.Exec(d.ddl(schema)); err != nil {
+		return err
+	}
+	if err := d.migrate(); err != nil {
+		return err
+	}
+	return d.setupSearch()
+}
+
+func (d *DB) Cleanup() {
+	d.db.MustExec("DROP TABLE users")
+	d.db.MustExec("DROP TABLE document_chunks")
+	d.db.MustExec("DROP TABLE conversations")
+	d.db.MustExec("DROP TABLE memories")
+	d.db.MustExec("DROP TABLE audit_log")
+	d.db.MustExec("DROP TABLE summaries")
+	d.db.MustExec("DROP TABLE reminders")
+	d.db.MustExec("DROP TABLE moderation_violations")
+	d.db.MustExec("DROP TABLE api_keys")
+	d.db.MustExec("DROP TABLE image_generations")
+	d.db.MustExec("DROP TABLE preferences")
+	d.db.MustExec("DROP TABLE usage")
+	d.db.MustExec("DROP TABLE chats")
+	d.db.MustExec("DROP TABLE templates")
+	d.db.MustExec("DROP TABLE response_cache")
+	d.db.MustExec("DROP TABLE pins")
+	d.db.MustExec("DROP TABLE chat_prompts")
+	d.db.MustExec("DROP TABLE IF EXISTS conversations_fts")
+	d.db.MustExec("DROP TABLE digests")
+	d.db.MustExec("DROP TABLE payments")
+	d.db.MustExec("DROP TABLE subscriptions")
+	d.db.MustExec("DROP TABLE shares")
+	d.db.MustExec("DROP TABLE jobs")
+	d.db.MustExec("DROP TABLE feature_flags")
+	d.db.MustExec("DROP TABLE feature_flag_users")
+	d.db.MustExec("DROP TABLE quiet_hours")
+	d.db.MustExec("DROP TABLE held_messages")
+	d.db.MustExec("DROP TABLE data_deletions")
+	d.db.MustExec("DROP TABLE topic_prompts")
+	d.db.MustExec("DROP TABLE gateway_keys")
+	d.db.MustExec("DROP TABLE schema_migrations")
+}
+
+// migrations alter tables created by CreateTables. They run in order and
+// the number already applied is kept in schema_migrations, so only ever
+// append to this list.
+var migrations = []string{
+	`ALTER TABLE conversations ADD COLUMN parent_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE conversations ADD COLUMN message_id INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_conversations_message ON conversations(user_id, message_id)`,
+	`ALTER TABLE conversations ADD COLUMN summarized INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE conversations ADD COLUMN prompt_message_id INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_conversations_prompt_message ON conversations(user_id, prompt_message_id)`,
+	`ALTER TABLE users ADD COLUMN created_at DATETIME`,
+	`ALTER TABLE conversations ADD COLUMN chat_id TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS idx_conversations_chat ON conversations(user_id, chat_id, created_at)`,
+	// Summaries become per chat, the existing ones belong to the main chat.
+	`CREATE TABLE chat_summaries (
+	user_id INTEGER NOT NULL,
+	chat_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, chat_id)
+);
+INSERT INTO chat_summaries(user_id, chat_id, content, updated_at) SELECT user_id, '', content, updated_at FROM summaries;
+DROP TABLE summaries;
+ALTER TABLE chat_summaries RENAME TO summaries`,
+	`ALTER TABLE users ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0`,
+	// Users were keyed by username, take their ID from anything else kept
+	// about them. The rest get theirs the next time they write.
+	`UPDATE users SET user_id = COALESCE((
+	SELECT MAX(known.user_id) FROM (
+		SELECT user_id, username FROM usage
+		UNION SELECT user_id, username FROM reminders
+		UNION SELECT user_id, username FROM audit_log
+		UNION SELECT user_id, username FROM moderation_violations
+	) known WHERE known.username = users.username AND known.username <> ''
+), 0) WHERE user_id = 0`,
+	`CREATE INDEX IF NOT EXISTS idx_users_user_id ON users(user_id)`,
+	// Every /auth used to add a user, keep the latest of each.
+	`DELETE FROM users WHERE id NOT IN (
+	SELECT MAX(id) FROM users GROUP BY CASE WHEN user_id = 0 THEN '@' || username ELSE CAST(user_id AS TEXT) END
+)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_unique_user_id ON users(user_id) WHERE user_id <> 0`,
+	`ALTER TABLE conversations ADD COLUMN feedback INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN workspace TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE usage ADD COLUMN workspace TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE usage ADD COLUMN request INTEGER NOT NULL DEFAULT 0`,
+}
+
+func (d *DB) migrate() error {
+	if _, err := d.db.Exec(d.ddl("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)")); err != nil {
+		return err
+	}
+
+	var version int
+	if err := d.get(&version, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(d.ddl(migrations[i])); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		if _, err := tx.Exec(tx.Rebind("INSERT INTO schema_migrations(version) VALUES(?)"), i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the number of migrations applied to the database
+// and the number this build knows of, equal once CreateTables has run.
+func (d *DB) SchemaVersion() (applied, latest int, err error) {
+	err = d.get(&applied, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	return applied, len(migrations), err
+}
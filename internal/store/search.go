@@ -0,0 +1,147 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchSchema indexes the exchanges with SQLite's FTS5, when it was built
+// in with the sqlite_fts5 tag. The exchange IDs are indexed too, so the
+// triggers can find the rows to drop without a full scan.
+const searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS conversations_fts USING fts5(id, prompt, response);
+CREATE TRIGGER IF NOT EXISTS conversations_fts_insert AFTER INSERT ON conversations BEGIN
+	INSERT INTO conversations_fts(id, prompt, response) VALUES (new.id, new.prompt, new.response);
+END;
+CREATE TRIGGER IF NOT EXISTS conversations_fts_update AFTER UPDATE OF prompt, response ON conversations BEGIN
+	DELETE FROM conversations_fts WHERE conversations_fts MATCH 'id:"' || old.id || '"';
+	INSERT INTO conversations_fts(id, prompt, response) VALUES (new.id, new.prompt, new.response);
+END;
+CREATE TRIGGER IF NOT EXISTS conversations_fts_delete AFTER DELETE ON conversations BEGIN
+	DELETE FROM conversations_fts WHERE conversations_fts MATCH 'id:"' || old.id || '"';
+END;
+`
+
+// SearchResult is an exchange found by a search, with the bit of it that
+// matched.
+type SearchResult struct {
+	Exchange
+	Snippet string `db:"snippet"`
+}
+
+// setupSearch creates the full-text index on SQLite builds with FTS5,
+// filling it with the exchanges kept so far whenever its triggers weren't
+// there to keep it up to date. Without FTS5, and on Postgres, searches fall
+// back to matching every word.
+func (d *DB) setupSearch() error {
+	if d.dialect != sqlite {
+		return nil
+	}
+	var fts5 int
+	if err := d.get(&fts5, "SELECT sqlite_compileoption_used('ENABLE_FTS5')"); err != nil {
+		return err
+	}
+	if fts5 == 0 {
+		// A build with FTS5 may have indexed this database before, and its
+		// triggers would fail every write to conversations here.
+		_, err := d.exec(`DROP TRIGGER IF EXISTS conversations_fts_insert;
+DROP TRIGGER IF EXISTS conversations_fts_update;
+DROP TRIGGER IF EXISTS conversations_fts_delete;`)
+		return err
+	}
+	var triggered int
+	if err := d.get(&triggered, "SELECT COUNT(*) FROM sqlite_master WHERE name='conversations_fts_insert'"); err != nil {
+		return err
+	}
+	if _, err := d.exec(searchSchema); err != nil {
+		return err
+	}
+	if triggered == 0 {
+		if _, err := d.exec("DELETE FROM conversations_fts; INSERT INTO conversations_fts(id, prompt, response) SELECT id, prompt, response FROM conversations"); err != nil {
+			return err
+		}
+	}
+	d.fts = true
+	return nil
+}
+
+// searchTerms splits query into words, dropping punctuation.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchExchanges returns the user's exchanges containing every word of
+// query, best matches first, in chats whose ID starts with chatIDPrefix.
+// An empty chatIDPrefix searches the private chats.
+func (d *DB) SearchExchanges(userID int64, chatIDPrefix, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	chat := "chat_id NOT LIKE 'group:%'"
+	if chatIDPrefix != "" {
+		chat = "chat_id LIKE ?"
+	}
+
+	var results []SearchResult
+	if d.fts {
+		quoted := make([]string, len(terms))
+		for i, t := range terms {
+			quoted[i] = `"` + t + `"`
+		}
+		args := []any{"{prompt response}: " + strings.Join(quoted, " "), userID}
+		if chatIDPrefix != "" {
+			args = append(args, chatIDPrefix+"%")
+		}
+		err := d.selectAll(&results, `SELECT conversations.*, snippet(conversations_fts, -1, '«', '»', '…', 16) AS snippet
+FROM conversations_fts JOIN conversations ON conversations.id = conversations_fts.id
+WHERE conversations_fts MATCH ? AND user_id=? AND `+chat+`
+ORDER BY rank LIMIT ?`, append(args, limit)...)
+		return results, err
+	}
+
+	where := []string{"user_id=?", chat}
+	args := []any{userID}
+	if chatIDPrefix != "" {
+		args = append(args, chatIDPrefix+"%")
+	}
+	for _, t := range terms {
+		where = append(where, "LOWER(prompt || ' ' || response) LIKE ?")
+		args = append(args, "%"+t+"%")
+	}
+	err := d.selectAll(&results, "SELECT conversations.*, '' AS snippet FROM conversations WHERE "+strings.Join(where, " AND ")+
+		" ORDER BY created_at DESC LIMIT ?", append(args, limit)...)
+	for i, r := range results {
+		results[i].Snippet = snippet(r.Prompt+"\n"+r.Response, terms[0])
+	}
+	return results, err
+}
+
+// snippet is the part of text around the first mention of term, with the
+// term marked the way FTS5's snippet marks it.
+func snippet(text, term string) string {
+	const around = 60
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	at := strings.Index(string(lower), term)
+	if at < 0 || len(lower) != len(runes) {
+		return strings.Join(strings.Fields(string(runes[:min(len(runes), 2*around)])), " ")
+	}
+	start := len([]rune(string(lower)[:at]))
+	end := start + len([]rune(term))
+
+	var sb strings.Builder
+	from := max(start-around, 0)
+	if from > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(string(runes[from:start]) + "«" + string(runes[start:end]) + "»")
+	to := min(end+around, len(runes))
+	sb.WriteString(string(runes[end:to]))
+	if to < len(runes) {
+		sb.WriteString("…")
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,52 @@
+package store
+
+import "time"
+
+// LastActive returns when the user's latest exchange happened.
+func (d *DB) LastActive(userID int64) (time.Time, error) {
+	var t time.Time
+	err := d.get(&t, "SELECT created_at FROM conversations WHERE user_id=? ORDER BY created_at DESC LIMIT 1", userID)
+	return t, err
+}
+
+// DeleteSession drops the user's conversation and its summary.
+func (d *DB) DeleteSession(userID int64) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM conversations WHERE user_id=?"), userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM summaries WHERE user_id=?"), userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExpireSessions deletes the conversations and summaries of every user who
+// has been inactive since before, returning how many exchanges went.
+func (d *DB) ExpireSessions(before time.Time) (int64, error) {
+	tx, err := d.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(tx.Rebind(`DELETE FROM conversations WHERE user_id IN (
+	SELECT user_id FROM conversations GROUP BY user_id HAVING MAX(created_at) < ?
+)`), before)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM summaries WHERE updated_at < ? AND user_id NOT IN (SELECT user_id FROM conversations)"), before); err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
@@ -0,0 +1,38 @@
+package store
+
+import "time"
+
+// Share is a read-only link to one of a user's chats as it was when the
+// link was made.
+type Share struct {
+	// Token is the secret part of the link.
+	Token  string `db:"token"`
+	UserID int64  `db:"user_id"`
+	ChatID string `db:"chat_id"`
+	Title  string `db:"title"`
+	// CreatedAt bounds the exchanges shown, later ones stay private.
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveShare(s Share) error {
+	_, err := d.namedExec(`INSERT INTO shares(token, user_id, chat_id, title, created_at) VALUES(:token, :user_id, :chat_id, :title, :created_at)`, s)
+	return err
+}
+
+// GetShare returns the share with the token, sql.ErrNoRows when there is
+// none.
+func (d *DB) GetShare(token string) (Share, error) {
+	var s Share
+	err := d.get(&s, "SELECT * FROM shares WHERE token=?", token)
+	return s, err
+}
+
+// DeleteShares revokes every link the user made, returning how many.
+func (d *DB) DeleteShares(userID int64) (int, error) {
+	res, err := d.exec("DELETE FROM shares WHERE user_id=?", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,43 @@
+//go:build cgo
+
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// cgoSQLite is whether mattn/go-sqlite3 is built in, which needs cgo.
+const cgoSQLite = true
+
+// onlineBackup copies the database to path with SQLite's online backup API.
+func (d *DB) onlineBackup(ctx context.Context, path string) error {
+	dest, err := (&sqlite3.SQLiteDriver{}).Open(path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		src, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected connection type %T", driverConn)
+		}
+		backup, err := dest.(*sqlite3.SQLiteConn).Backup("main", src, "main")
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Step(-1); err != nil {
+			backup.Finish()
+			return err
+		}
+		return backup.Finish()
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !cgo
+
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// cgoSQLite is whether mattn/go-sqlite3 is built in, which needs cgo.
+const cgoSQLite = false
+
+func (d *DB) onlineBackup(context.Context, string) error {
+	return errors.New("this binary was built without cgo")
+}
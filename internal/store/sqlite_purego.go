@@ -0,0 +1,11 @@
+package store
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	// sqlx only knows mattn/go-sqlite3's name for SQLite.
+	sqlx.BindDriver("sqlite", sqlx.QUESTION)
+}
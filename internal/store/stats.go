@@ -0,0 +1,38 @@
+package store
+
+// UsageStats summarizes usage across all users.
+type UsageStats struct {
+	Users     int `db:"users"`
+	Exchanges int `db:"exchanges"`
+	// Requests, Errors and AvgLatencyMS come from the audit log and are
+	// zero when it is disabled.
+	Requests     int     `db:"requests"`
+	Errors       int     `db:"errors"`
+	AvgLatencyMS float64 `db:"avg_latency_ms"`
+	TopModels    []ModelUsage
+}
+
+type ModelUsage struct {
+	Model     string `db:"model"`
+	Exchanges int    `db:"exchanges"`
+	Tokens    int    `db:"tokens"`
+}
+
+// UsageStats counts users, exchanges and audited requests, along with the
+// topModels most used models.
+func (d *DB) UsageStats(topModels int) (UsageStats, error) {
+	var s UsageStats
+	err := d.get(&s, `SELECT
+	(SELECT COUNT(DISTINCT username) FROM users) AS users,
+	(SELECT COUNT(*) FROM conversations) AS exchanges,
+	(SELECT COUNT(*) FROM audit_log) AS requests,
+	(SELECT COUNT(*) FROM audit_log WHERE status <> 'ok') AS errors,
+	(SELECT COALESCE(AVG(latency_ms), 0) FROM audit_log) AS avg_latency_ms`)
+	if err != nil {
+		return s, err
+	}
+
+	err = d.selectAll(&s.TopModels, `SELECT model, COUNT(*) AS exchanges, COALESCE(SUM(prompt_tokens + completion_tokens), 0) AS tokens
+FROM conversations GROUP BY model ORDER BY exchanges DESC LIMIT ?`, topModels)
+	return s, err
+}
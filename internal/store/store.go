@@ -0,0 +1,408 @@
+// Package store persists the bot's data in SQLite or Postgres.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/musaubrian/groqy/internal/tracing"
+)
+
+// Store is everything the bot persists. DB implements it on top of SQLite
+// or Postgres.
+type Store interface {
+	CreateTables() error
+	Ping(ctx context.Context) error
+	// WithContext traces the queries of the returned store under ctx.
+	WithContext(ctx context.Context) Store
+	Backup(ctx context.Context, path string) error
+	SchemaVersion() (applied, latest int, err error)
+
+	CreateUser(userID int64, username, token, workspace string) error
+	GetUser(userID int64) (User, error)
+	GetUserByUsername(username string) (User, error)
+	GetUsers() ([]User, error)
+	RevokeUser(userID int64) (bool, error)
+	ClaimUser(userID int64, username string) (bool, error)
+	DeleteUser(userID int64) error
+
+	SaveExchange(ex *Exchange) error
+	UpdateExchange(ex Exchange) error
+	DeleteExchange(id string) error
+	RecentExchanges(userID int64, chatID string, n int) ([]Exchange, error)
+	AllExchanges(userID int64) ([]Exchange, error)
+	ChatExchanges(userID int64, chatID string, until time.Time) ([]Exchange, error)
+	ImportChat(userID int64, title string, exchanges []Exchange) (Chat, error)
+	ExchangesSince(userID int64, since time.Time) ([]Exchange, error)
+	RatedExchanges() ([]Exchange, error)
+	UnsummarizedExchanges(userID int64, chatID string) ([]Exchange, error)
+	Thread(id string, n int) ([]Exchange, error)
+	ExchangeByMessage(userID int64, messageID int) (Exchange, error)
+	ExchangeInChats(chatIDPrefix string, messageID int) (Exchange, error)
+	LatestExchange(chatID string) (Exchange, error)
+	ExchangeByPrompt(userID int64, messageID int) (Exchange, error)
+	SetExchangeMessage(id string, messageID int) error
+	SetFeedback(userID int64, messageID int, feedback int) (bool, error)
+	SearchExchanges(userID int64, chatIDPrefix, query string, limit int) ([]SearchResult, error)
+	ActiveUsers(since time.Time) (int, error)
+	UsageStats(topModels int) (UsageStats, error)
+	LastActive(userID int64) (time.Time, error)
+	DeleteSession(userID int64) error
+	ExpireSessions(before time.Time) (int64, error)
+
+	GetSummary(userID int64, chatID string) (Summary, error)
+	SaveSummary(userID int64, chatID, content string, exchangeIDs []string) error
+
+	CreateChat(userID int64, title string) (Chat, error)
+	GetChat(userID int64, id string) (Chat, error)
+	GetChats(userID int64) ([]Chat, error)
+	SetChatTitle(id, title string) error
+
+	SaveMemory(m Memory) error
+	GetMemories(userID int64) ([]Memory, error)
+	DeleteMemories(conversationID string) error
+
+	SaveDocumentChunks(userID int64, fileName string, chunks []string) error
+	GetDocumentChunks(userID int64) ([]DocumentChunk, error)
+	DeleteDocumentChunks(userID int64) error
+
+	SaveAuditEntry(e AuditEntry) error
+	GetAuditEntries(username string, limit int) ([]AuditEntry, error)
+	DeleteAuditEntriesBefore(t time.Time) (int64, error)
+
+	SaveViolation(v Violation) error
+	GetViolations(username string, limit int) ([]Violation, error)
+
+	SaveAPIKey(userID int64, ciphertext []byte) error
+	GetAPIKey(userID int64) ([]byte, error)
+	DeleteAPIKey(userID int64) error
+	SaveGatewayKey(userID int64, hash string) error
+	GatewayKeyUser(hash string) (int64, error)
+	GatewayKeyCreated(userID int64) (time.Time, error)
+	DeleteGatewayKey(userID int64) error
+
+	SaveUsage(u Usage) error
+	CountRequests(userID int64, since time.Time) (int, error)
+	UsageTotals(since time.Time) ([]UsageTotal, error)
+
+	SaveCachedResponse(r CachedResponse) error
+	GetCachedResponse(promptHash string, since time.Time) (CachedResponse, error)
+	DeleteCachedResponsesBefore(t time.Time) (int64, error)
+
+	SavePin(ex Exchange) (bool, error)
+	GetPins(userID int64) ([]Pin, error)
+	DeletePin(userID int64, id string) (bool, error)
+
+	SaveTemplate(t Template) error
+	GetTemplate(userID int64, name string) (Template, error)
+	GetTemplates(userID int64) ([]Template, error)
+	DeleteTemplate(userID int64, name string) (bool, error)
+
+	GetPreference(userID int64, key string) (string, error)
+	SetPreference(userID int64, key, value string) error
+
+	GetChatPrompt(chatID int64) (string, error)
+	SetChatPrompt(chatID int64, content string, userID int64) error
+	DeleteChatPrompt(chatID int64) (bool, error)
+	GetTopicPrompt(chatID int64, threadID int) (string, error)
+	SetTopicPrompt(chatID int64, threadID int, content string, userID int64) error
+	DeleteTopicPrompt(chatID int64, threadID int) (bool, error)
+
+	SaveImageGeneration(userID int64, prompt string) error
+	CountImageGenerations(userID int64, since time.Time) (int, error)
+
+	SaveReminder(r Reminder) error
+	GetReminders(userID int64) ([]Reminder, error)
+	DueReminders(now time.Time) ([]Reminder, error)
+	SetReminderNextRun(id string, next time.Time) error
+	DeleteReminder(userID int64, id string) error
+
+	SaveDigest(dg Digest) error
+	GetDigest(userID int64) (Digest, error)
+	DeleteDigest(userID int64) (bool, error)
+	DueDigests(now time.Time) ([]Digest, error)
+	SetDigestNextRun(userID int64, next time.Time) error
+
+	GetSubscription(userID int64) (Subscription, error)
+	SavePayment(p Payment, sub Subscription) error
+
+	SaveShare(s Share) error
+	GetShare(token string) (Share, error)
+	DeleteShares(userID int64) (int, error)
+
+	SaveJob(j Job) error
+	DeleteJob(id string) error
+	PendingJobs() ([]Job, error)
+	SetJobAttempts(id string, attempts int) error
+
+	DeleteUserDataBefore(userID int64, t time.Time) (map[string]int64, error)
+	PurgeUserData(userID int64) (map[string]int64, error)
+	Deletions(userID int64) ([]Deletion, error)
+
+	SetQuietHours(q QuietHours) error
+	GetQuietHours(userID int64) (QuietHours, error)
+	DeleteQuietHours(userID int64) (bool, error)
+	HoldMessage(m HeldMessage) error
+	HeldMessages() ([]HeldMessage, error)
+	DeleteHeldMessage(id string) error
+
+	Flags() ([]Flag, error)
+	GetFlag(name string) (Flag, error)
+	SetFlag(name string, percent int, updatedBy int64) error
+	DeleteFlag(name string) error
+	FlagOverrides(name string) ([]FlagOverride, error)
+	SetFlagOverride(name string, userID int64, enabled bool) error
+	DeleteFlagOverride(name string, userID int64) (bool, error)
+}
+
+type dialect string
+
+const (
+	sqlite   dialect = "sqlite3"
+	postgres dialect = "postgres"
+)
+
+// SQLite drivers SQLITE_DRIVER picks from: CgoSQLite is mattn/go-sqlite3,
+// only there in binaries built with cgo, PureGoSQLite modernc.org/sqlite,
+// which cross-compiles to a static binary.
+const (
+	CgoSQLite    = "cgo"
+	PureGoSQLite = "purego"
+)
+
+// sqliteDrivers are the database/sql names of the SQLite drivers.
+var sqliteDrivers = map[string]string{CgoSQLite: "sqlite3", PureGoSQLite: "sqlite"}
+
+// DefaultSQLiteDriver is CgoSQLite when the binary was built with cgo.
+func DefaultSQLiteDriver() string {
+	if cgoSQLite {
+		return CgoSQLite
+	}
+	return PureGoSQLite
+}
+
+type DB struct {
+	db      *sqlx.DB
+	dialect dialect
+	// driver is the database/sql driver the database was opened with.
+	driver string
+	// fts is whether exchanges are searched with SQLite's full-text index.
+	fts bool
+	// writes queues writers so SQLite only ever sees one at a time instead
+	// of failing with "database is locked".
+	writes *sync.Mutex
+	// ctx is what queries are traced under, nil leaving them untraced.
+	ctx context.Context
+}
+
+// sqliteParams turn on WAL, so reads don't wait on the writer, and make a
+// connection wait for a lock instead of failing straight away. The pure Go
+// driver also stores times the way mattn/go-sqlite3 does, so either can
+// open the same file.
+var sqliteParams = map[string]string{
+	"sqlite3": "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate",
+	"sqlite":  "_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite",
+}
+
+// Open opens the database at databaseURL, a postgres:// URL selecting
+// Postgres. SQLite's ./sqlite.db is used when it is empty, with
+// sqliteDriver, or DefaultSQLiteDriver when that's empty.
+func Open(databaseURL, sqliteDriver string) (Store, error) {
+	path, ok := sqlitePath(databaseURL)
+	if !ok {
+		return openDB(postgres, "postgres", databaseURL)
+	}
+	if sqliteDriver == "" {
+		sqliteDriver = DefaultSQLiteDriver()
+	}
+	driver, ok := sqliteDrivers[sqliteDriver]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQLite driver %q, use %s or %s", sqliteDriver, CgoSQLite, PureGoSQLite)
+	}
+	if sqliteDriver == CgoSQLite && !cgoSQLite {
+		return nil, fmt.Errorf("this binary was built without cgo, use the %s SQLite driver", PureGoSQLite)
+	}
+	return openDB(sqlite, driver, path)
+}
+
+// sqlitePath is the SQLite file databaseURL points to, false for Postgres.
+func sqlitePath(databaseURL string) (string, bool) {
+	if databaseURL == "" {
+		return "./sqlite.db", true
+	}
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		return "", false
+	}
+	return strings.TrimPrefix(databaseURL, "sqlite://"), true
+}
+
+// NewMemory returns a store backed by a private in-memory SQLite database
+// with its tables already created, for tests.
+func NewMemory() (Store, error) {
+	d, err := openDB(sqlite, sqliteDrivers[DefaultSQLiteDriver()], ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to :memory: gets its own empty database.
+	d.db.SetMaxOpenConns(1)
+	if err := d.CreateTables(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func openDB(d dialect, driver, dsn string) (*DB, error) {
+	if d == sqlite {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + sqliteParams[driver]
+	}
+
+	db, err := sqlx.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d == sqlite {
+		db.SetMaxOpenConns(8)
+		db.SetMaxIdleConns(8)
+	} else {
+		db.SetMaxOpenConns(20)
+		db.SetMaxIdleConns(5)
+	}
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return &DB{db: db, dialect: d, driver: driver, writes: &sync.Mutex{}}, nil
+}
+
+// WithContext returns the store with its queries traced as children of the
+// span ctx carries.
+func (d *DB) WithContext(ctx context.Context) Store {
+	traced := *d
+	traced.ctx = ctx
+	return &traced
+}
+
+// trace starts a db_query span for query when the store is traced,
+// returning what ends it.
+func (d *DB) trace(query string) func(error) {
+	if d.ctx == nil {
+		return func(error) {}
+	}
+	_, span := tracing.Start(d.ctx, "db_query", tracing.Client)
+	system := "postgresql"
+	if d.dialect == sqlite {
+		system = "sqlite"
+	}
+	span.Set("db.system", system)
+	span.Set("db.statement", strings.Join(strings.Fields(query), " "))
+	return func(err error) {
+		if err != sql.ErrNoRows {
+			span.Fail(err)
+		}
+		span.End()
+	}
+}
+
+func (d *DB) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// ddl adapts schema written for SQLite to the store's dialect.
+func (d *DB) ddl(schema string) string {
+	if d.dialect != postgres {
+		return schema
+	}
+	return strings.NewReplacer(
+		"INTEGER", "BIGINT",
+		"DATETIME", "TIMESTAMPTZ",
+		"BLOB", "BYTEA",
+	).Replace(schema)
+}
+
+// lockWrites takes the write queue on SQLite and returns its release.
+// Postgres handles concurrent writers itself.
+func (d *DB) lockWrites() func() {
+	if d.dialect != sqlite {
+		return func() {}
+	}
+	d.writes.Lock()
+	return d.writes.Unlock
+}
+
+// exec, get and selectAll rebind ? placeholders for the dialect.
+func (d *DB) exec(query string, args ...any) (sql.Result, error) {
+	defer d.lockWrites()()
+	end := d.trace(query)
+	res, err := d.db.Exec(d.db.Rebind(query), args...)
+	end(err)
+	return res, err
+}
+
+func (d *DB) namedExec(query string, arg any) (sql.Result, error) {
+	defer d.lockWrites()()
+	end := d.trace(query)
+	res, err := d.db.NamedExec(query, arg)
+	end(err)
+	return res, err
+}
+
+func (d *DB) get(dest any, query string, args ...any) error {
+	end := d.trace(query)
+	err := d.db.Get(dest, d.db.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+func (d *DB) selectAll(dest any, query string, args ...any) error {
+	end := d.trace(query)
+	err := d.db.Select(dest, d.db.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+// tx is a transaction holding the write queue until it commits or rolls
+// back.
+type tx struct {
+	*sqlx.Tx
+	release sync.Once
+	unlock  func()
+	// end ends the transaction's span.
+	end func(error)
+}
+
+func (d *DB) begin() (*tx, error) {
+	unlock := d.lockWrites()
+	end := d.trace("transaction")
+	t, err := d.db.Beginx()
+	if err != nil {
+		unlock()
+		end(err)
+		return nil, err
+	}
+	return &tx{Tx: t, unlock: unlock, end: end}, nil
+}
+
+func (t *tx) Commit() error {
+	err := t.Tx.Commit()
+	t.release.Do(func() {
+		t.unlock()
+		t.end(err)
+	})
+	return err
+}
+
+func (t *tx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.release.Do(func() {
+		t.unlock()
+		t.end(nil)
+	})
+	return err
+}
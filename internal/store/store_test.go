@@ -0,0 +1,199 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// testDrivers are the SQLite drivers built in.
+func testDrivers() []string {
+	if cgoSQLite {
+		return []string{CgoSQLite, PureGoSQLite}
+	}
+	return []string{PureGoSQLite}
+}
+
+// newTestDB opens an in-memory database with driver and creates its tables.
+func newTestDB(t *testing.T, driver string) *DB {
+	t.Helper()
+	d, err := openDB(sqlite, sqliteDrivers[driver], ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.db.SetMaxOpenConns(1)
+	t.Cleanup(func() { d.db.Close() })
+	if err := d.CreateTables(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMigrations(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(driver, func(t *testing.T) {
+			d := newTestDB(t, driver)
+			applied, latest, err := d.SchemaVersion()
+			if err != nil || applied != latest || latest != len(migrations) {
+				t.Fatalf("schema at %d of %d, %v, want every migration applied", applied, latest, err)
+			}
+			// Running it again is how every restart goes.
+			if err := d.CreateTables(); err != nil {
+				t.Fatalf("CreateTables again: %v", err)
+			}
+			if again, _, _ := d.SchemaVersion(); again != applied {
+				t.Errorf("schema went from %d to %d on a second run", applied, again)
+			}
+
+			// Columns added by migrations are there.
+			ex := &Exchange{UserID: 1, Prompt: "p", Response: "r", MessageID: 7}
+			if err := d.SaveExchange(ex); err != nil {
+				t.Fatal(err)
+			}
+			if rated, err := d.SetFeedback(1, 7, 1); err != nil || !rated {
+				t.Errorf("SetFeedback: %v, %v", rated, err)
+			}
+		})
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(driver, func(t *testing.T) {
+			d := newTestDB(t, driver)
+			// Without FTS5, e.g. cgo builds without the sqlite_fts5 tag,
+			// searches match words instead and should find the same.
+			if driver == PureGoSQLite && !d.fts {
+				t.Fatal("the pure Go driver has FTS5, want the full-text index set up")
+			}
+			search := func(query string) []SearchResult {
+				t.Helper()
+				results, err := d.SearchExchanges(1, "", query, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return results
+			}
+
+			parrot := &Exchange{UserID: 1, Prompt: "tell me about parrots", Response: "they talk"}
+			if err := d.SaveExchange(parrot); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.SaveExchange(&Exchange{UserID: 2, Prompt: "parrots again", Response: "still talking"}); err != nil {
+				t.Fatal(err)
+			}
+			if got := search("parrots"); len(got) != 1 || got[0].ID != parrot.ID {
+				t.Fatalf("found %+v, want only user 1's parrot", got)
+			}
+
+			// The update trigger swaps what's indexed.
+			parrot.Response = "they mimic"
+			if err := d.UpdateExchange(*parrot); err != nil {
+				t.Fatal(err)
+			}
+			if got := search("talk"); len(got) != 0 {
+				t.Errorf("found %d for the old answer, want none", len(got))
+			}
+			if got := search("mimic"); len(got) != 1 {
+				t.Errorf("found %d for the new answer, want 1", len(got))
+			}
+
+			// And the delete trigger drops it.
+			if err := d.DeleteExchange(parrot.ID); err != nil {
+				t.Fatal(err)
+			}
+			if got := search("parrots"); len(got) != 0 {
+				t.Errorf("found %d after deleting, want none", len(got))
+			}
+		})
+	}
+}
+
+func TestRetention(t *testing.T) {
+	d := newTestDB(t, DefaultSQLiteDriver())
+	old, recent := &Exchange{UserID: 1, Prompt: "old"}, &Exchange{UserID: 1, Prompt: "recent"}
+	other := &Exchange{UserID: 2, Prompt: "someone else's"}
+	for _, ex := range []*Exchange{old, recent, other} {
+		if err := d.SaveExchange(ex); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SaveMemory(Memory{UserID: 1, ConversationID: old.ID, Content: "old", Embedding: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveSummary(1, "", "old", nil); err != nil {
+		t.Fatal(err)
+	}
+	monthAgo := time.Now().Add(-30 * 24 * time.Hour)
+	if _, err := d.exec("UPDATE conversations SET created_at=? WHERE id IN (?, ?)", monthAgo, old.ID, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.exec("UPDATE memories SET created_at=?", monthAgo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.exec("UPDATE summaries SET updated_at=?", monthAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := d.DeleteUserDataBefore(1, time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted["conversations"] != 1 || deleted["memories"] != 1 || deleted["summaries"] != 1 {
+		t.Errorf("deleted %v, want the old exchange, its memory and summary", deleted)
+	}
+
+	left, err := d.AllExchanges(1)
+	if err != nil || len(left) != 1 || left[0].ID != recent.ID {
+		t.Errorf("kept %+v, %v, want only the recent exchange", left, err)
+	}
+	if others, _ := d.AllExchanges(2); len(others) != 1 {
+		t.Errorf("user 2 has %d exchanges left, want theirs untouched", len(others))
+	}
+
+	deletions, err := d.Deletions(1)
+	if err != nil || len(deletions) != 1 || deletions[0].Reason != "retention" {
+		t.Fatalf("recorded %+v, %v, want the retention deletion", deletions, err)
+	}
+
+	// Nothing left to delete records nothing.
+	if _, err := d.DeleteUserDataBefore(1, time.Now().Add(-7*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if deletions, _ := d.Deletions(1); len(deletions) != 1 {
+		t.Errorf("recorded %d deletions, want still 1", len(deletions))
+	}
+}
+
+func TestPurge(t *testing.T) {
+	d := newTestDB(t, DefaultSQLiteDriver())
+	if err := d.SaveExchange(&Exchange{UserID: 1, Prompt: "hi", Response: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveUsage(Usage{UserID: 1, Model: "m", Request: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveImageGeneration(1, "a cat"); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := d.PurgeUserData(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted["conversations"] != 1 || deleted["image_generations.prompt"] != 1 || deleted["usage"] != 0 {
+		t.Errorf("deleted %v, want the exchange gone and the image prompt blanked", deleted)
+	}
+
+	// What the quotas count from is still there.
+	today := time.Now().Add(-time.Hour)
+	if n, err := d.CountRequests(1, today); err != nil || n != 1 {
+		t.Errorf("counted %d requests, %v, want the one from before the purge", n, err)
+	}
+	if n, err := d.CountImageGenerations(1, today); err != nil || n != 1 {
+		t.Errorf("counted %d images, %v, want the one from before the purge", n, err)
+	}
+	var prompt string
+	if err := d.get(&prompt, "SELECT prompt FROM image_generations WHERE user_id=?", 1); err != nil || prompt != "" {
+		t.Errorf("image prompt is %q, %v, want it blanked", prompt, err)
+	}
+}
@@ -0,0 +1,51 @@
+package store
+
+import "time"
+
+// Subscription is the paid plan a user is on until ExpiresAt.
+type Subscription struct {
+	UserID    int64     `db:"user_id"`
+	Plan      string    `db:"plan"`
+	ExpiresAt time.Time `db:"expires_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Payment is a Telegram Stars purchase of a plan.
+type Payment struct {
+	// ChargeID is Telegram's ID of the charge, needed to refund it.
+	ChargeID  string    `db:"charge_id"`
+	UserID    int64     `db:"user_id"`
+	Plan      string    `db:"plan"`
+	Stars     int       `db:"stars"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// GetSubscription returns the user's subscription, expired or not,
+// sql.ErrNoRows when they never had one.
+func (d *DB) GetSubscription(userID int64) (Subscription, error) {
+	var sub Subscription
+	err := d.get(&sub, "SELECT * FROM subscriptions WHERE user_id=?", userID)
+	return sub, err
+}
+
+// SavePayment records the payment and the subscription it bought, which
+// replaces the user's earlier one.
+func (d *DB) SavePayment(p Payment, sub Subscription) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(tx.Rebind("INSERT INTO payments(charge_id, user_id, plan, stars, created_at) VALUES(?, ?, ?, ?, ?)"),
+		p.ChargeID, p.UserID, p.Plan, p.Stars, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(tx.Rebind(`INSERT INTO subscriptions(user_id, plan, expires_at, updated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET plan=excluded.plan, expires_at=excluded.expires_at, updated_at=excluded.updated_at`),
+		sub.UserID, sub.Plan, sub.ExpiresAt.UTC(), now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
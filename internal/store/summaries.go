@@ -0,0 +1,44 @@
+package store
+
+import "time"
+
+type Summary struct {
+	UserID    int64     `db:"user_id"`
+	ChatID    string    `db:"chat_id"`
+	Content   string    `db:"content"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (d *DB) UnsummarizedExchanges(userID int64, chatID string) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := d.selectAll(&exchanges, "SELECT * FROM conversations WHERE user_id=? AND chat_id=? AND summarized=0 ORDER BY created_at", userID, chatID)
+	return exchanges, err
+}
+
+func (d *DB) GetSummary(userID int64, chatID string) (Summary, error) {
+	var s Summary
+	err := d.get(&s, "SELECT * FROM summaries WHERE user_id=? AND chat_id=?", userID, chatID)
+	return s, err
+}
+
+// SaveSummary replaces the chat's summary and marks the exchanges it covers
+// as summarized so they drop out of the context.
+func (d *DB) SaveSummary(userID int64, chatID, content string, exchangeIDs []string) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(tx.Rebind(`INSERT INTO summaries(user_id, chat_id, content, updated_at) VALUES(?, ?, ?, ?)
+ON CONFLICT(user_id, chat_id) DO UPDATE SET content=excluded.content, updated_at=excluded.updated_at`), userID, chatID, content, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, id := range exchangeIDs {
+		if _, err := tx.Exec(tx.Rebind("UPDATE conversations SET summarized=1 WHERE id=?"), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+package store
+
+import "time"
+
+// GlobalTemplates is the user ID admin-shared templates are saved under.
+const GlobalTemplates int64 = 0
+
+type Template struct {
+	UserID    int64     `db:"user_id"`
+	Name      string    `db:"name"`
+	Content   string    `db:"content"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SaveTemplate creates the template or replaces the one with the same name.
+func (d *DB) SaveTemplate(t Template) error {
+	t.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO templates(user_id, name, content, created_at) VALUES(:user_id, :name, :content, :created_at)
+ON CONFLICT(user_id, name) DO UPDATE SET content=excluded.content, created_at=excluded.created_at`, t)
+	return err
+}
+
+// GetTemplate returns the user's template called name, falling back to the
+// shared one.
+func (d *DB) GetTemplate(userID int64, name string) (Template, error) {
+	var t Template
+	err := d.get(&t, `SELECT * FROM templates WHERE name=? AND user_id IN (?, ?)
+ORDER BY CASE WHEN user_id=? THEN 0 ELSE 1 END LIMIT 1`, name, userID, GlobalTemplates, userID)
+	return t, err
+}
+
+// GetTemplates returns the user's templates and the shared ones by name.
+func (d *DB) GetTemplates(userID int64) ([]Template, error) {
+	var templates []Template
+	err := d.selectAll(&templates, "SELECT * FROM templates WHERE user_id IN (?, ?) ORDER BY name, user_id DESC", userID, GlobalTemplates)
+	return templates, err
+}
+
+func (d *DB) DeleteTemplate(userID int64, name string) (bool, error) {
+	res, err := d.exec("DELETE FROM templates WHERE user_id=? AND name=?", userID, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
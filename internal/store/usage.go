@@ -0,0 +1,62 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Usage is the tokens one completion consumed.
+type Usage struct {
+	ID               string    `db:"id"`
+	UserID           int64     `db:"user_id"`
+	Username         string    `db:"username"`
+	Model            string    `db:"model"`
+	PromptTokens     int       `db:"prompt_tokens"`
+	CompletionTokens int       `db:"completion_tokens"`
+	CreatedAt        time.Time `db:"created_at"`
+	Workspace        string    `db:"workspace"`
+	// Request is set on completions the user asked for, which count toward
+	// their daily message quota, and not on the bot's own like moderation.
+	Request bool `db:"request"`
+}
+
+// UsageTotal adds up a user's usage of one model in one workspace.
+type UsageTotal struct {
+	UserID           int64  `db:"user_id"`
+	Username         string `db:"username"`
+	Workspace        string `db:"workspace"`
+	Model            string `db:"model"`
+	PromptTokens     int    `db:"prompt_tokens"`
+	CompletionTokens int    `db:"completion_tokens"`
+}
+
+func (d *DB) SaveUsage(u Usage) error {
+	u.ID = ulid.Make().String()
+	u.CreatedAt = time.Now()
+	request := 0
+	if u.Request {
+		request = 1
+	}
+	_, err := d.exec(`INSERT INTO usage(id, user_id, username, model, prompt_tokens, completion_tokens, created_at, workspace, request)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`, u.ID, u.UserID, u.Username, u.Model, u.PromptTokens, u.CompletionTokens, u.CreatedAt, u.Workspace, request)
+	return err
+}
+
+// CountRequests counts the completions the user asked for since the given
+// time.
+func (d *DB) CountRequests(userID int64, since time.Time) (int, error) {
+	var n int
+	err := d.get(&n, "SELECT COUNT(*) FROM usage WHERE user_id=? AND request=1 AND created_at >= ?", userID, since)
+	return n, err
+}
+
+// UsageTotals returns usage since the given time per user, workspace and
+// model.
+func (d *DB) UsageTotals(since time.Time) ([]UsageTotal, error) {
+	var totals []UsageTotal
+	err := d.selectAll(&totals, `SELECT user_id, MAX(username) AS username, workspace, model,
+	SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens
+FROM usage WHERE created_at >= ? GROUP BY user_id, workspace, model`, since)
+	return totals, err
+}
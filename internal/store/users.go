@@ -0,0 +1,108 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type User struct {
+	ID string `db:"id"`
+	// UserID is the Telegram user ID, zero for users who authenticated
+	// before it was kept and haven't been seen since.
+	UserID   int64  `db:"user_id"`
+	Username string `db:"username"`
+	Token    string `db:"token"`
+	// CreatedAt is null for users who authenticated before it was kept.
+	CreatedAt sql.NullTime `db:"created_at"`
+	// Workspace is the one the user's token joined, "" for none.
+	Workspace string `db:"workspace"`
+}
+
+// ErrUserNotFound is returned when there's no user with the ID or username.
+var ErrUserNotFound = errors.New("user not found")
+
+// CreateUser saves the user, or replaces the token, username and workspace
+// of the one with userID.
+func (d *DB) CreateUser(userID int64, username, token, workspace string) error {
+	id := ulid.Make().String()
+	_, err := d.exec(`INSERT INTO users(id, user_id, username, token, created_at, workspace) VALUES(?, ?, ?, ?, ?, ?)
+ON CONFLICT(user_id) WHERE user_id <> 0 DO UPDATE SET username=excluded.username, token=excluded.token, workspace=excluded.workspace`, id, userID, username, token, time.Now(), workspace)
+	return err
+}
+
+func (d *DB) GetUser(userID int64) (User, error) {
+	return d.getUser("SELECT * FROM users WHERE user_id=?", userID)
+}
+
+func (d *DB) GetUserByUsername(username string) (User, error) {
+	return d.getUser("SELECT * FROM users WHERE username=?", username)
+}
+
+func (d *DB) getUser(query string, arg any) (User, error) {
+	var user User
+	err := d.get(&user, query, arg)
+	if err == sql.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// GetUsers returns every user, oldest first.
+func (d *DB) GetUsers() ([]User, error) {
+	var users []User
+	err := d.selectAll(&users, "SELECT * FROM users ORDER BY created_at, id")
+	return users, err
+}
+
+// RevokeUser removes the user's account, keeping what's stored about them,
+// so they have to authenticate again. It reports whether there was one.
+func (d *DB) RevokeUser(userID int64) (bool, error) {
+	res, err := d.exec("DELETE FROM users WHERE user_id=?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClaimUser sets the Telegram user ID of a user who authenticated by
+// username only, reporting whether there was one to claim.
+func (d *DB) ClaimUser(userID int64, username string) (bool, error) {
+	res, err := d.exec("UPDATE users SET user_id=? WHERE user_id=0 AND username=?", userID, username)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// userTables are the tables holding a user's data, keyed by user_id.
+var userTables = []string{
+	"conversations", "chats", "summaries", "memories", "document_chunks", "reminders",
+	"api_keys", "preferences", "templates", "image_generations", "usage", "audit_log",
+	"moderation_violations", "pins", "digests", "subscriptions", "payments", "shares", "jobs",
+	"feature_flag_users", "quiet_hours", "held_messages", "gateway_keys",
+}
+
+// DeleteUser removes the user's account and everything stored about them.
+func (d *DB) DeleteUser(userID int64) error {
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range userTables {
+		if _, err := tx.Exec(tx.Rebind("DELETE FROM "+table+" WHERE user_id=?"), userID); err != nil {
+			return fmt.Errorf("could not delete from %s: %v", table, err)
+		}
+	}
+	if _, err := tx.Exec(tx.Rebind("DELETE FROM users WHERE user_id=?"), userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
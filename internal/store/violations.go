@@ -0,0 +1,37 @@
+package store
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type Violation struct {
+	ID         string    `db:"id"`
+	UserID     int64     `db:"user_id"`
+	Username   string    `db:"username"`
+	Categories string    `db:"categories"`
+	Prompt     string    `db:"prompt"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (d *DB) SaveViolation(v Violation) error {
+	v.ID = ulid.Make().String()
+	v.CreatedAt = time.Now()
+	_, err := d.namedExec(`INSERT INTO moderation_violations(id, user_id, username, categories, prompt, created_at)
+VALUES(:id, :user_id, :username, :categories, :prompt, :created_at)`, v)
+	return err
+}
+
+// GetViolations returns the latest violations, for every user when
+// username is empty.
+func (d *DB) GetViolations(username string, limit int) ([]Violation, error) {
+	var violations []Violation
+	var err error
+	if username == "" {
+		err = d.selectAll(&violations, "SELECT * FROM moderation_violations ORDER BY created_at DESC LIMIT ?", limit)
+	} else {
+		err = d.selectAll(&violations, "SELECT * FROM moderation_violations WHERE username=? ORDER BY created_at DESC LIMIT ?", username, limit)
+	}
+	return violations, err
+}
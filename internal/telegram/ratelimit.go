@@ -0,0 +1,226 @@
+// Package telegram keeps the bot's calls to the Bot API within Telegram's
+// rate limits.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// groupRate is the 20 messages a minute Telegram allows in a group.
+	groupRate = 20.0 / 60
+	// burst is how many calls a chat can make at once before its bucket
+	// makes them wait, e.g. an answer followed by its first edits.
+	burst = 3
+	// maxRetries and maxRetryAfter bound how long a call waits out 429s
+	// before the error is handed back.
+	maxRetries    = 3
+	maxRetryAfter = time.Minute
+	// idleBucket is how long a chat's bucket is kept after its last call.
+	idleBucket = 10 * time.Minute
+)
+
+// bucket is a token bucket: calls take a token, tokens come back at rate
+// a second up to size, and none are handed out before blocked.
+type bucket struct {
+	rate    float64
+	size    float64
+	tokens  float64
+	last    time.Time
+	blocked time.Time
+}
+
+func newBucket(rate, size float64) *bucket {
+	return &bucket{rate: rate, size: size, tokens: size, last: time.Now()}
+}
+
+// take takes a token, or returns how long until one is available.
+func (b *bucket) take(now time.Time) time.Duration {
+	if now.Before(b.blocked) {
+		return b.blocked.Sub(now)
+	}
+	b.tokens = min(b.size, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Transport is an http.RoundTripper for telebot's client that spaces out
+// Bot API calls: all of them share a global bucket, and calls for a chat
+// also take from that chat's. Calls Telegram answers with 429 Too Many
+// Requests are retried after the retry_after it asks for.
+type Transport struct {
+	next    http.RoundTripper
+	perChat float64
+
+	mu sync.Mutex
+	// global is nil without a global limit.
+	global *bucket
+	chats  map[string]*bucket
+}
+
+// NewTransport limits the calls made through next to global a second and
+// perChat a second in each private chat, groups getting Telegram's 20 a
+// minute. Zero lifts the limit.
+func NewTransport(next http.RoundTripper, global, perChat float64) *Transport {
+	t := &Transport{next: next, perChat: perChat, chats: map[string]*bucket{}}
+	if global > 0 {
+		t.global = newBucket(global, max(global, 1))
+	}
+	return t
+}
+
+// chatBucket returns the chat's bucket, nil when the chat has no limit.
+func (t *Transport) chatBucket(chatID string, now time.Time) *bucket {
+	b, ok := t.chats[chatID]
+	if ok {
+		return b
+	}
+	if len(t.chats) > 1000 {
+		for id, old := range t.chats {
+			if now.Sub(old.last) > idleBucket && !now.Before(old.blocked) {
+				delete(t.chats, id)
+			}
+		}
+	}
+	rate := t.perChat
+	if strings.HasPrefix(chatID, "-") {
+		rate = groupRate
+	}
+	if rate <= 0 {
+		return nil
+	}
+	b = newBucket(rate, burst)
+	t.chats[chatID] = b
+	return b
+}
+
+// wait blocks until the chat's bucket, when there is a chat, and the
+// global one both hand out a token.
+func (t *Transport) wait(ctx context.Context, chatID string) error {
+	for _, global := range []bool{false, true} {
+		if !global && chatID == "" {
+			continue
+		}
+		for {
+			t.mu.Lock()
+			now := time.Now()
+			b := t.global
+			if !global {
+				b = t.chatBucket(chatID, now)
+			}
+			var d time.Duration
+			if b != nil {
+				d = b.take(now)
+			}
+			t.mu.Unlock()
+			if d == 0 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d):
+			}
+		}
+	}
+	return nil
+}
+
+// block holds back the chat's calls, everyone's without a chat, for d.
+func (t *Transport) block(chatID string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	b := t.global
+	if chatID != "" {
+		b = t.chatBucket(chatID, now)
+	}
+	if b != nil {
+		b.blocked = now.Add(d)
+	}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	method := path.Base(r.URL.Path)
+	if method == "getUpdates" || !strings.HasPrefix(r.URL.Path, "/bot") {
+		return t.next.RoundTrip(r)
+	}
+	chatID := chatOf(r)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(r.Context(), chatID); err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		retryAfter := retryAfter(data)
+		slog.Warn("Telegram rate limited a call", "method", method, "chat_id", chatID, "retry_after", retryAfter)
+		t.block(chatID, retryAfter)
+		// Uploads stream their body from a pipe and can't be sent again.
+		if attempt == maxRetries || retryAfter > maxRetryAfter || r.GetBody == nil {
+			return resp, nil
+		}
+		body, err := r.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		r = r.Clone(r.Context())
+		r.Body = body
+	}
+}
+
+// chatOf reads the chat_id of a JSON call. Uploads are multipart and only
+// take from the global bucket.
+func chatOf(r *http.Request) string {
+	if r.GetBody == nil || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var params struct {
+		ChatID json.RawMessage `json:"chat_id"`
+	}
+	if json.NewDecoder(body).Decode(&params) != nil {
+		return ""
+	}
+	return strings.Trim(string(params.ChatID), `"`)
+}
+
+// retryAfter is how long a 429 answer asks to wait, a second when it
+// doesn't say.
+func retryAfter(data []byte) time.Duration {
+	var res struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.Unmarshal(data, &res) != nil || res.Parameters.RetryAfter <= 0 {
+		return time.Second
+	}
+	return time.Duration(res.Parameters.RetryAfter) * time.Second
+}
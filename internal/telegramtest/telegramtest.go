@@ -0,0 +1,139 @@
+// Package telegramtest provides a fake Telegram Bot API for tests.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is the bot token the fake server expects in request paths.
+const Token = "123456:test-token"
+
+// Me is the bot the fake server says it is.
+var Me = map[string]any{"id": 123456, "is_bot": true, "first_name": "groqy", "username": "groqy_test_bot"}
+
+// Call is one Bot API request, its parameters flattened to strings.
+type Call struct {
+	Method string
+	Params map[string]string
+	// MessageID is the message the call sent or edited.
+	MessageID int
+}
+
+// Server records every call made to it and answers them the way Telegram
+// would, with made up message IDs.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	calls  []Call
+	nextID int
+}
+
+// NewServer starts a fake Bot API. Point telebot's Settings.URL at its URL
+// and use Token.
+func NewServer() *Server {
+	s := &Server{nextID: 1000}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Calls returns the calls made so far, oldest first.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Sent returns the text of every message sent or edited since call n, n
+// being a previous len(Calls()).
+func (s *Server) Sent(n int) []string {
+	var texts []string
+	for _, c := range s.Calls()[n:] {
+		switch c.Method {
+		case "sendMessage", "editMessageText":
+			texts = append(texts, c.Params["text"])
+		case "sendDocument", "sendPhoto", "sendVoice":
+			texts = append(texts, c.Params["caption"])
+		}
+	}
+	return texts
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+Token+"/")
+	if !ok {
+		http.Error(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	params, err := parseParams(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok":false,"error_code":400,"description":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	call := Call{Method: method, Params: params}
+	var result any = true
+	switch method {
+	case "getMe":
+		result = Me
+	case "sendMessage", "sendDocument", "sendPhoto", "sendVoice", "sendInvoice":
+		s.nextID++
+		call.MessageID = s.nextID
+		result = message(call.MessageID, params)
+	case "editMessageText", "editMessageReplyMarkup":
+		call.MessageID, _ = strconv.Atoi(params["message_id"])
+		result = message(call.MessageID, params)
+	}
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func message(id int, params map[string]string) map[string]any {
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	return map[string]any{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]any{"id": chatID, "type": "private"},
+		"from":       Me,
+		"text":       params["text"],
+		"caption":    params["caption"],
+	}
+}
+
+// parseParams reads a JSON or multipart request into strings, the way
+// telebot sends them.
+func parseParams(r *http.Request) (map[string]string, error) {
+	params := map[string]string{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(50 << 20); err != nil {
+			return nil, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+		return params, nil
+	}
+
+	var raw map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			params[k] = s
+			continue
+		}
+		data, _ := json.Marshal(v)
+		params[k] = string(data)
+	}
+	return params, nil
+}
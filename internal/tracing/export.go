@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+	// maxBatch is how many spans go in one export, and maxQueued how many
+	// wait for the next one before new ones are dropped.
+	maxBatch  = 512
+	maxQueued = 4096
+)
+
+// Exporter batches ended spans and posts them as OTLP/HTTP JSON to a
+// collector's /v1/traces.
+type Exporter struct {
+	url     string
+	service string
+	headers map[string]string
+	client  *http.Client
+
+	mu      sync.Mutex
+	queue   []finished
+	dropped int
+}
+
+type finished struct {
+	span *Span
+	end  time.Time
+}
+
+// NewExporter exports to the collector at endpoint, e.g.
+// http://localhost:4318, as service. headers are sent with every export,
+// for collectors that want an API key.
+func NewExporter(endpoint, service string, headers map[string]string) *Exporter {
+	return &Exporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		headers: headers,
+		client:  &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// ParseHeaders reads OTEL_EXPORTER_OTLP_HEADERS' key=value,key=value.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+func (e *Exporter) add(s *Span, end time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, finished{s, end})
+}
+
+// Run exports the queued spans every few seconds until the process exits.
+func (e *Exporter) Run() {
+	for range time.Tick(exportInterval) {
+		if err := e.Flush(); err != nil {
+			slog.Warn("Could not export traces", "err", err)
+		}
+	}
+}
+
+// Flush exports the queued spans.
+func (e *Exporter) Flush() error {
+	for {
+		e.mu.Lock()
+		batch := e.queue[:min(len(e.queue), maxBatch)]
+		e.queue = e.queue[len(batch):]
+		dropped := e.dropped
+		e.dropped = 0
+		e.mu.Unlock()
+		if dropped > 0 {
+			slog.Warn("Dropped spans, the export queue was full", "spans", dropped)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.export(batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *Exporter) export(batch []finished) error {
+	spans := make([]map[string]any, len(batch))
+	for i, f := range batch {
+		spans[i] = f.span.otlp(f.end)
+	}
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": attributes(map[string]any{"service.name": e.service}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "groqy"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// otlp is the span in OTLP's JSON encoding.
+func (s *Span) otlp(end time.Time) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := map[string]any{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        attributes(s.attrs),
+	}
+	if s.parentID != [8]byte{} {
+		span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		span["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+	}
+	return span
+}
+
+// attributes encodes attrs as OTLP key values, anything that isn't a
+// string, bool, integer or float as its string form.
+func attributes(attrs map[string]any) []map[string]any {
+	kvs := make([]map[string]any, 0, len(attrs))
+	for key, v := range attrs {
+		var value map[string]any
+		switch v := v.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		kvs = append(kvs, map[string]any{"key": key, "value": value})
+	}
+	return kvs
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// traceparentHeader carries the trace across HTTP calls, as W3C Trace
+// Context has it.
+const traceparentHeader = "traceparent"
+
+type remoteKey struct{}
+
+// remote is the span of a caller in another process.
+type remote struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// Extract returns ctx carrying the caller's span from h's traceparent, so
+// the spans started under it join the caller's trace. A missing or
+// malformed traceparent leaves ctx as it is.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	r, ok := parseTraceparent(h.Get(traceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, r)
+}
+
+// Inject sets h's traceparent to the span ctx carries, or passes on the
+// caller's when it carries none, e.g. with tracing off.
+func Inject(ctx context.Context, h http.Header) {
+	if s := FromContext(ctx); s != nil {
+		h.Set(traceparentHeader, traceparent(s.traceID, s.spanID))
+	} else if r, ok := ctx.Value(remoteKey{}).(remote); ok {
+		h.Set(traceparentHeader, traceparent(r.traceID, r.spanID))
+	}
+}
+
+// traceparent formats a version 00 traceparent. Every span is exported, so
+// they're all sampled.
+func traceparent(traceID [16]byte, spanID [8]byte) string {
+	return "00-" + hex.EncodeToString(traceID[:]) + "-" + hex.EncodeToString(spanID[:]) + "-01"
+}
+
+// parseTraceparent reads version-traceid-parentid-flags. Later versions may
+// add fields after the flags, which are ignored.
+func parseTraceparent(s string) (remote, bool) {
+	var r remote
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || !isHex(parts[0], 1) || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) || !isHex(parts[3], 1) {
+		return r, false
+	}
+	if !isHex(parts[1], len(r.traceID)) || !isHex(parts[2], len(r.spanID)) {
+		return r, false
+	}
+	hex.Decode(r.traceID[:], []byte(parts[1]))
+	hex.Decode(r.spanID[:], []byte(parts[2]))
+	// All zeros are invalid IDs.
+	return r, r.traceID != [16]byte{} && r.spanID != [8]byte{}
+}
+
+// isHex reports whether s is n bytes in hex.
+func isHex(s string, n int) bool {
+	if len(s) != 2*n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
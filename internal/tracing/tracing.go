@@ -0,0 +1,130 @@
+// Package tracing records spans of the bot's work and exports them to an
+// OpenTelemetry collector over OTLP/HTTP, so a slow update shows where its
+// time went.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type spanKey struct{}
+
+// Span is a named, timed piece of work. A nil Span, which Start returns
+// while tracing is off, ignores everything done to it.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+// Kind says what side of a call a span is on.
+type Kind int
+
+// The OTLP span kinds used.
+const (
+	Internal Kind = 1
+	Server   Kind = 2
+	Client   Kind = 3
+)
+
+var (
+	mu       sync.RWMutex
+	exporter *Exporter
+)
+
+// Enable sends the spans started from now on to e, nil turning tracing off.
+func Enable(e *Exporter) {
+	mu.Lock()
+	exporter = e
+	mu.Unlock()
+}
+
+func current() *Exporter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return exporter
+}
+
+// Start starts a span, the child of the one ctx carries if any, or of the
+// caller's Extract found, and returns ctx carrying it.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if current() == nil {
+		return ctx, nil
+	}
+	s := &Span{name: name, kind: kind, start: time.Now(), attrs: map[string]any{}}
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else if r, ok := ctx.Value(remoteKey{}).(remote); ok {
+		s.traceID = r.traceID
+		s.parentID = r.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the span ctx carries, nil when there is none.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Set adds an attribute, a string, bool, integer or float.
+func (s *Span) Set(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// Fail marks the span as failed with err, when err isn't nil.
+func (s *Span) Fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End ends the span and queues it for export. Only the first End counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+	if e := current(); e != nil {
+		e.add(s, end)
+	}
+}
+
+// TraceID is the span's trace ID in hex, "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
@@ -0,0 +1,263 @@
+// Package web fetches pages for the bot to read. It honours robots.txt,
+// caps how much it downloads and refuses to connect to private addresses.
+package web
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// UserAgent is sent with every request and matched against robots.txt.
+const UserAgent = "groqy"
+
+var (
+	ErrDisallowed  = errors.New("robots.txt doesn't allow fetching this page")
+	ErrTooLarge    = errors.New("page is too large")
+	ErrUnsupported = errors.New("not a web page")
+	errPrivate     = errors.New("private address")
+)
+
+type Page struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+type Fetcher struct {
+	// MaxSize is the most bytes read from a page.
+	MaxSize    int64
+	HTTPClient *http.Client
+}
+
+// NewFetcher returns a fetcher whose client won't connect to loopback,
+// private or link-local addresses, so users can't point it inside the
+// network the bot runs in.
+func NewFetcher(maxSize int64) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("%s: %w", host, errPrivate)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &Fetcher{MaxSize: maxSize, HTTPClient: &http.Client{Transport: transport, Timeout: 30 * time.Second}}
+}
+
+// Fetch downloads rawURL and extracts its readable text.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Page{}, ErrUnsupported
+	}
+	allowed, err := f.allowed(ctx, u)
+	if err != nil {
+		return Page{}, err
+	}
+	if !allowed {
+		return Page{}, ErrDisallowed
+	}
+
+	resp, err := f.get(ctx, u.String())
+	if err != nil {
+		return Page{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Page{}, fmt.Errorf("%s returned %s", u.Host, resp.Status)
+	}
+	if resp.ContentLength > f.MaxSize {
+		return Page{}, ErrTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxSize+1))
+	if err != nil {
+		return Page{}, fmt.Errorf("Error reading response body:\n%v", err)
+	}
+	if int64(len(body)) > f.MaxSize {
+		return Page{}, ErrTooLarge
+	}
+
+	page := Page{URL: resp.Request.URL.String()}
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/html"), strings.HasPrefix(contentType, "application/xhtml"):
+		page.Title, page.Text = extract(string(body))
+	case strings.HasPrefix(contentType, "text/"):
+		page.Text = string(body)
+	default:
+		return Page{}, ErrUnsupported
+	}
+	return page, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := f.HTTPClient.Do(req)
+	if errors.Is(err, errPrivate) {
+		return nil, errPrivate
+	}
+	return resp, err
+}
+
+// allowed checks u against its site's robots.txt. Sites without one allow
+// everything.
+func (f *Fetcher) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	resp, err := f.get(ctx, u.Scheme+"://"+u.Host+"/robots.txt")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return true, nil
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsAllow(io.LimitReader(resp.Body, 512<<10), path), nil
+}
+
+// robotsAllow applies the rules for our user agent, or for * when there
+// are none, to path. The longest matching rule wins, Allow on a tie.
+func robotsAllow(r io.Reader, path string) bool {
+	type rule struct {
+		allow  bool
+		prefix string
+	}
+	groups := map[string][]rule{}
+	var agents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, agent := range agents {
+				groups[agent] = append(groups[agent], rule{allow: key == "allow", prefix: value})
+			}
+		}
+	}
+
+	rules, ok := groups[UserAgent]
+	if !ok {
+		rules = groups["*"]
+	}
+	allowed, longest := true, -1
+	for _, rl := range rules {
+		prefix := strings.TrimSuffix(rl.prefix, "*")
+		if strings.HasSuffix(prefix, "$") {
+			if path != strings.TrimSuffix(prefix, "$") {
+				continue
+			}
+		} else if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		if len(rl.prefix) > longest || (len(rl.prefix) == longest && rl.allow) {
+			allowed, longest = rl.allow, len(rl.prefix)
+		}
+	}
+	return allowed
+}
+
+// skippedElements hold no readable text.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true, "iframe": true,
+}
+
+// blockElements end a line of text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true, "blockquote": true,
+}
+
+// extract returns the title and readable text of an HTML document.
+func extract(document string) (string, string) {
+	z := html.NewTokenizer(strings.NewReader(document))
+	var title string
+	var text strings.Builder
+	skipping, inTitle := 0, false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(title), tidy(text.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedElements[tag] && tt == html.StartTagToken {
+				skipping++
+			}
+			inTitle = tag == "title"
+			if blockElements[tag] {
+				text.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedElements[tag] && skipping > 0 {
+				skipping--
+			}
+			inTitle = false
+			if blockElements[tag] {
+				text.WriteString("\n")
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			} else if skipping == 0 {
+				text.WriteString(string(z.Text()))
+			}
+		}
+	}
+}
+
+// tidy collapses the whitespace within lines and drops empty ones.
+func tidy(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,296 +1,127 @@
 package main
 
 import (
-	"bytes"
-	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
-	_ "github.com/mattn/go-sqlite3"
-	"github.com/oklog/ulid/v2"
+	"github.com/musaubrian/groqy/internal/bot"
+	"github.com/musaubrian/groqy/internal/config"
+	"github.com/musaubrian/groqy/internal/llm"
+	"github.com/musaubrian/groqy/internal/logging"
+	"github.com/musaubrian/groqy/internal/store"
+	"github.com/musaubrian/groqy/internal/telegram"
 	tele "gopkg.in/telebot.v3"
 )
 
-const MODEL = "llama-3.1-8b-instant"
-
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type RequestBody struct {
-	Messages    []Message `json:"messages"`
-	Model       string    `json:"model"`
-	Temperature float64   `json:"temperature"`
-	MaxTokens   int       `json:"max_tokens"`
-	TopP        float64   `json:"top_p"`
-	Stream      bool      `json:"stream"`
-	Stop        *string   `json:"stop"`
-}
-
-type Command struct {
-	Name        string
-	Description string
-	Handler     func(c tele.Context) error
-}
-
-type User struct {
-	ID       string `db:"id"`
-	Username string `db:"username"`
-	Token    string `db:"token"`
-}
-
-type DB struct {
-	db *sqlx.DB
-}
-
 func main() {
-	slog.Info("Bot started")
-	err := godotenv.Load()
-	if err != nil {
-		slog.Error("Error loading .env file")
-		return
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
+	}
+
+	var err error
+	switch args[0] {
+	case "serve":
+		err = serve(args[1:])
+	case "users":
+		err = users(args[1:])
+	case "migrate":
+		err = migrate(args[1:])
+	case "backup":
+		err = backup(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-	botToken := os.Getenv("BOT_TOKEN")
-
-	db, err := connectToDB()
 	if err != nil {
-		slog.Error(fmt.Sprintf("Could not conect to db:\n%v", err))
-	}
-
-	if err := db.CreateTables(); err != nil {
-		slog.Error(fmt.Sprintf("Could not create tables:\n%v", err))
-	}
-
-	pref := tele.Settings{
-		Token: botToken,
-		Poller: &tele.LongPoller{
-			Timeout:        2 * time.Second,
-			AllowedUpdates: []string{"message"},
-		},
-		ParseMode: tele.ModeDefault,
-	}
-
-	b, err := tele.NewBot(pref)
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
-
-	commands := []Command{
-		{Name: "/auth", Description: "Provide token to allow usage", Handler: func(c tele.Context) error {
-			return authHandler(c, db)
-		}},
-	}
-
-	// menu := createMenu(commands)
-
-	b.Handle("/start", func(c tele.Context) error {
-		return c.Send(fmt.Sprintf("Hello, %s", c.Sender().FirstName))
-	})
-
-	for _, cmd := range commands {
-		b.Handle(cmd.Name, cmd.Handler)
-	}
-
-	b.Handle(tele.OnText, withAuth(db, func(c tele.Context) error {
-
-		user, err := db.GetUser(c.Sender().Username)
-		if err != nil {
-			return err
-		}
-		if user.Username == "" {
-			return c.Send("Can't seem to find you " + c.Sender().Username)
-		}
-
-		return chatHandler(c, c.Text())
-	}))
-
-	b.Start()
-}
-
-// func createMenu(commands []Command) *tele.ReplyMarkup {
-// 	menu := &tele.ReplyMarkup{ResizeKeyboard: true}
-// 	buttons := []tele.Row{}
-// 	for _, cmd := range commands {
-// 		btn := menu.Text(cmd.Name)
-// 		buttons = append(buttons, menu.Row(btn))
-// 	}
-// 	menu.Reply(buttons...)
-// 	return menu
-// }
-
-func authHandler(c tele.Context, db *DB) error {
-	user := c.Sender().Username
-	args := c.Args()
-	if len(args) != 1 {
-		return c.Send("Either provided too many or too little arguments")
-	}
-	token := args[0]
-	if !validateToken(token) {
-		return c.Send("Invalid token")
-	}
-	if err := db.CreateUser(user, token); err != nil {
-		return c.Send("ERROR: Could not save your token" + err.Error())
+		slog.Error(err.Error())
+		os.Exit(1)
 	}
-	return c.Send("Authenticated successfully")
 }
 
-func chatHandler(tc tele.Context, userMessage string) error {
-	var AIResponse string
+// serve runs the bot until it's stopped.
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	restore := flags.String("restore", "", "replace the SQLite database with this backup before starting")
+	flags.Parse(args)
 
-	baseInstruct := "Do not use any markdown formatting in your response, keep it plain text\n\n\n"
-	res, err := queryGroq(baseInstruct + userMessage)
+	cfg, err := setup()
 	if err != nil {
-		slog.Error(err.Error())
-		AIResponse = "An error occured"
+		return err
 	}
-	AIResponse = res
-
-	return tc.Send(AIResponse)
-}
+	slog.Info("Bot started")
 
-func connectToDB() (*DB, error) {
-	db, err := sqlx.Open("sqlite3", "./sqlite.db")
+	client, err := newClient(cfg)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("could not create the %s client: %v", cfg.Provider, err)
 	}
-	return &DB{db: db}, nil
-}
 
-func (d *DB) CreateTables() error {
-	schema := `
-CREATE TABLE IF NOT EXISTS users (
-	id TEXT NOT NULL PRIMARY KEY,
-	username TEXT NOT NULL,
-	token TEXT NOT NULL
-);
-    `
-	_, err := d.db.Exec(schema)
-	return err
-
-}
-
-func (d *DB) CreateUser(username, token string) error {
-	id := ulid.Make().String()
-	_, err := d.db.Exec("INSERT INTO users(id, username, token) VALUES(?, ?, ?)", id, username, token)
-	return err
-}
-
-func (d *DB) GetUser(username string) (User, error) {
-	var user User
-	err := d.db.Get(&user, "SELECT * FROM users WHERE username=?", username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return user, fmt.Errorf("user not found")
+	if *restore != "" {
+		if err := store.Restore(cfg.DatabaseURL, *restore); err != nil {
+			return fmt.Errorf("could not restore the database from %s: %v", *restore, err)
 		}
-		return user, err
+		slog.Info("Restored the database", "backup", *restore)
 	}
-	return user, err
-}
-
-func (d *DB) Cleanup() {
-	d.db.MustExec("DROP TABLE users")
-}
-
-func validateToken(token string) bool {
-	expectedToken := os.Getenv("AUTH_TOKEN")
-	return token == expectedToken
-}
-
-func checkAuth(c tele.Context, db *DB) error {
-	user := c.Sender().Username
 
-	dbUser, err := db.GetUser(user)
+	db, err := store.Open(cfg.DatabaseURL, cfg.SQLiteDriver)
 	if err != nil {
-		return fmt.Errorf("could not get user: %v", err)
+		slog.Error("Could not conect to db", "err", err)
 	}
 
-	if validateToken(dbUser.Token) {
-		return nil
-	}
-
-	return fmt.Errorf("invalid token")
-}
-
-func withAuth(db *DB, handler func(c tele.Context) error) func(c tele.Context) error {
-	return func(c tele.Context) error {
-		if err := checkAuth(c, db); err != nil {
-			return c.Send("Authentication required\nPlease use /auth yourtoken")
-		}
-		return handler(c)
+	if err := db.CreateTables(); err != nil {
+		slog.Error("Could not create tables", "err", err)
 	}
-}
-
-func queryGroq(message string) (string, error) {
-	apiKey := os.Getenv("GROQ_TOKEN")
 
-	url := "https://api.groq.com/openai/v1/chat/completions"
-
-	requestBody := RequestBody{
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: message,
-			},
+	pref := tele.Settings{
+		Token: cfg.BotToken,
+		Client: &http.Client{
+			Timeout:   time.Minute,
+			Transport: telegram.NewTransport(http.DefaultTransport, cfg.TelegramRate, cfg.TelegramChatRate),
 		},
-		Model:       MODEL,
-		Temperature: 0.5,
-		MaxTokens:   1024,
-		TopP:        1,
-		Stream:      false,
-		Stop:        nil,
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("Error marshaling JSON:\n%v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("Error creating request:\n%v", err)
+		Poller: &tele.LongPoller{
+			Timeout:        2 * time.Second,
+			AllowedUpdates: []string{"message", "edited_message", "callback_query", "message_reaction", "pre_checkout_query"},
+		},
+		ParseMode: tele.ModeDefault,
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	b, err := tele.NewBot(pref)
 	if err != nil {
-		return "", fmt.Errorf("Error sending request:\n%v", err)
+		log.Fatal(err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	groqy, err := bot.New(b, cfg, db, client)
 	if err != nil {
-		return "", fmt.Errorf("Error reading response body:\n%v", err)
-	}
-
-	var responseBody struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+		return fmt.Errorf("could not set up the bot: %v", err)
 	}
+	groqy.Start()
+	return nil
+}
 
-	err = json.Unmarshal(body, &responseBody)
+// setup loads the configuration and sets up logging with it.
+func setup() (config.Config, error) {
+	cfg, err := config.Load()
 	if err != nil {
-		return "", fmt.Errorf("Error unmarshaling response: %v", err)
+		return cfg, err
 	}
+	return cfg, logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+}
 
-	if len(responseBody.Choices) == 0 {
-		return "", fmt.Errorf("No message found in the response")
+func newClient(cfg config.Config) (llm.Client, error) {
+	switch cfg.Provider {
+	case "groq":
+		return llm.NewGroq(), nil
+	case "mock":
+		slog.Info("Using the mock provider, nothing is sent to Groq")
+		return llm.NewMock(cfg.MockTemplate)
 	}
-
-	return responseBody.Choices[0].Message.Content, nil
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
 }